@@ -0,0 +1,4 @@
+// Package searchv1 - сгенерированный код gRPC-сервиса поиска
+package searchv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative search.proto
@@ -8,6 +8,12 @@ import (
 	"time"
 
 	redis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"torrentServer/internal/lib/metrics"
+	"torrentServer/internal/lib/tracing"
 )
 
 type RedisCache struct {
@@ -27,12 +33,18 @@ func NewRedisCache(addr string, ttl time.Duration) *RedisCache {
 
 // Сохранить результат в кэш
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}) error {
+	ctx, span := tracing.Tracer().Start(ctx, "RedisCache.Set", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
+
 	data, err := json.Marshal(value)
 	if err != nil {
 		log.Printf("Cache marshal error: %v (key: %s)", err, key)
 		return err
 	}
+	span.SetAttributes(attribute.Int("cache.value_bytes", len(data)))
 	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "cache set failed")
 		log.Printf("Cache set error: %v (key: %s)", err, key)
 		return err
 	}
@@ -42,18 +54,64 @@ func (c *RedisCache) Set(ctx context.Context, key string, value interface{}) err
 
 // Получить результат из кэша
 func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) bool {
+	ctx, span := tracing.Tracer().Start(ctx, "RedisCache.Get", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
+
 	data, err := c.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			log.Printf("Key %s not found in cache", key)
+			metrics.CacheRequests.WithLabelValues("miss").Inc()
+			span.SetAttributes(attribute.String("cache.status", "miss"))
 		} else {
 			log.Printf("Cache get error: %v", err)
+			metrics.CacheRequests.WithLabelValues("error").Inc()
+			span.SetAttributes(attribute.String("cache.status", "error"))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "cache get failed")
 		}
 		return false
 	}
-	if err := json.Unmarshal(data, dest); err != nil {
+
+	_, decodeSpan := tracing.Tracer().Start(ctx, "RedisCache.decode", trace.WithAttributes(attribute.Int("cache.value_bytes", len(data))))
+	err = json.Unmarshal(data, dest)
+	decodeSpan.End()
+	if err != nil {
 		log.Printf("Cache unmarshal error: %v", err)
+		metrics.CacheRequests.WithLabelValues("error").Inc()
+		span.SetAttributes(attribute.String("cache.status", "error"))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "cache unmarshal failed")
 		return false
 	}
+	metrics.CacheRequests.WithLabelValues("hit").Inc()
+	span.SetAttributes(attribute.String("cache.status", "hit"))
 	return true
 }
+
+// Client возвращает клиент Redis, чтобы другие компоненты (например, rate limiter)
+// использовали тот же пул соединений
+func (c *RedisCache) Client() *redis.Client {
+	return c.client
+}
+
+// TTL возвращает оставшееся время жизни записи, 0 - если записи нет или Redis недоступен
+func (c *RedisCache) TTL(ctx context.Context, key string) time.Duration {
+	ttl, err := c.client.PTTL(ctx, key).Result()
+	if err != nil {
+		log.Printf("Cache ttl error: %v (key: %s)", err, key)
+		return 0
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
@@ -2,11 +2,58 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi"
+	"google.golang.org/grpc"
+
+	searchv1 "torrentServer/api/search/v1"
+	cache "torrentServer/cache"
+	"torrentServer/dlna_server/mediaserver"
+	"torrentServer/dlna_server/ssdp"
+	grpcSearch "torrentServer/grpc_server/handlers/search"
+	icAuth "torrentServer/grpc_server/interceptor/auth"
+	icRateLimit "torrentServer/grpc_server/interceptor/ratelimit"
+	grpcServer "torrentServer/grpc_server/server"
+	"torrentServer/http_server/handlers/apikeys"
+	bandwidthHandler "torrentServer/http_server/handlers/bandwidth"
+	"torrentServer/http_server/handlers/diskusage"
+	"torrentServer/http_server/handlers/docs"
+	"torrentServer/http_server/handlers/events"
+	"torrentServer/http_server/handlers/health"
+	"torrentServer/http_server/handlers/playlist"
 	"torrentServer/http_server/handlers/search"
+	"torrentServer/http_server/handlers/stream"
+	"torrentServer/http_server/handlers/subtitles"
+	"torrentServer/http_server/handlers/torrents"
+	mwAuth "torrentServer/http_server/midleware/auth"
+	mwRateLimit "torrentServer/http_server/midleware/ratelimit"
+	mwRecoverer "torrentServer/http_server/midleware/recoverer"
+	mwTimeout "torrentServer/http_server/midleware/timeout"
+	"torrentServer/http_server/server"
 	"torrentServer/internal/config"
+	getTorrents "torrentServer/internal/handlers/request"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/lib/tracing"
+	"torrentServer/internal/services/auth"
+	"torrentServer/internal/services/bandwidth"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/services/quota"
+	"torrentServer/internal/services/ratelimit"
+	"torrentServer/internal/services/seeding"
+	"torrentServer/internal/services/warmer"
+	"torrentServer/internal/storage/sqlite"
 )
 
 const (
@@ -22,17 +69,355 @@ func main() {
 	log := setupLogger(cfg.Env)
 	log = log.With(slog.String("env", cfg.Env)) // к каждому сообщению будет добавляться поле с информацией о текущем окружении
 
-	log.Info("initializing server", slog.String("address", cfg.Address)) // Помимо сообщения выведем параметр с адресом
+	log.Info("initializing server", slog.String("address", cfg.HTTPServer.Address)) // Помимо сообщения выведем параметр с адресом
 	log.Debug("logger debug mode enabled")
 
-	// router := chi.NewRouter()
+	// ctx отменяется по SIGINT/SIGTERM, по нему останавливаются фоновые задачи и сервер
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+
+	storage, err := sqlite.New(cfg.StoragePath)
+	if err != nil {
+		log.Error("failed to init storage", sl.Err(err))
+		os.Exit(1)
+	}
+	defer storage.Close()
+
+	getTorrents.SetResultSaver(storage)
+	getTorrents.SetLocalIndex(storage)
+
+	redisCache := cache.NewRedisCache(cfg.Redis.Address, cfg.Redis.CacheTTL)
+	defer redisCache.Close()
+	searchHandler := search.New(log, redisCache, storage, cfg.HTTPServer.SearchTimeout)
+
+	if cfg.CacheWarmer.Enabled {
+		w, err := warmer.New(log, cfg.CacheWarmer, storage, func(ctx context.Context, q warmer.Query) (int, error) {
+			return searchHandler.RefreshResults(ctx, q.Query, q.Categories, q.SafeOnly)
+		})
+		if err != nil {
+			log.Error("failed to init cache warmer", sl.Err(err))
+			os.Exit(1)
+		}
+		go w.Run(ctx)
+	}
+
+	// встроенный BitTorrent-клиент, данные раздач по умолчанию лежат рядом с базой
+	var torrentEngine *engine.Engine
+	var quotaManager *quota.Manager
+	var bandwidthManager *bandwidth.Manager
+	if cfg.Engine.Enabled {
+		if cfg.Engine.DataDir == "" {
+			cfg.Engine.DataDir = filepath.Join(filepath.Dir(cfg.StoragePath), "torrents")
+		}
+		torrentEngine, err = engine.New(log, cfg.Engine, storage)
+		if err != nil {
+			log.Error("failed to init engine", sl.Err(err))
+			os.Exit(1)
+		}
+		defer torrentEngine.Close()
+
+		// квота на место под данные раздач, отчёт по занятому месту доступен и без неё
+		quotaManager, err = quota.New(log, cfg.Quota, torrentEngine)
+		if err != nil {
+			log.Error("failed to init storage quota", sl.Err(err))
+			os.Exit(1)
+		}
+		if cfg.Quota.Enabled {
+			go quotaManager.Run(ctx)
+		}
+
+		// остановка раздач, выполнивших требования трекера
+		if cfg.Seeding.Enabled {
+			seedingManager, err := seeding.New(log, cfg.Seeding, torrentEngine)
+			if err != nil {
+				log.Error("failed to init seeding policy", sl.Err(err))
+				os.Exit(1)
+			}
+			go seedingManager.Run(ctx)
+		}
+
+		// ограничения скорости и расписания, без настроек скорость не ограничивается
+		bandwidthManager, err = bandwidth.New(log, cfg.Bandwidth, torrentEngine)
+		if err != nil {
+			log.Error("failed to init bandwidth limits", sl.Err(err))
+			os.Exit(1)
+		}
+		go bandwidthManager.Run(ctx)
+	}
+
+	authService, err := auth.New(cfg.Auth, storage)
+	if err != nil {
+		log.Error("failed to init auth", sl.Err(err))
+		os.Exit(1)
+	}
+	if cfg.Auth.Enabled && cfg.Auth.SigningKey == "" {
+		log.Warn("auth.signing_key is not set, signed playlist urls will stop working after restart")
+	}
+
+	// общие для API middleware: rate limit по IP, авторизация и rate limit по ключу.
+	// Лимит по IP стоит до авторизации, чтобы запросы с неверными ключами тоже его расходовали.
+	// Ограничение времени обработки ставится отдельно, потоковые маршруты работают без него.
+	// У /stream свой лимит по IP вместо per_ip и per_key, квоту ключа он не расходует.
+	apiMiddlewares, streamMiddlewares := chi.Middlewares{}, chi.Middlewares{}
+	limiter := ratelimit.New(redisCache.Client())
+	if cfg.RateLimit.Enabled {
+		apiMiddlewares = append(apiMiddlewares, mwRateLimit.PerIP(log, limiter, cfg.RateLimit))
+		streamMiddlewares = append(streamMiddlewares, mwRateLimit.Stream(log, limiter, cfg.RateLimit))
+	}
+	if cfg.Auth.Enabled {
+		authMiddleware := mwAuth.New(log, authService)
+		apiMiddlewares = append(apiMiddlewares, authMiddleware)
+		streamMiddlewares = append(streamMiddlewares, authMiddleware)
+	}
+	if cfg.RateLimit.Enabled {
+		apiMiddlewares = append(apiMiddlewares, mwRateLimit.PerKey(log, limiter, cfg.RateLimit, authService))
+	}
+
+	router, err := server.NewRouter(log, cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		log.Error("failed to init router", sl.Err(err))
+		os.Exit(1)
+	}
+
+	var eventsHandler *events.Handler
+	var torrentsHandler *torrents.Handler
+	if torrentEngine != nil {
+		eventsHandler = events.New(log, torrentEngine)
+		torrentsHandler = torrents.New(log, torrentEngine)
+	}
+
+	healthHandler := health.New(log, cfg.HTTPServer.Timeout,
+		health.Check{Name: "redis", Ping: redisCache.Ping},
+		health.Check{Name: "jackett", Ping: getTorrents.GetJackettInstance().Ping},
+		health.Check{Name: "storage", Ping: storage.Ping},
+	)
+	router.Get("/healthz", healthHandler.Live)
+	router.Get("/readyz", healthHandler.Ready)
+
+	router.Route(mwAuth.APIPrefix, func(r chi.Router) {
+		r.Get("/openapi.json", docs.New())
+
+		r.Group(func(r chi.Router) {
+			r.Use(apiMiddlewares...)
+
+			r.Group(func(r chi.Router) {
+				r.Use(mwTimeout.New(cfg.HTTPServer.Timeout))
+
+				r.Mount("/search", searchHandler.Routes())
+
+				if torrentEngine != nil {
+					r.Mount("/torrents", torrentsHandler.Routes())
+				}
+
+				// без авторизации административные маршруты не подключаются: их вызвал бы кто угодно
+				if cfg.Auth.Enabled {
+					r.Mount("/admin/keys", apikeys.New(log, authService).Routes())
+					if torrentEngine != nil {
+						r.Mount("/admin/storage", diskusage.New(log, quotaManager).Routes())
+						r.Mount("/admin/bandwidth", bandwidthHandler.New(log, bandwidthManager).Routes())
+					}
+				}
+			})
 
-	// router.Use(middleware.Logger)
+			if torrentEngine != nil {
+				r.Mount("/subtitles", subtitles.New(log, torrentEngine).Routes())
+				r.Mount("/playlist", playlist.New(log, torrentEngine, authService, mwAuth.APIPrefix).Routes())
+				// ожидание метаданных от пиров дольше таймаута обычных запросов
+				r.Get("/torrents/resolve", torrentsHandler.Resolve)
+				// приём файла до 10 МиБ от медленного клиента
+				r.Post("/torrents/upload", torrentsHandler.Upload)
+				r.Get("/torrents/events", eventsHandler.All)
+				r.Get("/torrents/{hash}/events", eventsHandler.Torrent)
+			}
+		})
 
-	http.HandleFunc("/search", search.SearchHandler)
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Error(err.Error())
+		// плееры шлют к потоку десятки Range-запросов при перемотке, см. streamMiddlewares
+		if torrentEngine != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(streamMiddlewares...)
+				r.Mount("/stream", stream.New(log, torrentEngine).Routes())
+			})
+		}
+	})
+
+	// старый адрес поиска без версии оставлен для совместимости с текущим фронтендом
+	router.Group(func(r chi.Router) {
+		r.Use(mwTimeout.New(cfg.HTTPServer.Timeout))
+		r.Use(apiMiddlewares...)
+		r.Mount("/search", searchHandler.Routes())
+	})
+
+	log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
+
+	srv := server.New(cfg.HTTPServer, router)
+	if eventsHandler != nil {
+		// Shutdown не дожидается долгих потоков событий, их нужно закрыть самим
+		srv.RegisterOnShutdown(eventsHandler.Close)
 	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("failed to start server", sl.Err(err))
+			stop()
+		}
+	}()
+
+	log.Info("server started")
+
+	// gRPC API поиска: тот же конвейер поиска, ключи и лимиты, что и у HTTP
+	var grpcSrv *grpc.Server
+	if cfg.GRPCServer.Enabled {
+		// лимит по IP до авторизации, чтобы перебор ключей тоже его расходовал, лимит по ключу - после
+		var unary []grpc.UnaryServerInterceptor
+		var stream []grpc.StreamServerInterceptor
+		if cfg.RateLimit.Enabled {
+			unary = append(unary, icRateLimit.UnaryPerIP(log, limiter, cfg.RateLimit))
+			stream = append(stream, icRateLimit.StreamPerIP(log, limiter, cfg.RateLimit))
+		}
+		if cfg.Auth.Enabled {
+			scopes := icAuth.Scopes{searchv1.SearchService_ServiceDesc.ServiceName: grpcSearch.Scope}
+			unary = append(unary, icAuth.Unary(log, authService, scopes))
+			stream = append(stream, icAuth.Stream(log, authService, scopes))
+		}
+		if cfg.RateLimit.Enabled {
+			unary = append(unary, icRateLimit.UnaryPerKey(log, limiter, cfg.RateLimit, authService))
+			stream = append(stream, icRateLimit.StreamPerKey(log, limiter, cfg.RateLimit, authService))
+		}
+
+		grpcSrv = grpcServer.New(log, cfg.GRPCServer, cfg.HTTPServer.Timeout, unary, stream)
+		grpcSearch.New(log, searchHandler, getTorrents.GetJackettInstance()).Register(grpcSrv)
+
+		lis, err := net.Listen("tcp", cfg.GRPCServer.Address)
+		if err != nil {
+			log.Error("failed to listen grpc address", sl.Err(err))
+			os.Exit(1)
+		}
+		log.Info("starting grpc server", slog.String("address", cfg.GRPCServer.Address))
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Error("failed to start grpc server", sl.Err(err))
+				stop()
+			}
+		}()
+	}
+
+	// DLNA-медиасервер: телевизоры находят его по SSDP и смотрят раздачи через тот же /stream
+	var dlnaSrv *http.Server
+	var ssdpDone <-chan struct{}
+	if cfg.DLNA.Enabled && torrentEngine == nil {
+		log.Warn("dlna requires engine.enabled, media server is not started")
+	} else if cfg.DLNA.Enabled {
+		dlnaSrv, ssdpDone, err = startDLNA(ctx, log, cfg.DLNA, torrentEngine, stop)
+		if err != nil {
+			log.Error("failed to start dlna", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	<-ctx.Done()
+	log.Info("stopping server")
+
+	// даём обрабатываемым запросам завершиться, новые соединения уже не принимаются
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to stop server", sl.Err(err))
+	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	if dlnaSrv != nil {
+		if err := dlnaSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to stop dlna server", sl.Err(err))
+		}
+		<-ssdpDone
+	}
+
+	// отправляем спаны, которые ещё не успели уйти в экспортер
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error("failed to stop tracing", sl.Err(err))
+	}
+
+	// хранилище, Redis и движок закрываются в defer
+	log.Info("server stopped")
+}
+
+// stopGRPC дожидается завершения вызовов, но не дольше shutdown_timeout:
+// потоки SearchStream могут быть долгими, оставшиеся соединения закрываются принудительно
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// startDLNA запускает HTTP-сервер медиасервера и объявления SSDP. Объявления прекращаются
+// с отменой ctx, закрытый канал означает, что ssdp:byebye разослан.
+func startDLNA(ctx context.Context, log *slog.Logger, cfg config.DLNA, e *engine.Engine, stop func()) (*http.Server, <-chan struct{}, error) {
+	lis, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	port := strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)
+
+	uuid := mediaserver.DeviceUUID(cfg.FriendlyName)
+	advertiser, err := ssdp.Listen(log, ssdp.Config{
+		Address:   cfg.SSDPAddress,
+		Interface: cfg.Interface,
+		UUID:      uuid,
+		Types:     []string{mediaserver.DeviceType, mediaserver.ContentDirectory, mediaserver.ConnectionManager},
+		Server:    runtime.GOOS + "/1.0 UPnP/1.0 torrentServer/1.0",
+		MaxAge:    cfg.MaxAge,
+		Location: func(local net.IP) string {
+			host := cfg.AdvertiseHost
+			if host == "" {
+				host = local.String()
+			}
+			return "http://" + net.JoinHostPort(host, port) + "/description.xml"
+		},
+	})
+	if err != nil {
+		lis.Close()
+		return nil, nil, err
+	}
+
+	router := chi.NewRouter()
+	router.Use(mwRecoverer.New(log))
+	router.Mount("/", mediaserver.New(log, e, stream.New(log, e).Routes(), cfg.FriendlyName, uuid).Routes())
+
+	// без WriteTimeout: через этот сервер телевизор смотрит фильм целиком
+	srv := &http.Server{Handler: router, ReadHeaderTimeout: 10 * time.Second, IdleTimeout: time.Minute}
+
+	log.Info("starting dlna media server", slog.String("address", lis.Addr().String()), slog.String("uuid", uuid))
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("failed to start dlna server", sl.Err(err))
+			stop()
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := advertiser.Serve(ctx); err != nil {
+			log.Error("ssdp stopped", sl.Err(err))
+		}
+	}()
+
+	return srv, done, nil
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,272 @@
+package mediaserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"torrentServer/http_server/handlers/stream"
+	"torrentServer/internal/lib/episode"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+// Torrents - раздачи движка (engine.Engine)
+type Torrents interface {
+	List() []engine.Status
+}
+
+// Идентификаторы объектов каталога: "0" - корень, info-hash - папка раздачи,
+// "{hash}/{index}" - видеофайл раздачи
+const rootID = "0"
+
+// DLNA.ORG_OP=01 - поддерживается перемотка по байтам (Range),
+// FLAGS - потоковая передача, фоновая загрузка, DLNA 1.5
+const dlnaFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+const (
+	classFolder = "object.container.storageFolder"
+	classVideo  = "object.item.videoItem"
+)
+
+type didlLite struct {
+	XMLName    xml.Name    `xml:"DIDL-Lite"`
+	XMLNS      string      `xml:"xmlns,attr"`
+	DC         string      `xml:"xmlns:dc,attr"`
+	UPnP       string      `xml:"xmlns:upnp,attr"`
+	DLNA       string      `xml:"xmlns:dlna,attr"`
+	Containers []container `xml:"container"`
+	Items      []item      `xml:"item"`
+}
+
+type container struct {
+	ID         string `xml:"id,attr"`
+	ParentID   string `xml:"parentID,attr"`
+	Restricted int    `xml:"restricted,attr"`
+	Searchable int    `xml:"searchable,attr"`
+	ChildCount int    `xml:"childCount,attr"`
+	Title      string `xml:"dc:title"`
+	Class      string `xml:"upnp:class"`
+}
+
+type item struct {
+	ID         string `xml:"id,attr"`
+	ParentID   string `xml:"parentID,attr"`
+	Restricted int    `xml:"restricted,attr"`
+	Title      string `xml:"dc:title"`
+	Class      string `xml:"upnp:class"`
+	Res        res    `xml:"res"`
+}
+
+type res struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr"`
+	URL          string `xml:",chardata"`
+}
+
+// browse выполняет Browse: BrowseMetadata возвращает сам объект, BrowseDirectChildren - его детей.
+// Фильтр и сортировка не поддерживаются, порядок всегда одинаковый: раздачи по времени добавления,
+// серии - по номерам сезона и эпизода.
+func (h *Handler) browse(args map[string]string, base string) ([]arg, *upnpError) {
+	start, err := strconv.Atoi(defaultString(args["StartingIndex"], "0"))
+	if err != nil || start < 0 {
+		return nil, errInvalidArgs
+	}
+	count, err := strconv.Atoi(defaultString(args["RequestedCount"], "0"))
+	if err != nil || count < 0 {
+		return nil, errInvalidArgs
+	}
+
+	id := args["ObjectID"]
+	torrents := h.active()
+
+	doc := didlLite{
+		XMLNS: "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/",
+		DC:    "http://purl.org/dc/elements/1.1/",
+		UPnP:  "urn:schemas-upnp-org:metadata-1-0/upnp/",
+		DLNA:  "urn:schemas-dlna-org:metadata-1-0/",
+	}
+	var total int
+
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		total = 1
+		switch hash, index, isItem := strings.Cut(id, "/"); {
+		case id == rootID:
+			doc.Containers = []container{h.root(torrents)}
+		case !isItem:
+			t, ok := find(torrents, hash)
+			if !ok {
+				return nil, errNoSuchObject
+			}
+			doc.Containers = []container{folder(t)}
+		default:
+			t, ok := find(torrents, hash)
+			if !ok {
+				return nil, errNoSuchObject
+			}
+			i := slices.IndexFunc(videos(t), func(f engine.File) bool { return strconv.Itoa(f.Index) == index })
+			if i < 0 {
+				return nil, errNoSuchObject
+			}
+			doc.Items = []item{video(t, videos(t)[i], base)}
+		}
+
+	case "BrowseDirectChildren":
+		if id == rootID {
+			total = len(torrents)
+			for _, t := range page(torrents, start, count) {
+				doc.Containers = append(doc.Containers, folder(t))
+			}
+			break
+		}
+		t, ok := find(torrents, id)
+		if !ok {
+			return nil, errNoSuchObject
+		}
+		files := videos(t)
+		total = len(files)
+		for _, f := range page(files, start, count) {
+			doc.Items = append(doc.Items, video(t, f, base))
+		}
+
+	default:
+		return nil, errInvalidArgs
+	}
+
+	result, err := xml.Marshal(doc)
+	if err != nil {
+		h.log.Error("failed to marshal didl-lite", slog.String("object", id), sl.Err(err))
+		return nil, errCannotProcess
+	}
+
+	return []arg{
+		{"Result", string(result)},
+		{"NumberReturned", strconv.Itoa(len(doc.Containers) + len(doc.Items))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", fmt.Sprint(h.updateID())},
+	}, nil
+}
+
+// active - раздачи, которые можно смотреть: не на паузе и с видео среди файлов
+func (h *Handler) active() []engine.Status {
+	var list []engine.Status
+	for _, t := range h.torrents.List() {
+		if t.State != engine.StatePaused && len(videos(t)) > 0 {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// updateID меняется, когда меняется состав каталога; по нему телевизор понимает,
+// что закэшированный список папок устарел
+func (h *Handler) updateID() uint32 {
+	sum := fnv.New32a()
+	for _, t := range h.active() {
+		fmt.Fprintf(sum, "%s/%d;", t.InfoHash, len(t.Files))
+	}
+	return sum.Sum32()
+}
+
+func (h *Handler) root(torrents []engine.Status) container {
+	return container{
+		ID:         rootID,
+		ParentID:   "-1",
+		Restricted: 1,
+		ChildCount: len(torrents),
+		Title:      h.name,
+		Class:      classFolder,
+	}
+}
+
+func folder(t engine.Status) container {
+	return container{
+		ID:         t.InfoHash,
+		ParentID:   rootID,
+		Restricted: 1,
+		ChildCount: len(videos(t)),
+		Title:      t.Name,
+		Class:      classFolder,
+	}
+}
+
+func video(t engine.Status, f engine.File, base string) item {
+	title := strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path))
+	if n, ok := episode.Parse(f.Path); ok {
+		title = n.String() + " - " + title
+	}
+
+	return item{
+		ID:         t.InfoHash + "/" + strconv.Itoa(f.Index),
+		ParentID:   t.InfoHash,
+		Restricted: 1,
+		Title:      title,
+		Class:      classVideo,
+		Res: res{
+			ProtocolInfo: "http-get:*:" + contentType(f.Path) + ":" + dlnaFeatures,
+			Size:         f.Size,
+			URL:          fmt.Sprintf("%s%s/%s/%d", base, StreamPrefix, t.InfoHash, f.Index),
+		},
+	}
+}
+
+// videos - видеофайлы раздачи в порядке сезонов и серий
+func videos(t engine.Status) []engine.File {
+	var list []engine.File
+	for _, f := range t.Files {
+		if f.Kind == engine.KindVideo {
+			list = append(list, f)
+		}
+	}
+	slices.SortStableFunc(list, func(a, b engine.File) int { return episode.Compare(a.Path, b.Path) })
+	return list
+}
+
+func find(torrents []engine.Status, hash string) (engine.Status, bool) {
+	i := slices.IndexFunc(torrents, func(t engine.Status) bool { return strings.EqualFold(t.InfoHash, hash) })
+	if i < 0 {
+		return engine.Status{}, false
+	}
+	return torrents[i], true
+}
+
+// page - срез по StartingIndex и RequestedCount, 0 означает "все"
+func page[T any](list []T, start, count int) []T {
+	if start >= len(list) {
+		return nil
+	}
+	list = list[start:]
+	if count > 0 && count < len(list) {
+		list = list[:count]
+	}
+	return list
+}
+
+func contentType(name string) string {
+	if t := stream.ContentType(name); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// sourceProtocols - форматы, которые сервер может отдать, для GetProtocolInfo
+func sourceProtocols() string {
+	var list []string
+	for _, ext := range []string{".mp4", ".mkv", ".avi", ".webm", ".mov", ".ts", ".wmv", ".mpg"} {
+		list = append(list, "http-get:*:"+stream.ContentType(ext)+":*")
+	}
+	return strings.Join(list, ",")
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
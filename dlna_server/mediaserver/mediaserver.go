@@ -0,0 +1,320 @@
+// Package mediaserver - UPnP MediaServer для телевизоров и плееров в локальной сети:
+// описание устройства, ContentDirectory со списком активных раздач и их видеофайлов
+// и ConnectionManager. Воспроизведение идёт через обработчик /stream с поддержкой Range.
+package mediaserver
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// Типы устройства и служб
+const (
+	DeviceType        = "urn:schemas-upnp-org:device:MediaServer:1"
+	ContentDirectory  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	ConnectionManager = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+// StreamPrefix - путь, под которым монтируется обработчик потоков
+const StreamPrefix = "/stream"
+
+// сколько действует подписка на события; события не рассылаются, но без ответа
+// на SUBSCRIBE часть телевизоров считает устройство неисправным
+const subscriptionTimeout = "Second-1800"
+
+// SUBSCRIBE и UNSUBSCRIBE из UPnP GENA, без регистрации chi отвечает на них 405
+func init() {
+	chi.RegisterMethod("SUBSCRIBE")
+	chi.RegisterMethod("UNSUBSCRIBE")
+}
+
+type Handler struct {
+	log      *slog.Logger
+	torrents Torrents
+	stream   http.Handler
+	name     string
+	uuid     string
+}
+
+// New создаёт медиасервер с именем name, которое видно в меню телевизора.
+// stream - обработчик /stream/{hash}/{index}, его маршруты монтируются в Routes.
+func New(log *slog.Logger, torrents Torrents, stream http.Handler, name, uuid string) *Handler {
+	return &Handler{
+		log:      log.With(slog.String("component", "dlna/mediaserver")),
+		torrents: torrents,
+		stream:   stream,
+		name:     name,
+		uuid:     uuid,
+	}
+}
+
+// Routes - описание устройства, служб, SOAP-управление и потоки
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/description.xml", h.Description)
+	r.Get("/ContentDirectory.xml", scpd(contentDirectorySCPD))
+	r.Get("/ConnectionManager.xml", scpd(connectionManagerSCPD))
+	r.Post("/control/ContentDirectory", h.ContentDirectory)
+	r.Post("/control/ConnectionManager", h.ConnectionManager)
+	r.MethodFunc("SUBSCRIBE", "/event/{service}", h.Subscribe)
+	r.MethodFunc("UNSUBSCRIBE", "/event/{service}", h.Subscribe)
+	r.Mount(StreamPrefix, contentFeatures(h.stream))
+	return r
+}
+
+// Description отдаёт описание устройства, адрес которого объявляется по SSDP в LOCATION
+func (h *Handler) Description(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, http.StatusOK, fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0" xmlns:dlna="urn:schemas-dlna-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>%s</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>torrentServer</manufacturer>
+    <modelName>torrentServer</modelName>
+    <modelDescription>Torrents streamed while downloading</modelDescription>
+    <UDN>uuid:%s</UDN>
+    <dlna:X_DLNADOC>DMS-1.50</dlna:X_DLNADOC>
+    <serviceList>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ContentDirectory</serviceId>
+        <SCPDURL>/ContentDirectory.xml</SCPDURL>
+        <controlURL>/control/ContentDirectory</controlURL>
+        <eventSubURL>/event/ContentDirectory</eventSubURL>
+      </service>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ConnectionManager</serviceId>
+        <SCPDURL>/ConnectionManager.xml</SCPDURL>
+        <controlURL>/control/ConnectionManager</controlURL>
+        <eventSubURL>/event/ConnectionManager</eventSubURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`, DeviceType, escape(h.name), h.uuid, ContentDirectory, ConnectionManager))
+}
+
+func scpd(doc string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, http.StatusOK, doc)
+	}
+}
+
+// Subscribe принимает подписку на события служб, но уведомлений не отправляет:
+// список раздач контрольная точка перечитывает при каждом открытии папки
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "SUBSCRIBE" {
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			sid = "uuid:" + newUUID()
+		}
+		// имена как в спецификации GENA, часть телевизоров сравнивает их с учётом регистра
+		w.Header()["SID"] = []string{sid}
+		w.Header()["TIMEOUT"] = []string{subscriptionTimeout}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ContentDirectory - действия службы каталога: Browse и сведения о возможностях
+func (h *Handler) ContentDirectory(w http.ResponseWriter, r *http.Request) {
+	action, args, err := readAction(r)
+	if err != nil {
+		writeFault(w, errInvalidArgs)
+		return
+	}
+	h.log.Debug("content directory action", slog.String("action", action), slog.String("object", args["ObjectID"]))
+
+	switch action {
+	case "Browse":
+		out, uerr := h.browse(args, baseURL(r))
+		if uerr != nil {
+			writeFault(w, uerr)
+			return
+		}
+		writeResponse(w, ContentDirectory, action, out)
+	case "GetSearchCapabilities":
+		writeResponse(w, ContentDirectory, action, []arg{{"SearchCaps", ""}})
+	case "GetSortCapabilities":
+		writeResponse(w, ContentDirectory, action, []arg{{"SortCaps", ""}})
+	case "GetSystemUpdateID":
+		writeResponse(w, ContentDirectory, action, []arg{{"Id", fmt.Sprint(h.updateID())}})
+	default:
+		writeFault(w, errInvalidAction)
+	}
+}
+
+// ConnectionManager - сервер только отдаёт потоки по HTTP, соединений не устанавливает
+func (h *Handler) ConnectionManager(w http.ResponseWriter, r *http.Request) {
+	action, _, err := readAction(r)
+	if err != nil {
+		writeFault(w, errInvalidArgs)
+		return
+	}
+
+	switch action {
+	case "GetProtocolInfo":
+		writeResponse(w, ConnectionManager, action, []arg{{"Source", sourceProtocols()}, {"Sink", ""}})
+	case "GetCurrentConnectionIDs":
+		writeResponse(w, ConnectionManager, action, []arg{{"ConnectionIDs", "0"}})
+	case "GetCurrentConnectionInfo":
+		writeResponse(w, ConnectionManager, action, []arg{
+			{"RcsID", "-1"},
+			{"AVTransportID", "-1"},
+			{"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""},
+			{"PeerConnectionID", "-1"},
+			{"Direction", "Output"},
+			{"Status", "OK"},
+		})
+	default:
+		writeFault(w, errInvalidAction)
+	}
+}
+
+// contentFeatures добавляет заголовки DLNA, которые телевизоры запрашивают перед воспроизведением
+func contentFeatures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("getcontentFeatures.dlna.org") == "1" {
+			w.Header().Set("contentFeatures.dlna.org", dlnaFeatures)
+		}
+		if mode := r.Header.Get("transferMode.dlna.org"); mode != "" {
+			w.Header().Set("transferMode.dlna.org", mode)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UPnP-ошибки из спецификаций Device Architecture и ContentDirectory
+type upnpError struct {
+	Code        int
+	Description string
+}
+
+var (
+	errInvalidAction = &upnpError{401, "Invalid Action"}
+	errInvalidArgs   = &upnpError{402, "Invalid Args"}
+	errNoSuchObject  = &upnpError{701, "No such object"}
+	errCannotProcess = &upnpError{720, "Cannot process the request"}
+)
+
+type arg struct {
+	Name  string
+	Value string
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Action struct {
+			XMLName xml.Name
+			Args    []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+// readAction разбирает SOAP-запрос. Имя действия берётся из заголовка SOAPACTION
+// ("urn:schemas-upnp-org:service:ContentDirectory:1#Browse"), без него - из тела.
+func readAction(r *http.Request) (string, map[string]string, error) {
+	var env soapEnvelope
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&env); err != nil {
+		return "", nil, err
+	}
+
+	args := make(map[string]string, len(env.Body.Action.Args))
+	for _, a := range env.Body.Action.Args {
+		args[a.XMLName.Local] = strings.TrimSpace(a.Value)
+	}
+
+	action := env.Body.Action.XMLName.Local
+	if header := strings.Trim(r.Header.Get("SOAPACTION"), `"`); header != "" {
+		if _, name, ok := strings.Cut(header, "#"); ok {
+			action = name
+		}
+	}
+	return action, args, nil
+}
+
+func writeResponse(w http.ResponseWriter, service, action string, args []arg) {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<u:%sResponse xmlns:u="%s">`, action, service)
+	for _, a := range args {
+		fmt.Fprintf(&b, "<%s>%s</%s>", a.Name, escape(a.Value), a.Name)
+	}
+	fmt.Fprintf(&b, "</u:%sResponse>", action)
+	writeXML(w, http.StatusOK, envelope(b.String()))
+}
+
+func writeFault(w http.ResponseWriter, err *upnpError) {
+	writeXML(w, http.StatusInternalServerError, envelope(fmt.Sprintf(`<s:Fault>`+
+		`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>`+
+		`<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0">`+
+		`<errorCode>%d</errorCode><errorDescription>%s</errorDescription>`+
+		`</UPnPError></detail></s:Fault>`, err.Code, err.Description)))
+}
+
+func envelope(body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body>` + body + `</s:Body></s:Envelope>`
+}
+
+func writeXML(w http.ResponseWriter, status int, doc string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("EXT", "")
+	w.WriteHeader(status)
+	w.Write([]byte(doc))
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// baseURL - адрес, по которому телевизор обратился к серверу, на него же ведут ссылки на потоки
+func baseURL(r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			host = addr.String()
+		}
+	}
+	return "http://" + host
+}
+
+// DeviceUUID - постоянный UDN устройства: телевизоры запоминают сервер по нему,
+// поэтому он зависит только от имени хоста и имени сервера (UUID версии 5)
+func DeviceUUID(name string) string {
+	hostname, _ := os.Hostname()
+	sum := sha1.Sum([]byte("torrentServer/" + hostname + "/" + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return formatUUID(sum[:16])
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
@@ -0,0 +1,154 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"torrentServer/http_server/handlers/stream"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine/enginetest"
+)
+
+// DIDL-Lite для разбора ответа: при чтении префиксы dc: и upnp: становятся пространствами имён
+type result struct {
+	Containers []struct {
+		ID         string `xml:"id,attr"`
+		ChildCount int    `xml:"childCount,attr"`
+		Title      string `xml:"title"`
+		Class      string `xml:"class"`
+	} `xml:"container"`
+	Items []struct {
+		ID    string `xml:"id,attr"`
+		Title string `xml:"title"`
+		Class string `xml:"class"`
+		Res   res    `xml:"res"`
+	} `xml:"item"`
+}
+
+func TestMediaServer(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "Show", map[string]int{
+		"Season 1/Show.S01E10.mkv": 64 << 10,
+		"Season 1/Show.S01E02.mkv": 64 << 10,
+		"Show.S01E02.srt":          1 << 10,
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := e.Files(ctx, seeder.InfoHash); err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+
+	log := slogdiscard.NewDiscardLogger()
+	srv := httptest.NewServer(New(log, e, stream.New(log, e).Routes(), "Torrents", "uuid-1").Routes())
+	defer srv.Close()
+
+	t.Run("description", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/description.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		for _, want := range []string{"<friendlyName>Torrents</friendlyName>", "<UDN>uuid:uuid-1</UDN>", DeviceType, ContentDirectory} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("description has no %q", want)
+			}
+		}
+	})
+
+	browse := func(t *testing.T, id, flag string) (result, int) {
+		t.Helper()
+		body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+			`<u:Browse xmlns:u="` + ContentDirectory + `"><ObjectID>` + id + `</ObjectID><BrowseFlag>` + flag + `</BrowseFlag>` +
+			`<Filter>*</Filter><StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount><SortCriteria></SortCriteria></u:Browse>` +
+			`</s:Body></s:Envelope>`
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/control/ContentDirectory", strings.NewReader(body))
+		req.Header.Set("SOAPACTION", `"`+ContentDirectory+`#Browse"`)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		var env struct {
+			Body struct {
+				Response struct {
+					Result string
+				} `xml:"BrowseResponse"`
+			} `xml:"Body"`
+		}
+		if err := xml.NewDecoder(res.Body).Decode(&env); err != nil {
+			t.Fatalf("decode soap response: %v", err)
+		}
+		var doc result
+		if env.Body.Response.Result != "" {
+			if err := xml.Unmarshal([]byte(env.Body.Response.Result), &doc); err != nil {
+				t.Fatalf("decode didl-lite: %v", err)
+			}
+		}
+		return doc, res.StatusCode
+	}
+
+	t.Run("root", func(t *testing.T) {
+		doc, status := browse(t, "0", "BrowseDirectChildren")
+		if status != http.StatusOK || len(doc.Containers) != 1 {
+			t.Fatalf("status = %d, containers = %+v", status, doc.Containers)
+		}
+		if c := doc.Containers[0]; c.ID != seeder.InfoHash || c.Title != "Show" || c.ChildCount != 2 || c.Class != classFolder {
+			t.Errorf("container = %+v", c)
+		}
+	})
+
+	var url string
+	t.Run("torrent", func(t *testing.T) {
+		doc, status := browse(t, seeder.InfoHash, "BrowseDirectChildren")
+		if status != http.StatusOK || len(doc.Items) != 2 {
+			t.Fatalf("status = %d, items = %+v", status, doc.Items)
+		}
+		// файлы отсортированы по пути: 0 - Season 1/Show.S01E02.mkv, 1 - Season 1/Show.S01E10.mkv
+		first := doc.Items[0]
+		if first.Title != "S01E02 - Show.S01E02" || first.ID != seeder.InfoHash+"/0" || first.Class != classVideo {
+			t.Errorf("first item = %+v", first)
+		}
+		if !strings.HasPrefix(first.Res.ProtocolInfo, "http-get:*:video/x-matroska:DLNA.ORG_OP=01") || first.Res.Size != 64<<10 {
+			t.Errorf("res = %+v", first.Res)
+		}
+		url = first.Res.URL
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		if url != srv.URL+"/stream/"+seeder.InfoHash+"/0" {
+			t.Fatalf("url = %q", url)
+		}
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Range", "bytes=100-199")
+		req.Header.Set("getcontentFeatures.dlna.org", "1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusPartialContent || len(body) != 100 {
+			t.Errorf("status = %d, body = %d bytes", res.StatusCode, len(body))
+		}
+		if got := res.Header.Get("contentFeatures.dlna.org"); got != dlnaFeatures {
+			t.Errorf("contentFeatures.dlna.org = %q", got)
+		}
+	})
+
+	t.Run("no such object", func(t *testing.T) {
+		if _, status := browse(t, "deadbeef", "BrowseMetadata"); status != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500 with UPnP fault", status)
+		}
+	})
+}
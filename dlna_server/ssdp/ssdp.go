@@ -0,0 +1,316 @@
+// Package ssdp - обнаружение устройства в локальной сети по SSDP (UPnP Device Architecture 1.0):
+// ответы на M-SEARCH и периодические объявления NOTIFY ssdp:alive, при остановке - ssdp:byebye.
+package ssdp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"torrentServer/internal/lib/logger/sl"
+)
+
+// DefaultAddress - multicast-группа и порт SSDP
+const DefaultAddress = "239.255.255.250:1900"
+
+// Типы поиска, на которые отвечает любое устройство
+const (
+	All        = "ssdp:all"
+	RootDevice = "upnp:rootdevice"
+)
+
+// максимальная задержка ответа на M-SEARCH, больше MX спецификация не требует ждать
+const maxDelay = 5 * time.Second
+
+// Config - что объявлять в сети
+type Config struct {
+	Address   string   // multicast-группа или обычный адрес (для тестов)
+	Interface string   // интерфейс для multicast, пусто - выбирает система
+	UUID      string   // без префикса uuid:
+	Types     []string // тип устройства и типы служб, например urn:schemas-upnp-org:device:MediaServer:1
+	Server    string   // заголовок SERVER
+	MaxAge    time.Duration
+	// Location возвращает адрес описания устройства для клиента,
+	// которому виден локальный адрес local
+	Location func(local net.IP) string
+}
+
+type Server struct {
+	log   *slog.Logger
+	cfg   Config
+	conn  *net.UDPConn
+	group *net.UDPAddr
+}
+
+// Listen открывает UDP-сокет SSDP. Для multicast-адреса сервер вступает в группу,
+// для обычного адреса слушает только его - так поиск можно проверить на loopback.
+func Listen(log *slog.Logger, cfg Config) (*Server, error) {
+	const op = "dlna.ssdp.Listen"
+
+	group, err := net.ResolveUDPAddr("udp4", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var conn *net.UDPConn
+	if group.IP.IsMulticast() {
+		var ifi *net.Interface
+		if cfg.Interface != "" {
+			if ifi, err = net.InterfaceByName(cfg.Interface); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		conn, err = net.ListenMulticastUDP("udp4", ifi, group)
+	} else {
+		conn, err = net.ListenUDP("udp4", group)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if group.Port == 0 {
+		group = conn.LocalAddr().(*net.UDPAddr)
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 30 * time.Minute
+	}
+
+	return &Server{
+		log:   log.With(slog.String("component", "dlna/ssdp")),
+		cfg:   cfg,
+		conn:  conn,
+		group: group,
+	}, nil
+}
+
+// Addr - адрес, на котором сервер принимает M-SEARCH
+func (s *Server) Addr() net.Addr {
+	return s.group
+}
+
+// Serve отвечает на запросы поиска и повторяет объявления каждые max_age/3, пока не отменён ctx.
+// При остановке рассылает ssdp:byebye и закрывает сокет.
+func (s *Server) Serve(ctx context.Context) error {
+	go func() {
+		s.notify(NotifyAlive)
+
+		ticker := time.NewTicker(s.cfg.MaxAge / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.notify(NotifyByeBye)
+				s.conn.Close()
+				return
+			case <-ticker.C:
+				s.notify(NotifyAlive)
+			}
+		}
+	}()
+
+	buf := make([]byte, 2048)
+	for {
+		n, remote, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("dlna.ssdp.Serve: %w", err)
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" {
+			// NOTIFY других устройств и мусор
+			continue
+		}
+
+		responses, delay := s.search(req)
+		if len(responses) == 0 {
+			continue
+		}
+		go func() {
+			// клиенты ждут ответов MX секунд, задержка разносит ответы устройств по времени
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			s.reply(remote, responses)
+		}()
+	}
+}
+
+// search возвращает типы, о которых нужно ответить на M-SEARCH, и задержку ответа
+func (s *Server) search(req *http.Request) ([]string, time.Duration) {
+	if strings.Trim(req.Header.Get("MAN"), `"`) != "ssdp:discover" {
+		return nil, 0
+	}
+
+	var delay time.Duration
+	if mx, err := strconv.Atoi(req.Header.Get("MX")); err == nil && mx > 0 {
+		delay = min(time.Duration(mx)*time.Second, maxDelay)
+		delay = rand.N(delay)
+	}
+
+	st := req.Header.Get("ST")
+	switch st {
+	case All:
+		return s.types(), delay
+	case RootDevice, "uuid:" + s.cfg.UUID:
+		return []string{st}, delay
+	}
+	for _, t := range s.cfg.Types {
+		if t == st {
+			return []string{st}, delay
+		}
+	}
+	return nil, 0
+}
+
+// types - всё, что устройство объявляет: корневое устройство, его uuid, тип устройства и службы
+func (s *Server) types() []string {
+	return append([]string{RootDevice, "uuid:" + s.cfg.UUID}, s.cfg.Types...)
+}
+
+func (s *Server) usn(nt string) string {
+	if nt == "uuid:"+s.cfg.UUID {
+		return nt
+	}
+	return "uuid:" + s.cfg.UUID + "::" + nt
+}
+
+func (s *Server) reply(remote *net.UDPAddr, types []string) {
+	location := s.cfg.Location(localIP(remote))
+	for _, st := range types {
+		msg := "HTTP/1.1 200 OK\r\n" +
+			"CACHE-CONTROL: max-age=" + strconv.Itoa(int(s.cfg.MaxAge.Seconds())) + "\r\n" +
+			"DATE: " + time.Now().UTC().Format(http.TimeFormat) + "\r\n" +
+			"EXT:\r\n" +
+			"LOCATION: " + location + "\r\n" +
+			"SERVER: " + s.cfg.Server + "\r\n" +
+			"ST: " + st + "\r\n" +
+			"USN: " + s.usn(st) + "\r\n" +
+			"\r\n"
+		if _, err := s.conn.WriteToUDP([]byte(msg), remote); err != nil {
+			s.log.Debug("failed to answer m-search", slog.String("remote", remote.String()), sl.Err(err))
+			return
+		}
+	}
+}
+
+// Значения NTS в объявлениях
+const (
+	NotifyAlive  = "ssdp:alive"
+	NotifyByeBye = "ssdp:byebye"
+)
+
+func (s *Server) notify(nts string) {
+	location := s.cfg.Location(localIP(s.group))
+	for _, nt := range s.types() {
+		msg := "NOTIFY * HTTP/1.1\r\n" +
+			"HOST: " + s.group.String() + "\r\n" +
+			"NT: " + nt + "\r\n" +
+			"NTS: " + nts + "\r\n" +
+			"USN: " + s.usn(nt) + "\r\n"
+		if nts == NotifyAlive {
+			msg += "CACHE-CONTROL: max-age=" + strconv.Itoa(int(s.cfg.MaxAge.Seconds())) + "\r\n" +
+				"LOCATION: " + location + "\r\n" +
+				"SERVER: " + s.cfg.Server + "\r\n"
+		}
+		msg += "\r\n"
+
+		if _, err := s.conn.WriteToUDP([]byte(msg), s.group); err != nil {
+			s.log.Warn("failed to send ssdp notify", slog.String("nts", nts), sl.Err(err))
+			return
+		}
+	}
+}
+
+// localIP - адрес этой машины, с которого уходят пакеты к remote. UDP-сокет
+// ничего не отправляет при Dial, система только выбирает маршрут.
+func localIP(remote *net.UDPAddr) net.IP {
+	conn, err := net.DialUDP("udp4", nil, remote)
+	if err != nil {
+		return net.IPv4zero
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
+
+// Service - ответ устройства на M-SEARCH
+type Service struct {
+	ST       string
+	USN      string
+	Location string
+	Server   string
+}
+
+// Discover отправляет M-SEARCH на addr и собирает ответы, пока не отменён ctx.
+// Повторяющиеся USN отбрасываются.
+func Discover(ctx context.Context, addr, st string) ([]Service, error) {
+	const op = "dlna.ssdp.Discover"
+
+	raddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	msg := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + raddr.String() + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: " + st + "\r\n" +
+		"\r\n"
+	if _, err := conn.WriteToUDP([]byte(msg), raddr); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+
+	var services []Service
+	seen := make(map[string]bool)
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return services, nil
+			}
+			return services, fmt.Errorf("%s: %w", op, err)
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil || res.StatusCode != http.StatusOK {
+			continue
+		}
+		usn := res.Header.Get("USN")
+		if seen[usn] {
+			continue
+		}
+		seen[usn] = true
+
+		services = append(services, Service{
+			ST:       res.Header.Get("ST"),
+			USN:      usn,
+			Location: res.Header.Get("LOCATION"),
+			Server:   res.Header.Get("SERVER"),
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package ssdp
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+)
+
+const (
+	testUUID   = "4d696e69-444c-164e-9d41-001ec92f0d5e"
+	mediaType  = "urn:schemas-upnp-org:device:MediaServer:1"
+	directory  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	connection = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+func TestDiscover(t *testing.T) {
+	// обычный адрес вместо multicast-группы: поиск проверяется без доступа к сети
+	srv, err := Listen(slogdiscard.NewDiscardLogger(), Config{
+		Address: "127.0.0.1:0",
+		UUID:    testUUID,
+		Types:   []string{mediaType, directory, connection},
+		Server:  "Linux UPnP/1.0 torrentServer/1.0",
+		Location: func(local net.IP) string {
+			return "http://" + local.String() + ":8200/description.xml"
+		},
+	})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- srv.Serve(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}()
+
+	discover := func(t *testing.T, st string) []Service {
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+		services, err := Discover(ctx, srv.Addr().String(), st)
+		if err != nil {
+			t.Fatalf("Discover(%q) error = %v", st, err)
+		}
+		return services
+	}
+
+	t.Run("device type", func(t *testing.T) {
+		services := discover(t, mediaType)
+		if len(services) != 1 {
+			t.Fatalf("got %d services, want 1: %+v", len(services), services)
+		}
+		want := Service{
+			ST:       mediaType,
+			USN:      "uuid:" + testUUID + "::" + mediaType,
+			Location: "http://127.0.0.1:8200/description.xml",
+			Server:   "Linux UPnP/1.0 torrentServer/1.0",
+		}
+		if services[0] != want {
+			t.Errorf("got %+v, want %+v", services[0], want)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		var got []string
+		for _, s := range discover(t, All) {
+			got = append(got, s.USN)
+		}
+		slices.Sort(got)
+		want := []string{
+			"uuid:" + testUUID,
+			"uuid:" + testUUID + "::" + mediaType,
+			"uuid:" + testUUID + "::" + connection,
+			"uuid:" + testUUID + "::" + directory,
+			"uuid:" + testUUID + "::" + RootDevice,
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("other device", func(t *testing.T) {
+		if services := discover(t, "urn:schemas-upnp-org:device:MediaRenderer:1"); len(services) != 0 {
+			t.Errorf("got %+v, want no answer", services)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	searchv1 "torrentServer/api/search/v1"
+	"torrentServer/http_server/handlers/search"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/jackett"
+)
+
+// Scope - путь HTTP API, права на который открывают SearchService
+const Scope = "/search"
+
+// Searcher - общий с HTTP конвейер поиска (search.Handler)
+type Searcher interface {
+	Search(ctx context.Context, p search.Params) (search.Result, error)
+}
+
+// IndexerLister возвращает индексаторы Jackett
+type IndexerLister interface {
+	Indexers(ctx context.Context) ([]jackett.IndexerInfo, error)
+}
+
+type Server struct {
+	searchv1.UnimplementedSearchServiceServer
+
+	log      *slog.Logger
+	searcher Searcher
+	indexers IndexerLister
+}
+
+func New(log *slog.Logger, searcher Searcher, indexers IndexerLister) *Server {
+	return &Server{
+		log:      log.With(slog.String("component", "grpc/search")),
+		searcher: searcher,
+		indexers: indexers,
+	}
+}
+
+// Register регистрирует сервис на gRPC-сервере
+func (s *Server) Register(srv *grpc.Server) {
+	searchv1.RegisterSearchServiceServer(srv, s)
+}
+
+func (s *Server) Search(ctx context.Context, req *searchv1.SearchRequest) (*searchv1.SearchResponse, error) {
+	res, err := s.search(ctx, req.GetQuery(), req.GetCategories(), req.GetSafeOnly(), req.GetSource())
+	if err != nil {
+		return nil, err
+	}
+
+	// те же значения по умолчанию, что и у параметров page и per_page в HTTP
+	page, perPage := int(req.GetPage()), int(req.GetPerPage())
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	data, totalPages := search.Paginate(res.Results, page, perPage)
+
+	torrents, err := toTorrents(data)
+	if err != nil {
+		s.log.Error("failed to convert search results", sl.Err(err))
+		return nil, status.Error(codes.Internal, "failed to convert search results")
+	}
+
+	return &searchv1.SearchResponse{
+		Torrents:   torrents,
+		Page:       uint32(page),
+		PerPage:    uint32(perPage),
+		TotalItems: uint32(len(res.Results)),
+		TotalPages: uint32(totalPages),
+		Source:     toSource(res.Source),
+		Warning:    res.Warning,
+	}, nil
+}
+
+func (s *Server) SearchStream(req *searchv1.SearchStreamRequest, stream grpc.ServerStreamingServer[searchv1.SearchStreamResponse]) error {
+	res, err := s.search(stream.Context(), req.GetQuery(), req.GetCategories(), req.GetSafeOnly(), req.GetSource())
+	if err != nil {
+		return err
+	}
+
+	torrents, err := toTorrents(res.Results)
+	if err != nil {
+		s.log.Error("failed to convert search results", sl.Err(err))
+		return status.Error(codes.Internal, "failed to convert search results")
+	}
+
+	if err := stream.Send(&searchv1.SearchStreamResponse{
+		Event: &searchv1.SearchStreamResponse_Summary{Summary: &searchv1.SearchSummary{
+			TotalItems: uint32(len(torrents)),
+			Source:     toSource(res.Source),
+			Warning:    res.Warning,
+		}},
+	}); err != nil {
+		return err
+	}
+
+	for _, t := range torrents {
+		if err := stream.Send(&searchv1.SearchStreamResponse{
+			Event: &searchv1.SearchStreamResponse_Torrent{Torrent: t},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) GetIndexers(ctx context.Context, _ *searchv1.GetIndexersRequest) (*searchv1.GetIndexersResponse, error) {
+	indexers, err := s.indexers.Indexers(ctx)
+	if err != nil {
+		s.log.Error("failed to get indexers", sl.Err(err))
+		return nil, status.Error(codes.Unavailable, "jackett is unavailable")
+	}
+
+	resp := &searchv1.GetIndexersResponse{Indexers: make([]*searchv1.Indexer, 0, len(indexers))}
+	for _, idx := range indexers {
+		resp.Indexers = append(resp.Indexers, &searchv1.Indexer{
+			Id:          idx.ID,
+			Name:        idx.Name,
+			Description: idx.Description,
+			Type:        idx.Type,
+			Language:    idx.Language,
+		})
+	}
+
+	return resp, nil
+}
+
+// search проверяет параметры так же, как HTTP-обработчик, и запускает общий конвейер
+func (s *Server) search(ctx context.Context, query string, categories []uint32, safeOnly bool, source searchv1.Source) (search.Result, error) {
+	if query == "" || len(categories) == 0 {
+		return search.Result{}, status.Error(codes.InvalidArgument, "query and categories parameters are required")
+	}
+
+	p := search.Params{
+		Query:      query,
+		Categories: make([]uint, 0, len(categories)),
+	}
+	for _, c := range categories {
+		p.Categories = append(p.Categories, uint(c))
+	}
+	if safeOnly {
+		p.SafeOnly = 1
+	}
+	switch source {
+	case searchv1.Source_SOURCE_UNSPECIFIED, searchv1.Source_SOURCE_JACKETT:
+		p.Source = search.SourceJackett
+	case searchv1.Source_SOURCE_LOCAL:
+		p.Source = search.SourceLocal
+	default:
+		return search.Result{}, status.Errorf(codes.InvalidArgument, "invalid source %v", source)
+	}
+
+	res, err := s.searcher.Search(ctx, p)
+	if errors.Is(err, search.ErrInvalidSource) {
+		return search.Result{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil && ctx.Err() != nil {
+		return search.Result{}, status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		// ошибку уже записал в лог конвейер поиска
+		return search.Result{}, status.Error(codes.Internal, err.Error())
+	}
+
+	return res, nil
+}
+
+// toTorrents переводит результаты конвейера (JSON-объекты jackett.SimpleResult) в сообщения protobuf
+func toTorrents(results []map[string]interface{}) ([]*searchv1.Torrent, error) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	var simple []jackett.SimpleResult
+	if err := json.Unmarshal(data, &simple); err != nil {
+		return nil, err
+	}
+
+	torrents := make([]*searchv1.Torrent, 0, len(simple))
+	for _, r := range simple {
+		categories := make([]uint32, 0, len(r.Category))
+		for _, c := range r.Category {
+			categories = append(categories, uint32(c))
+		}
+		torrents = append(torrents, &searchv1.Torrent{
+			Title:       r.Title,
+			Categories:  categories,
+			MagnetUri:   r.MagnetUri,
+			Seeders:     uint32(r.Seeders),
+			Peers:       uint32(r.Peers),
+			Size:        uint64(r.Size),
+			Description: r.Description,
+			Tracker:     r.Tracker,
+		})
+	}
+
+	return torrents, nil
+}
+
+func toSource(source string) searchv1.Source {
+	if source == search.SourceLocal {
+		return searchv1.Source_SOURCE_LOCAL
+	}
+	return searchv1.Source_SOURCE_JACKETT
+}
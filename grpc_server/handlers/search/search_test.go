@@ -0,0 +1,242 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	searchv1 "torrentServer/api/search/v1"
+	icAuth "torrentServer/grpc_server/interceptor/auth"
+	"torrentServer/grpc_server/server"
+	"torrentServer/http_server/handlers/search"
+	mwAuth "torrentServer/http_server/midleware/auth"
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	authService "torrentServer/internal/services/auth"
+	"torrentServer/internal/services/jackett"
+	"torrentServer/internal/storage"
+)
+
+type mockSearcher struct {
+	params   search.Params
+	policy   string
+	deadline time.Time
+}
+
+func (m *mockSearcher) Search(ctx context.Context, p search.Params) (search.Result, error) {
+	m.params = p
+	m.deadline, _ = ctx.Deadline()
+	if key, ok := mwAuth.KeyFromContext(ctx); ok {
+		m.policy = key.Policy
+	}
+
+	results := make([]map[string]interface{}, 0, 25)
+	for i := 1; i <= 25; i++ {
+		results = append(results, map[string]interface{}{
+			"title":     fmt.Sprintf("Test%d", i),
+			"category":  []interface{}{2000},
+			"magnetUri": fmt.Sprintf("magnet:?xt=urn:btih:%d", i),
+			"Seeders":   i,
+			"Size":      1024,
+		})
+	}
+	return search.Result{Results: results, Source: search.SourceJackett}, nil
+}
+
+type mockIndexers struct{}
+
+func (mockIndexers) Indexers(context.Context) ([]jackett.IndexerInfo, error) {
+	return []jackett.IndexerInfo{{ID: "archive", Name: "Internet Archive", Type: "public"}}, nil
+}
+
+type mockAuth struct{}
+
+func (mockAuth) Authenticate(rawKey, path string) (storage.APIKey, error) {
+	switch rawKey {
+	case "":
+		return storage.APIKey{}, authService.ErrNoKey
+	case "search":
+		key := storage.APIKey{ID: "1", Endpoints: []string{"/search"}, Policy: storage.PolicySafe}
+		if !authService.Allowed(key, path) {
+			return key, authService.ErrForbidden
+		}
+		return key, nil
+	default:
+		return storage.APIKey{}, authService.ErrInvalidKey
+	}
+}
+
+func newClient(t *testing.T, searcher Searcher) searchv1.SearchServiceClient {
+	t.Helper()
+
+	log := slogdiscard.NewDiscardLogger()
+	scopes := icAuth.Scopes{searchv1.SearchService_ServiceDesc.ServiceName: Scope}
+	srv := server.New(log, config.GRPCServer{}, time.Minute,
+		[]grpc.UnaryServerInterceptor{icAuth.Unary(log, mockAuth{}, scopes)},
+		[]grpc.StreamServerInterceptor{icAuth.Stream(log, mockAuth{}, scopes)},
+	)
+	New(log, searcher, mockIndexers{}).Register(srv)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return searchv1.NewSearchServiceClient(conn)
+}
+
+func withKey(rawKey string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), icAuth.MetadataAPIKey, rawKey)
+}
+
+func TestSearch(t *testing.T) {
+	searcher := &mockSearcher{}
+	client := newClient(t, searcher)
+
+	resp, err := client.Search(withKey("search"), &searchv1.SearchRequest{
+		Query:      "test",
+		Categories: []uint32{2000},
+		Page:       2,
+		PerPage:    10,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if resp.TotalItems != 25 || resp.TotalPages != 3 || len(resp.Torrents) != 10 {
+		t.Errorf("got total_items=%d total_pages=%d torrents=%d, want 25, 3, 10", resp.TotalItems, resp.TotalPages, len(resp.Torrents))
+	}
+	if got := resp.Torrents[0]; got.Title != "Test11" || got.Seeders != 11 || got.Size != 1024 || len(got.Categories) != 1 {
+		t.Errorf("unexpected first torrent: %v", got)
+	}
+	if resp.Source != searchv1.Source_SOURCE_JACKETT {
+		t.Errorf("source = %v, want %v", resp.Source, searchv1.Source_SOURCE_JACKETT)
+	}
+	if searcher.params.Source != search.SourceJackett || searcher.policy != storage.PolicySafe {
+		t.Errorf("pipeline got source=%q policy=%q", searcher.params.Source, searcher.policy)
+	}
+	// клиент не передал дедлайн, сервер ограничил вызов своим таймаутом
+	if left := time.Until(searcher.deadline); left <= 0 || left > time.Minute {
+		t.Errorf("deadline in %v, want server timeout of 1m", left)
+	}
+
+	// дедлайн клиента не продлевается
+	ctx, cancel := context.WithTimeout(withKey("search"), 5*time.Second)
+	defer cancel()
+	if _, err := client.Search(ctx, &searchv1.SearchRequest{Query: "test", Categories: []uint32{2000}}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if left := time.Until(searcher.deadline); left <= 0 || left > 5*time.Second {
+		t.Errorf("deadline in %v, want client deadline of 5s", left)
+	}
+}
+
+func TestSearchStream(t *testing.T) {
+	searcher := &mockSearcher{}
+	client := newClient(t, searcher)
+
+	stream, err := client.SearchStream(withKey("search"), &searchv1.SearchStreamRequest{Query: "test", Categories: []uint32{2000}})
+	if err != nil {
+		t.Fatalf("SearchStream() error = %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if first.GetSummary().GetTotalItems() != 25 {
+		t.Fatalf("first message = %v, want summary with 25 items", first)
+	}
+
+	count := 0
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if msg.GetTorrent() == nil {
+			t.Fatalf("message %d is not a torrent: %v", count, msg)
+		}
+		count++
+	}
+	if count != 25 {
+		t.Errorf("received %d torrents, want 25", count)
+	}
+	if searcher.deadline.IsZero() {
+		t.Error("stream without client deadline got no server timeout")
+	}
+}
+
+func TestAuth(t *testing.T) {
+	client := newClient(t, &mockSearcher{})
+	req := &searchv1.SearchRequest{Query: "test", Categories: []uint32{2000}}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"no key", context.Background(), codes.Unauthenticated},
+		{"invalid key", withKey("wrong"), codes.Unauthenticated},
+		{"valid key", withKey("search"), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Search(tt.ctx, req)
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("code = %v, want %v", got, tt.code)
+			}
+		})
+	}
+
+	stream, err := client.SearchStream(context.Background(), &searchv1.SearchStreamRequest{Query: "test", Categories: []uint32{2000}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if got := status.Code(err); got != codes.Unauthenticated {
+		t.Errorf("stream without key: code = %v, want %v", got, codes.Unauthenticated)
+	}
+}
+
+func TestInvalidArgument(t *testing.T) {
+	client := newClient(t, &mockSearcher{})
+
+	_, err := client.Search(withKey("search"), &searchv1.SearchRequest{Query: "test"})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("code = %v, want %v", got, codes.InvalidArgument)
+	}
+}
+
+func TestGetIndexers(t *testing.T) {
+	client := newClient(t, &mockSearcher{})
+
+	resp, err := client.GetIndexers(withKey("search"), &searchv1.GetIndexersRequest{})
+	if err != nil {
+		t.Fatalf("GetIndexers() error = %v", err)
+	}
+	if len(resp.Indexers) != 1 || resp.Indexers[0].Id != "archive" || resp.Indexers[0].Type != "public" {
+		t.Errorf("unexpected indexers: %v", resp.Indexers)
+	}
+}
@@ -0,0 +1,100 @@
+// grpc_server/interceptor/auth/auth.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	"torrentServer/internal/lib/logger/sl"
+	authService "torrentServer/internal/services/auth"
+)
+
+// MetadataAPIKey - ключ передаётся в метаданных вызова, как заголовок X-API-Key в HTTP
+var MetadataAPIKey = strings.ToLower(mwAuth.HeaderAPIKey)
+
+// Scopes сопоставляет gRPC-сервис пути HTTP API, права ключей на который действуют и для сервиса.
+// Например, ключ с правом "/search" может вызывать все методы SearchService.
+// Методы сервисов, которых нет в Scopes, доступны только ключам с правом "*".
+type Scopes map[string]string
+
+func (s Scopes) path(fullMethod string) string {
+	// fullMethod имеет вид /package.Service/Method
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if path, ok := s[service]; ok {
+		return path
+	}
+	return fullMethod
+}
+
+func Unary(log *slog.Logger, auth mwAuth.Authenticator, scopes Scopes) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/auth"))
+	log.Info("grpc auth interceptor enabled")
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, log, auth, scopes.path(info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func Stream(log *slog.Logger, auth mwAuth.Authenticator, scopes Scopes) grpc.StreamServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/auth"))
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), log, auth, scopes.path(info.FullMethod))
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate проверяет ключ и кладёт его в контекст так же, как middleware/auth
+func authenticate(ctx context.Context, log *slog.Logger, auth mwAuth.Authenticator, path string) (context.Context, error) {
+	rawKey := ""
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataAPIKey); len(values) > 0 {
+		rawKey = values[0]
+	}
+
+	key, err := auth.Authenticate(rawKey, path)
+	if err != nil {
+		entry := log.With(slog.String("path", path), slog.String("key_id", key.ID))
+
+		switch {
+		case errors.Is(err, authService.ErrNoKey), errors.Is(err, authService.ErrInvalidKey):
+			entry.Info("unauthorized call", sl.Err(err))
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, authService.ErrForbidden):
+			entry.Info("forbidden call", sl.Err(err))
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, authService.ErrQuotaExceeded):
+			entry.Info("quota exceeded", sl.Err(err))
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		default:
+			entry.Error("failed to authenticate call", sl.Err(err))
+			return nil, status.Error(codes.Internal, "failed to authenticate request")
+		}
+	}
+
+	return mwAuth.WithPath(mwAuth.WithKey(ctx, key), path), nil
+}
+
+// serverStream подменяет контекст потока, чтобы обработчик видел ключ
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,50 @@
+// grpc_server/interceptor/logger/logger.go
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Unary пишет в лог каждый вызов так же, как middleware/logger для HTTP
+func Unary(log *slog.Logger) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/logger"))
+	log.Info("grpc logger interceptor enabled")
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t1 := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, log, info.FullMethod, t1, err)
+		return resp, err
+	}
+}
+
+func Stream(log *slog.Logger) grpc.StreamServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/logger"))
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t1 := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), log, info.FullMethod, t1, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, log *slog.Logger, method string, t1 time.Time, err error) {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	log.Info("grpc call completed",
+		slog.String("method", method),
+		slog.String("remote_addr", remoteAddr),
+		slog.String("code", status.Code(err).String()),
+		slog.String("duration", time.Since(t1).String()),
+	)
+}
@@ -0,0 +1,134 @@
+// grpc_server/interceptor/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	mwRateLimit "torrentServer/http_server/midleware/ratelimit"
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/sl"
+)
+
+type bucket struct {
+	key   string
+	limit config.Limit
+}
+
+// bucketFunc выбирает счётчик для вызова, false - вызов этим лимитом не ограничивается
+type bucketFunc func(ctx context.Context) (bucket, bool)
+
+// perIP - лимит по IP, общий для HTTP и gRPC
+func perIP(cfg config.RateLimit) bucketFunc {
+	return func(ctx context.Context) (bucket, bool) {
+		return bucket{"ip:" + clientIP(ctx), cfg.PerIP}, true
+	}
+}
+
+// perKey - лимит по API-ключу, общий для HTTP и gRPC
+func perKey(cfg config.RateLimit) bucketFunc {
+	return func(ctx context.Context) (bucket, bool) {
+		key, ok := mwAuth.KeyFromContext(ctx)
+		return bucket{"key:" + key.ID, cfg.PerKey}, ok
+	}
+}
+
+// refund возвращает в суточную квоту ключа отклонённый вызов, как middleware/ratelimit.PerKey
+func refund(quota mwAuth.Refunder) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return mwAuth.Refund(ctx, quota)
+	}
+}
+
+// UnaryPerIP ограничивает частоту вызовов по IP теми же счётчиками, что и middleware/ratelimit.
+// Ставится перед перехватчиком авторизации, чтобы перебор ключей тоже расходовал лимит.
+func UnaryPerIP(log *slog.Logger, limiter mwRateLimit.Limiter, cfg config.RateLimit) grpc.UnaryServerInterceptor {
+	return unary(log, limiter, "ip", perIP(cfg), nil)
+}
+
+// UnaryPerKey ограничивает частоту вызовов по API-ключу, ставится после перехватчика авторизации.
+// Отклонённый вызов возвращается в суточную квоту ключа через quota.
+func UnaryPerKey(log *slog.Logger, limiter mwRateLimit.Limiter, cfg config.RateLimit, quota mwAuth.Refunder) grpc.UnaryServerInterceptor {
+	return unary(log, limiter, "key", perKey(cfg), refund(quota))
+}
+
+func StreamPerIP(log *slog.Logger, limiter mwRateLimit.Limiter, cfg config.RateLimit) grpc.StreamServerInterceptor {
+	return stream(log, limiter, "ip", perIP(cfg), nil)
+}
+
+func StreamPerKey(log *slog.Logger, limiter mwRateLimit.Limiter, cfg config.RateLimit, quota mwAuth.Refunder) grpc.StreamServerInterceptor {
+	return stream(log, limiter, "key", perKey(cfg), refund(quota))
+}
+
+func unary(log *slog.Logger, limiter mwRateLimit.Limiter, kind string, bucketOf bucketFunc, rejected func(ctx context.Context) error) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/ratelimit"), slog.String("limit", kind))
+	log.Info("grpc rate limit interceptor enabled")
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, log, limiter, bucketOf, rejected); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func stream(log *slog.Logger, limiter mwRateLimit.Limiter, kind string, bucketOf bucketFunc, rejected func(ctx context.Context) error) grpc.StreamServerInterceptor {
+	log = log.With(slog.String("component", "interceptor/ratelimit"), slog.String("limit", kind))
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(ss.Context(), log, limiter, bucketOf, rejected); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allow проверяет лимит, если Redis недоступен - вызов пропускается.
+// Для отклонённого вызова вызывается rejected, если он задан.
+func allow(ctx context.Context, log *slog.Logger, limiter mwRateLimit.Limiter, bucketOf bucketFunc, rejected func(ctx context.Context) error) error {
+	c, ok := bucketOf(ctx)
+	if !ok {
+		return nil
+	}
+
+	res, err := limiter.Allow(ctx, c.key, c.limit)
+	if err != nil {
+		log.Error("failed to check rate limit", sl.Err(err))
+		return nil
+	}
+	if !res.Allowed {
+		log.Info("rate limit exceeded", slog.String("bucket", c.key))
+		if rejected != nil {
+			if err := rejected(ctx); err != nil {
+				log.Error("failed to refund rejected call", sl.Err(err))
+			}
+		}
+		retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %d seconds", retryAfter)
+	}
+
+	return nil
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,144 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	authService "torrentServer/internal/services/auth"
+	"torrentServer/internal/storage"
+)
+
+type KeyManager interface {
+	Issue(name string, endpoints []string, dailyQuota int, policy string) (storage.APIKey, string, error)
+	List() ([]storage.APIKey, error)
+	Revoke(id string) error
+}
+
+type Request struct {
+	Name       string   `json:"name"`
+	Endpoints  []string `json:"endpoints"`
+	DailyQuota int      `json:"daily_quota"`
+	Policy     string   `json:"policy"`
+}
+
+type KeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"` // отдаётся только при выпуске ключа
+	Endpoints  []string   `json:"endpoints"`
+	DailyQuota int        `json:"daily_quota"`
+	Policy     string     `json:"policy"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type Handler struct {
+	log  *slog.Logger
+	keys KeyManager
+}
+
+func New(log *slog.Logger, keys KeyManager) *Handler {
+	return &Handler{
+		log:  log.With(slog.String("component", "handlers/apikeys")),
+		keys: keys,
+	}
+}
+
+// Routes - администрирование ключей: выпуск, список и отзыв
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Issue)
+	r.Get("/", h.List)
+	r.Delete("/{id}", h.Revoke)
+	return r
+}
+
+func (h *Handler) Issue(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "failed to decode request body")
+		return
+	}
+
+	key, rawKey, err := h.keys.Issue(req.Name, req.Endpoints, req.DailyQuota, req.Policy)
+	if errors.Is(err, authService.ErrEmptyName) || errors.Is(err, authService.ErrEmptyEndpoints) ||
+		errors.Is(err, authService.ErrInvalidPolicy) {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to issue api key", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to issue api key")
+		return
+	}
+
+	h.log.Info("api key issued", slog.String("id", key.ID), slog.String("name", key.Name))
+
+	created := toResponse(key)
+	created.Key = rawKey
+
+	resp.JSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List()
+	if err != nil {
+		h.log.Error("failed to list api keys", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to list api keys")
+		return
+	}
+
+	list := make([]KeyResponse, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, toResponse(k))
+	}
+
+	resp.JSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.keys.Revoke(id)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, authService.ErrConfigKey) {
+		resp.WriteError(w, r, http.StatusConflict, resp.CodeConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to revoke api key", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to revoke api key")
+		return
+	}
+
+	h.log.Info("api key revoked", slog.String("id", id))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toResponse(k storage.APIKey) KeyResponse {
+	kr := KeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Endpoints:  k.Endpoints,
+		DailyQuota: k.DailyQuota,
+		Policy:     k.Policy,
+	}
+	if !k.CreatedAt.IsZero() {
+		kr.CreatedAt = &k.CreatedAt
+	}
+	if !k.RevokedAt.IsZero() {
+		kr.RevokedAt = &k.RevokedAt
+	}
+	return kr
+}
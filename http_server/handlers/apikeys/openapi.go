@@ -0,0 +1,50 @@
+package apikeys
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Describe добавляет в документ OpenAPI описание администрирования ключей
+func Describe(doc *openapi.Document) {
+	req := doc.AddSchema("APIKeyRequest", Request{})
+	req.Required = []string{"name", "endpoints"}
+	req.Properties["policy"].Enum = []interface{}{"full", "safe"}
+	doc.AddSchema("APIKey", KeyResponse{})
+
+	issued := openapi.ErrorResponses("400", "401", "403", "500")
+	issued["201"] = &openapi.Response{
+		Description: "Issued key, the key value is returned only once",
+		Content:     openapi.JSONContent(openapi.Ref("APIKey")),
+	}
+	doc.AddOperation("POST", "/admin/keys", &openapi.Operation{
+		OperationID: "issueAPIKey",
+		Summary:     "Issue API key",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONContent(openapi.Ref("APIKeyRequest"))},
+		Responses:   issued,
+	})
+
+	list := openapi.ErrorResponses("401", "403", "500")
+	list["200"] = &openapi.Response{
+		Description: "All keys including revoked ones",
+		Content:     openapi.JSONContent(&openapi.Schema{Type: "array", Items: openapi.Ref("APIKey")}),
+	}
+	doc.AddOperation("GET", "/admin/keys", &openapi.Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "List API keys",
+		Tags:        []string{"admin"},
+		Responses:   list,
+	})
+
+	revoked := openapi.ErrorResponses("401", "403", "404", "409", "500")
+	revoked["204"] = &openapi.Response{Description: "Key revoked"}
+	doc.AddOperation("DELETE", "/admin/keys/{id}", &openapi.Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke API key",
+		Tags:        []string{"admin"},
+		Parameters: []openapi.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: revoked,
+	})
+}
@@ -0,0 +1,156 @@
+package bandwidth
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"torrentServer/internal/config"
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	bandwidthService "torrentServer/internal/services/bandwidth"
+)
+
+// Manager - ограничения скорости движка (bandwidth.Manager)
+type Manager interface {
+	Status() bandwidthService.Status
+	Update(cfg config.Bandwidth) (bandwidthService.Status, error)
+}
+
+// Request - ограничения в КБ/с, 0 - без ограничения. Заменяет все ограничения и расписания целиком.
+type Request struct {
+	DownloadKBps           int        `json:"download_kbps"`
+	UploadKBps             int        `json:"upload_kbps"`
+	PeerDownloadKBps       int        `json:"peer_download_kbps"` // на каждое соединение с пиром
+	PeerUploadKBps         int        `json:"peer_upload_kbps"`
+	TorrentDownloadKBps    int        `json:"torrent_download_kbps"` // средняя скорость раздачи за stats_interval, см. config.Bandwidth
+	TorrentUploadKBps      int        `json:"torrent_upload_kbps"`
+	BackgroundDownloadKBps int        `json:"background_download_kbps"` // раздачи без потоков, пока открыт хотя бы один поток
+	Schedules              []Schedule `json:"schedules"`
+}
+
+type Schedule struct {
+	Name         string   `json:"name"`
+	Days         []string `json:"days"` // mon, tue, ..., sun; пусто - каждый день
+	From         string   `json:"from"` // HH:MM по времени сервера
+	To           string   `json:"to"`
+	DownloadKBps int      `json:"download_kbps"`
+	UploadKBps   int      `json:"upload_kbps"`
+}
+
+type Response struct {
+	Config         Request        `json:"config"`                    // настроенные ограничения
+	ActiveSchedule string         `json:"active_schedule,omitempty"` // расписание, которое действует сейчас
+	Effective      LimitsResponse `json:"effective"`
+}
+
+// LimitsResponse - действующие сейчас ограничения в байтах в секунду, 0 - без ограничения
+type LimitsResponse struct {
+	Download           int64 `json:"download"`
+	Upload             int64 `json:"upload"`
+	PeerDownload       int64 `json:"peer_download"`
+	PeerUpload         int64 `json:"peer_upload"`
+	TorrentDownload    int64 `json:"torrent_download"`
+	TorrentUpload      int64 `json:"torrent_upload"`
+	BackgroundDownload int64 `json:"background_download"`
+}
+
+type Handler struct {
+	log     *slog.Logger
+	manager Manager
+}
+
+func New(log *slog.Logger, manager Manager) *Handler {
+	return &Handler{
+		log:     log.With(slog.String("component", "handlers/bandwidth")),
+		manager: manager,
+	}
+}
+
+// Routes - просмотр и замена ограничений скорости на ходу
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.Get)
+	r.Put("/", h.Update)
+	return r
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	resp.JSON(w, http.StatusOK, toResponse(h.manager.Status()))
+}
+
+// Update заменяет ограничения до перезапуска сервера, после перезапуска снова действует конфиг
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "failed to decode request body")
+		return
+	}
+
+	cfg := config.Bandwidth{
+		DownloadKBps:           req.DownloadKBps,
+		UploadKBps:             req.UploadKBps,
+		PeerDownloadKBps:       req.PeerDownloadKBps,
+		PeerUploadKBps:         req.PeerUploadKBps,
+		TorrentDownloadKBps:    req.TorrentDownloadKBps,
+		TorrentUploadKBps:      req.TorrentUploadKBps,
+		BackgroundDownloadKBps: req.BackgroundDownloadKBps,
+	}
+	for _, s := range req.Schedules {
+		cfg.Schedules = append(cfg.Schedules, config.BandwidthSchedule(s))
+	}
+
+	status, err := h.manager.Update(cfg)
+	if errors.Is(err, bandwidthService.ErrNegativeLimit) || errors.Is(err, bandwidthService.ErrInvalidSchedule) {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to update bandwidth limits", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to update bandwidth limits")
+		return
+	}
+
+	h.log.Info("bandwidth limits updated",
+		slog.Int("download_kbps", cfg.DownloadKBps),
+		slog.Int("upload_kbps", cfg.UploadKBps),
+		slog.Int("schedules", len(cfg.Schedules)),
+	)
+
+	resp.JSON(w, http.StatusOK, toResponse(status))
+}
+
+func toResponse(s bandwidthService.Status) Response {
+	res := Response{
+		Config: Request{
+			DownloadKBps:           s.Config.DownloadKBps,
+			UploadKBps:             s.Config.UploadKBps,
+			PeerDownloadKBps:       s.Config.PeerDownloadKBps,
+			PeerUploadKBps:         s.Config.PeerUploadKBps,
+			TorrentDownloadKBps:    s.Config.TorrentDownloadKBps,
+			TorrentUploadKBps:      s.Config.TorrentUploadKBps,
+			BackgroundDownloadKBps: s.Config.BackgroundDownloadKBps,
+			Schedules:              make([]Schedule, 0, len(s.Config.Schedules)),
+		},
+		ActiveSchedule: s.Schedule,
+		Effective: LimitsResponse{
+			Download:           s.Limits.Download,
+			Upload:             s.Limits.Upload,
+			PeerDownload:       s.Limits.PeerDownload,
+			PeerUpload:         s.Limits.PeerUpload,
+			TorrentDownload:    s.Limits.TorrentDownload,
+			TorrentUpload:      s.Limits.TorrentUpload,
+			BackgroundDownload: s.Limits.BackgroundDownload,
+		},
+	}
+	for _, sc := range s.Config.Schedules {
+		if sc.Days == nil {
+			sc.Days = []string{}
+		}
+		res.Config.Schedules = append(res.Config.Schedules, Schedule(sc))
+	}
+	return res
+}
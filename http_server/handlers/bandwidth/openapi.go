@@ -0,0 +1,57 @@
+package bandwidth
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Ограничение раздачи проверяется раз в stats_interval по переданному за это время
+const averageDescription = "Average rate of every torrent, not a strict rate limit: a torrent that exceeded it " +
+	"over the stats interval is paused until its average drops below the limit"
+
+// Describe добавляет в документ OpenAPI описание ограничений скорости
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("BandwidthSchedule", Schedule{})
+	limits := doc.AddSchema("BandwidthLimits", Request{})
+	limits.Properties["schedules"] = &openapi.Schema{Type: "array", Items: openapi.Ref("BandwidthSchedule")}
+	effective := doc.AddSchema("BandwidthEffectiveLimits", LimitsResponse{})
+	for _, p := range []*openapi.Schema{
+		limits.Properties["torrent_download_kbps"], limits.Properties["torrent_upload_kbps"],
+		effective.Properties["torrent_download"], effective.Properties["torrent_upload"],
+	} {
+		p.Description = averageDescription
+	}
+	for _, p := range []*openapi.Schema{
+		limits.Properties["peer_download_kbps"], limits.Properties["peer_upload_kbps"],
+		effective.Properties["peer_download"], effective.Properties["peer_upload"],
+	} {
+		p.Description = "Rate limit of every peer connection, applies to open connections too"
+	}
+	status := doc.AddSchema("BandwidthStatus", Response{})
+	status.Properties["config"] = openapi.Ref("BandwidthLimits")
+	status.Properties["effective"] = openapi.Ref("BandwidthEffectiveLimits")
+
+	get := openapi.ErrorResponses("401", "403", "429", "500")
+	get["200"] = &openapi.Response{
+		Description: "Configured limits in KiB/s and the limits in effect now in bytes per second",
+		Content:     openapi.JSONContent(openapi.Ref("BandwidthStatus")),
+	}
+	doc.AddOperation("GET", "/admin/bandwidth", &openapi.Operation{
+		OperationID: "getBandwidthLimits",
+		Summary:     "Bandwidth limits of the torrent engine",
+		Tags:        []string{"admin"},
+		Responses:   get,
+	})
+
+	updated := openapi.ErrorResponses("400", "401", "403", "429", "500")
+	updated["200"] = &openapi.Response{
+		Description: "Limits applied until the server restarts",
+		Content:     openapi.JSONContent(openapi.Ref("BandwidthStatus")),
+	}
+	doc.AddOperation("PUT", "/admin/bandwidth", &openapi.Operation{
+		OperationID: "updateBandwidthLimits",
+		Summary:     "Replace bandwidth limits and schedules",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONContent(openapi.Ref("BandwidthLimits"))},
+		Responses:   updated,
+	})
+}
@@ -0,0 +1,104 @@
+package diskusage
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/services/quota"
+)
+
+// Manager - учёт места под данные раздач (quota.Manager)
+type Manager interface {
+	Report() quota.Report
+	Enforce() []string
+}
+
+type Response struct {
+	Quota    int64             `json:"quota"` // байт, 0 - без ограничения
+	Used     int64             `json:"used"`
+	Torrents []TorrentResponse `json:"torrents"`
+}
+
+type TorrentResponse struct {
+	InfoHash  string `json:"info_hash"`
+	Name      string `json:"name"`
+	Bytes     int64  `json:"bytes"` // занято на диске скачанными данными
+	Size      int64  `json:"size"`
+	Complete  bool   `json:"complete"`
+	Streams   int    `json:"streams"`
+	Pinned    bool   `json:"pinned"`    // раздачу смотрят, при нехватке места она не удаляется
+	Evictable bool   `json:"evictable"` // может быть удалена при нехватке места
+	// требования трекера выполнены; иначе раздача удаляется, только если больше удалять нечего
+	RequirementsMet bool       `json:"requirements_met"`
+	WatchedAt       *time.Time `json:"watched_at,omitempty"`
+	AddedAt         time.Time  `json:"added_at"`
+}
+
+type EvictResponse struct {
+	Evicted []string `json:"evicted"` // info-hash удалённых раздач
+}
+
+type Handler struct {
+	log     *slog.Logger
+	manager Manager
+}
+
+func New(log *slog.Logger, manager Manager) *Handler {
+	return &Handler{
+		log:     log.With(slog.String("component", "handlers/diskusage")),
+		manager: manager,
+	}
+}
+
+// Routes - занятое раздачами место и принудительная проверка квоты
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.Usage)
+	r.Post("/evict", h.Evict)
+	return r
+}
+
+// Usage возвращает занятое место по раздачам в порядке, в котором они будут удаляться
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	report := h.manager.Report()
+
+	res := Response{
+		Quota:    report.Quota,
+		Used:     report.Used,
+		Torrents: make([]TorrentResponse, 0, len(report.Torrents)),
+	}
+	for _, t := range report.Torrents {
+		tr := TorrentResponse{
+			InfoHash:        t.InfoHash,
+			Name:            t.Name,
+			Bytes:           t.Bytes,
+			Size:            t.Size,
+			Complete:        t.Complete,
+			Streams:         t.Streams,
+			Pinned:          t.Pinned,
+			Evictable:       t.Evictable,
+			RequirementsMet: t.RequirementsMet,
+			AddedAt:         t.AddedAt,
+		}
+		if !t.WatchedAt.IsZero() {
+			tr.WatchedAt = &t.WatchedAt
+		}
+		res.Torrents = append(res.Torrents, tr)
+	}
+
+	resp.JSON(w, http.StatusOK, res)
+}
+
+// Evict сразу проверяет квоту и удаляет раздачи, не дожидаясь плановой проверки
+func (h *Handler) Evict(w http.ResponseWriter, r *http.Request) {
+	evicted := h.manager.Enforce()
+	if evicted == nil {
+		evicted = []string{}
+	}
+
+	resp.JSON(w, http.StatusOK, EvictResponse{Evicted: evicted})
+}
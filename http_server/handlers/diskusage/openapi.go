@@ -0,0 +1,36 @@
+package diskusage
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Describe добавляет в документ OpenAPI описание учёта места под раздачи
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("StorageTorrentUsage", TorrentResponse{})
+	doc.AddSchema("StorageUsage", Response{}).Properties["torrents"] = &openapi.Schema{Type: "array", Items: openapi.Ref("StorageTorrentUsage")}
+	doc.AddSchema("StorageEviction", EvictResponse{})
+
+	usage := openapi.ErrorResponses("401", "403", "429", "500")
+	usage["200"] = &openapi.Response{
+		Description: "Disk usage per torrent, ordered from the first to be evicted",
+		Content:     openapi.JSONContent(openapi.Ref("StorageUsage")),
+	}
+	doc.AddOperation("GET", "/admin/storage", &openapi.Operation{
+		OperationID: "getStorageUsage",
+		Summary:     "Disk usage of downloaded torrents",
+		Tags:        []string{"admin"},
+		Responses:   usage,
+	})
+
+	evicted := openapi.ErrorResponses("401", "403", "429", "500")
+	evicted["200"] = &openapi.Response{
+		Description: "Torrents removed to fit the quota, empty when the quota is disabled or not exceeded",
+		Content:     openapi.JSONContent(openapi.Ref("StorageEviction")),
+	}
+	doc.AddOperation("POST", "/admin/storage/evict", &openapi.Operation{
+		OperationID: "evictStorage",
+		Summary:     "Enforce the storage quota now",
+		Tags:        []string{"admin"},
+		Responses:   evicted,
+	})
+}
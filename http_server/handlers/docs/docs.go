@@ -0,0 +1,51 @@
+package docs
+
+import (
+	"net/http"
+
+	"torrentServer/http_server/handlers/apikeys"
+	"torrentServer/http_server/handlers/bandwidth"
+	"torrentServer/http_server/handlers/diskusage"
+	"torrentServer/http_server/handlers/events"
+	"torrentServer/http_server/handlers/playlist"
+	"torrentServer/http_server/handlers/search"
+	"torrentServer/http_server/handlers/stream"
+	"torrentServer/http_server/handlers/subtitles"
+	"torrentServer/http_server/handlers/torrents"
+	mwAuth "torrentServer/http_server/midleware/auth"
+	"torrentServer/internal/lib/api/openapi"
+	resp "torrentServer/internal/lib/api/response"
+)
+
+const Version = "1.0.0"
+
+// Spec собирает документ OpenAPI из описаний обработчиков, схемы строятся по Go-типам ответов
+func Spec() *openapi.Document {
+	doc := openapi.New("torrentServer API", Version, mwAuth.APIPrefix)
+	doc.AddSchema("ErrorResponse", resp.ErrorResponse{})
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"apiKeyHeader": {Type: "apiKey", Name: mwAuth.HeaderAPIKey, In: "header"},
+		"apiKeyQuery":  {Type: "apiKey", Name: mwAuth.ParamAPIKey, In: "query"},
+	}
+	doc.Security = []map[string][]string{{"apiKeyHeader": {}}, {"apiKeyQuery": {}}}
+
+	search.Describe(doc)
+	apikeys.Describe(doc)
+	torrents.Describe(doc)
+	stream.Describe(doc)
+	subtitles.Describe(doc)
+	playlist.Describe(doc)
+	diskusage.Describe(doc)
+	bandwidth.Describe(doc)
+	events.Describe(doc)
+
+	return doc
+}
+
+// New отдаёт документ OpenAPI, он строится один раз при старте
+func New() http.HandlerFunc {
+	spec := Spec()
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp.JSON(w, http.StatusOK, spec)
+	}
+}
@@ -0,0 +1,273 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+// TypeLagged - последнее событие перед закрытием соединения с клиентом, который не успевал
+// читать события. Клиенту нужно переподключиться, после подписки придёт актуальное состояние.
+const TypeLagged = "lagged"
+
+const (
+	// комментарий SSE, чтобы прокси не закрывали простаивающее соединение
+	sseHeartbeat = 15 * time.Second
+
+	// клиент, который дольше не принимает данные, отключается
+	sseWriteWait = 10 * time.Second
+
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 2 * wsPingInterval
+	wsWriteWait    = 10 * time.Second
+)
+
+// Subscriber - подписка на события раздач (engine.Engine)
+type Subscriber interface {
+	Subscribe(infoHash string) (*engine.Subscription, error)
+}
+
+type EventResponse struct {
+	Type     string         `json:"type"`
+	InfoHash string         `json:"info_hash,omitempty"`
+	Time     time.Time      `json:"time"`
+	State    string         `json:"state,omitempty"`
+	Stats    *StatsResponse `json:"stats,omitempty"`
+	Piece    *PieceResponse `json:"piece,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Dropped  int            `json:"dropped,omitempty"` // пропущено событий перед этим из-за медленного чтения
+}
+
+type StatsResponse struct {
+	DownloadRate   int64 `json:"download_rate"` // байт в секунду
+	UploadRate     int64 `json:"upload_rate"`
+	Peers          int   `json:"peers"`
+	Seeders        int   `json:"seeders"`
+	BytesCompleted int64 `json:"bytes_completed"`
+	Size           int64 `json:"size"`
+	PiecesComplete int   `json:"pieces_complete"`
+	Pieces         int   `json:"pieces"`
+}
+
+type PieceResponse struct {
+	Index          int `json:"index"`
+	PiecesComplete int `json:"pieces_complete"`
+	Pieces         int `json:"pieces"`
+}
+
+type Handler struct {
+	log      *slog.Logger
+	events   Subscriber
+	upgrader websocket.Upgrader
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func New(log *slog.Logger, events Subscriber) *Handler {
+	return &Handler{
+		log:    log.With(slog.String("component", "handlers/events")),
+		events: events,
+		done:   make(chan struct{}),
+	}
+}
+
+// Close завершает открытые потоки событий, чтобы остановка сервера их не ждала
+func (h *Handler) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
+
+// All - события всех раздач. Маршруты событий не должны стоять за middleware/timeout.
+func (h *Handler) All(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "")
+}
+
+// Torrent - события одной раздачи
+func (h *Handler) Torrent(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, chi.URLParam(r, "hash"))
+}
+
+// serve отдаёт события по WebSocket, если клиент просит upgrade, иначе как Server-Sent Events
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, hash string) {
+	sub, err := h.events.Subscribe(hash)
+	if errors.Is(err, engine.ErrNotFound) {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to subscribe to events", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to subscribe to events")
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r, sub)
+		return
+	}
+	h.serveSSE(w, r, sub)
+}
+
+func (h *Handler) serveSSE(w http.ResponseWriter, r *http.Request, sub *engine.Subscription) {
+	rc := http.NewResponseController(w)
+	// WriteTimeout сервера рассчитан на обычные запросы, поток событий не ограничен по времени:
+	// дедлайн продлевается на sseWriteWait перед каждой записью
+	if err := rc.SetWriteDeadline(time.Now().Add(sseWriteWait)); err != nil {
+		h.log.Warn("failed to set write deadline", sl.Err(err))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case <-heartbeat.C:
+			rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-sub.C:
+			rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+			if !ok {
+				if sub.Lagged() {
+					writeSSE(w, lagged())
+					rc.Flush()
+				}
+				return
+			}
+			err = writeSSE(w, Response(ev))
+		}
+		if err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, ev EventResponse) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	return err
+}
+
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request, sub *engine.Subscription) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // ответ с ошибкой уже отправлен Upgrade
+	}
+	defer conn.Close()
+
+	// сообщения клиента не нужны, их чтение обрабатывает pong и закрытие соединения
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-h.done:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteWait))
+			return
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case ev, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				if sub.Lagged() {
+					conn.WriteJSON(lagged())
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, TypeLagged), time.Now().Add(wsWriteWait))
+					return
+				}
+				// раздача удалена или движок остановлен
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+				return
+			}
+			err = conn.WriteJSON(Response(ev))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func lagged() EventResponse {
+	return EventResponse{Type: TypeLagged, Time: time.Now()}
+}
+
+// Response переводит событие движка в ответ API
+func Response(ev engine.Event) EventResponse {
+	res := EventResponse{
+		Type:     ev.Type,
+		InfoHash: ev.InfoHash,
+		Time:     ev.Time,
+		State:    ev.State,
+		Error:    ev.Error,
+		Dropped:  ev.Dropped,
+	}
+	if ev.Stats != nil {
+		res.Stats = &StatsResponse{
+			DownloadRate:   ev.Stats.DownloadRate,
+			UploadRate:     ev.Stats.UploadRate,
+			Peers:          ev.Stats.Peers,
+			Seeders:        ev.Stats.Seeders,
+			BytesCompleted: ev.Stats.BytesCompleted,
+			Size:           ev.Stats.Size,
+			PiecesComplete: ev.Stats.PiecesComplete,
+			Pieces:         ev.Stats.Pieces,
+		}
+	}
+	if ev.Piece != nil {
+		res.Piece = &PieceResponse{
+			Index:          ev.Piece.Index,
+			PiecesComplete: ev.Piece.PiecesComplete,
+			Pieces:         ev.Piece.Pieces,
+		}
+	}
+	return res
+}
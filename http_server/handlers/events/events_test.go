@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/services/engine/enginetest"
+)
+
+func TestEvents(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.bin", map[string]int{"video.bin": 128 << 10})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	h := New(slogdiscard.NewDiscardLogger(), e)
+	router := chi.NewRouter()
+	router.Get("/torrents/events", h.All)
+	router.Get("/torrents/{hash}/events", h.Torrent)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	defer h.Close()
+
+	t.Run("sse", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/torrents/" + seeder.InfoHash + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got := res.Header.Get("Content-Type"); res.StatusCode != http.StatusOK || got != "text/event-stream" {
+			t.Fatalf("status = %d, Content-Type = %q", res.StatusCode, got)
+		}
+
+		// торрент качается или уже скачан, ждём состояния seeding
+		done := make(chan struct{})
+		time.AfterFunc(30*time.Second, func() {
+			select {
+			case <-done:
+			default:
+				res.Body.Close()
+			}
+		})
+		defer close(done)
+
+		scanner := bufio.NewScanner(res.Body)
+		var name string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+
+			var ev EventResponse
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatalf("invalid event data %q: %v", data, err)
+			}
+			if ev.Type != name || ev.InfoHash != seeder.InfoHash {
+				t.Errorf("event %q with data %+v", name, ev)
+			}
+			if ev.Type == engine.EventState && ev.State == engine.StateSeeding {
+				if ev.Stats == nil || ev.Stats.BytesCompleted != 128<<10 {
+					t.Errorf("seeding event stats = %+v", ev.Stats)
+				}
+				return
+			}
+		}
+		t.Fatal("stream ended before seeding state")
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/torrents/events", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var ev EventResponse
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != engine.EventState || ev.InfoHash != seeder.InfoHash || ev.State == "" {
+			t.Errorf("first message = %+v, want current state", ev)
+		}
+	})
+
+	t.Run("unknown torrent", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/torrents/0123456789abcdef0123456789abcdef01234567/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+	"torrentServer/internal/services/engine"
+)
+
+// Describe добавляет в документ OpenAPI описание потоков событий раздач
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("TorrentStats", StatsResponse{})
+	doc.AddSchema("TorrentPiece", PieceResponse{})
+	event := doc.AddSchema("TorrentEvent", EventResponse{})
+	event.Properties["type"].Enum = []interface{}{engine.EventState, engine.EventStats, engine.EventPiece, engine.EventRemoved, engine.EventError, TypeLagged}
+	event.Properties["state"].Enum = []interface{}{engine.StateMetadata, engine.StateDownloading, engine.StateSeeding, engine.StatePaused}
+	event.Properties["stats"] = openapi.Ref("TorrentStats")
+	event.Properties["piece"] = openapi.Ref("TorrentPiece")
+
+	stream := func(description string) *openapi.Response {
+		return &openapi.Response{
+			Description: description,
+			Content:     map[string]*openapi.MediaType{"text/event-stream": {Schema: openapi.Ref("TorrentEvent")}},
+		}
+	}
+	const upgrade = "Server-Sent Events: each event is named after its type and carries a TorrentEvent as JSON data. " +
+		"Send a WebSocket upgrade to the same URL to receive the events as JSON text messages instead. " +
+		"The stream starts with the current state; clients that fall behind get a lagged event and are disconnected."
+
+	all := openapi.ErrorResponses("401", "403", "429", "500")
+	all["200"] = stream("Events of all torrents")
+	all["101"] = &openapi.Response{Description: "Switched to WebSocket"}
+	doc.AddOperation("GET", "/torrents/events", &openapi.Operation{
+		OperationID: "torrentsEvents",
+		Summary:     "Live state, rates, peers, pieces and errors of all torrents",
+		Description: upgrade,
+		Tags:        []string{"torrents"},
+		Responses:   all,
+	})
+
+	one := openapi.ErrorResponses("401", "403", "404", "429", "500")
+	one["200"] = stream("Events of the torrent; the stream ends after the removed event")
+	one["101"] = &openapi.Response{Description: "Switched to WebSocket"}
+	doc.AddOperation("GET", "/torrents/{hash}/events", &openapi.Operation{
+		OperationID: "torrentEvents",
+		Summary:     "Live state, rates, peers, pieces and errors of a torrent",
+		Description: upgrade,
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			{Name: "hash", In: "path", Required: true, Description: "Info-hash in hex", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: one,
+	})
+}
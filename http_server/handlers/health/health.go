@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	resp "torrentServer/internal/lib/api/response"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check - проверка одной зависимости (Redis, Jackett, хранилище)
+type Check struct {
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Handler struct {
+	log     *slog.Logger
+	checks  []Check
+	timeout time.Duration
+}
+
+// New создаёт обработчики liveness и readiness. timeout ограничивает каждую проверку зависимостей.
+func New(log *slog.Logger, timeout time.Duration, checks ...Check) *Handler {
+	return &Handler{
+		log:     log.With(slog.String("component", "handlers/health")),
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Live отвечает, что процесс жив. Зависимости не проверяются,
+// чтобы недоступность Redis или Jackett не приводила к перезапуску сервера.
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	resp.JSON(w, http.StatusOK, Response{Status: StatusOK})
+}
+
+// Ready проверяет все зависимости параллельно. Если хотя бы одна недоступна, отвечает 503.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	results := make(map[string]CheckResult, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := c.Ping(ctx)
+			res := CheckResult{
+				Status:    StatusOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				res.Status = StatusUnavailable
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[c.Name] = res
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	status := StatusOK
+	code := http.StatusOK
+	for name, res := range results {
+		if res.Status != StatusOK {
+			status = StatusUnavailable
+			code = http.StatusServiceUnavailable
+			h.log.Warn("dependency is unavailable", slog.String("dependency", name), slog.String("error", res.Error))
+		}
+	}
+
+	resp.JSON(w, code, Response{Status: status, Checks: results})
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+)
+
+func ping(err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error { return err }
+}
+
+func ready(t *testing.T, h *Handler) (int, Response) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body Response
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	return rec.Code, body
+}
+
+func TestReady(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), time.Second,
+		Check{Name: "redis", Ping: ping(nil)},
+		Check{Name: "storage", Ping: ping(nil)},
+	)
+	code, body := ready(t, h)
+	if code != http.StatusOK || body.Status != StatusOK || len(body.Checks) != 2 {
+		t.Errorf("Ready() = %d %+v, want 200 with 2 checks", code, body)
+	}
+}
+
+func TestReadyOneDependencyDown(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), time.Second,
+		Check{Name: "redis", Ping: ping(nil)},
+		Check{Name: "jackett", Ping: ping(errors.New("connection refused"))},
+		Check{Name: "storage", Ping: ping(nil)},
+	)
+	code, body := ready(t, h)
+	if code != http.StatusServiceUnavailable || body.Status != StatusUnavailable {
+		t.Errorf("Ready() = %d %q, want 503 %q", code, body.Status, StatusUnavailable)
+	}
+	if c := body.Checks["jackett"]; c.Status != StatusUnavailable || c.Error != "connection refused" {
+		t.Errorf("jackett check = %+v", c)
+	}
+	for _, name := range []string{"redis", "storage"} {
+		if c := body.Checks[name]; c.Status != StatusOK || c.Error != "" {
+			t.Errorf("%s check = %+v, want ok", name, c)
+		}
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), 50*time.Millisecond,
+		Check{Name: "jackett", Ping: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	start := time.Now()
+	code, body := ready(t, h)
+	if code != http.StatusServiceUnavailable || body.Checks["jackett"].Status != StatusUnavailable {
+		t.Errorf("Ready() = %d %+v, want 503", code, body)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Ready() took %v with 50ms timeout", d)
+	}
+}
+
+func TestLive(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), time.Second, Check{Name: "redis", Ping: ping(errors.New("down"))})
+	rec := httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Live() = %d, want 200 regardless of dependencies", rec.Code)
+	}
+}
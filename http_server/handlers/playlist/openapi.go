@@ -0,0 +1,25 @@
+package playlist
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Describe добавляет в документ OpenAPI описание плейлистов раздач
+func Describe(doc *openapi.Document) {
+	responses := openapi.ErrorResponses("401", "403", "404", "429", "500", "504")
+	responses["200"] = &openapi.Response{
+		Description: "Extended M3U playlist in UTF-8 with absolute /stream URLs of the video files in season and episode order. " +
+			"When the playlist is requested with an API key, the URLs are signed on behalf of that key and work without it until signed_url_ttl expires.",
+		Content: map[string]*openapi.MediaType{"audio/x-mpegurl": {Schema: &openapi.Schema{Type: "string"}}},
+	}
+
+	doc.AddOperation("GET", "/playlist/{file}", &openapi.Operation{
+		OperationID: "getPlaylist",
+		Summary:     "M3U playlist of the video files of a torrent for VLC, mpv and IPTV players",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			{Name: "file", In: "path", Required: true, Description: "Info-hash in hex with .m3u8 or .m3u extension", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: responses,
+	})
+}
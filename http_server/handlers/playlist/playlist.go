@@ -0,0 +1,174 @@
+package playlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	mwRealIP "torrentServer/http_server/midleware/realip"
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/episode"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/storage"
+)
+
+// Files - файлы раздач движка (engine.Engine)
+type Files interface {
+	Files(ctx context.Context, infoHash string) ([]engine.File, error)
+	Get(infoHash string) (engine.Status, error)
+}
+
+// Signer подписывает ссылки на потоки от имени ключа, с которым запрошен плейлист (auth.Service)
+type Signer interface {
+	Sign(key storage.APIKey, path string) url.Values
+}
+
+type Handler struct {
+	log       *slog.Logger
+	files     Files
+	signer    Signer
+	apiPrefix string // префикс, под которым смонтированы /stream
+}
+
+func New(log *slog.Logger, files Files, signer Signer, apiPrefix string) *Handler {
+	return &Handler{
+		log:       log.With(slog.String("component", "handlers/playlist")),
+		files:     files,
+		signer:    signer,
+		apiPrefix: apiPrefix,
+	}
+}
+
+// Routes - плейлисты раздач: /{hash}.m3u8 и /{hash}.m3u
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/{file}", h.Playlist)
+	return r
+}
+
+// Playlist отдаёт видеофайлы раздачи в порядке сезонов и серий как плейлист M3U
+// со ссылками на /stream. Если запрос пришёл с API-ключом, ссылки подписываются от его имени,
+// чтобы плеер открывал их без ключа.
+func (h *Handler) Playlist(w http.ResponseWriter, r *http.Request) {
+	file := chi.URLParam(r, "file")
+	ext := path.Ext(file)
+	if ext != ".m3u8" && ext != ".m3u" {
+		resp.NotFound(w, r)
+		return
+	}
+	hash := strings.ToLower(strings.TrimSuffix(file, ext))
+
+	files, err := h.files.Files(r.Context(), hash)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	status, err := h.files.Get(hash)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var videos []engine.File
+	for _, f := range files {
+		if f.Kind == engine.KindVideo {
+			videos = append(videos, f)
+		}
+	}
+	if len(videos) == 0 {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "torrent has no video files")
+		return
+	}
+	slices.SortStableFunc(videos, func(a, b engine.File) int { return episode.Compare(a.Path, b.Path) })
+
+	key, signed := mwAuth.KeyFromContext(r.Context())
+	base := baseURL(r) + h.apiPrefix
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#PLAYLIST:%s\n", attr(status.Name))
+	for _, f := range videos {
+		title := strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path))
+		group := status.Name
+		if n, ok := episode.Parse(f.Path); ok {
+			title = n.String() + " - " + title
+			if n.Season > 0 {
+				group = "Season " + strconv.Itoa(n.Season)
+			}
+		}
+
+		streamPath := fmt.Sprintf("/stream/%s/%d", hash, f.Index)
+		link := base + streamPath
+		if signed {
+			link += "?" + h.signer.Sign(key, streamPath).Encode()
+		}
+
+		// tvg-name и group-title читают IPTV-плееры, длительность заранее неизвестна
+		fmt.Fprintf(&b, "#EXTINF:-1 tvg-name=\"%s\" group-title=\"%s\",%s\n", attr(title), attr(group), attr(title))
+		b.WriteString(link)
+		b.WriteString("\n")
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": status.Name + ".m3u8"}))
+	// в подписанных ссылках срок действия, кэшировать такой плейлист нельзя
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// baseURL - схема и адрес сервера, по которым клиент к нему обратился. Заголовки X-Forwarded-*
+// учитываются только от доверенного прокси (http_server.trusted_proxies), иначе любой клиент
+// подставил бы в плейлист ссылки на чужой сервер.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if !mwRealIP.FromTrustedProxy(r) {
+		return scheme + "://" + r.Host
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" {
+		host, _, _ = strings.Cut(fwd, ",")
+		host = strings.TrimSpace(host)
+	}
+
+	return scheme + "://" + host
+}
+
+// attr убирает из значения символы, которые ломают строку #EXTINF
+func attr(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ", `"`, "'").Replace(s)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, engine.ErrNotFound):
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+	case errors.Is(err, engine.ErrNoMetadata):
+		resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeNoMetadata, err.Error())
+	case errors.Is(err, context.Canceled):
+		// клиент ушёл, пока ждали метаданные
+	default:
+		h.log.Error("failed to build playlist", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to build playlist")
+	}
+}
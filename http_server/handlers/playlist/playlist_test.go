@@ -0,0 +1,122 @@
+package playlist
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	mwRealIP "torrentServer/http_server/midleware/realip"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine/enginetest"
+	"torrentServer/internal/storage"
+)
+
+type mockSigner struct{}
+
+func (mockSigner) Sign(key storage.APIKey, path string) url.Values {
+	return url.Values{"key_id": {key.ID}, "signature": {path}}
+}
+
+func TestPlaylist(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "Show", map[string]int{
+		"Season 1/Show.S01E10.mkv": 1 << 10,
+		"Season 1/Show.S01E02.mkv": 1 << 10,
+		"Season 2/01. Pilot.mkv":   1 << 10,
+		"Extras/Trailer.mp4":       1 << 10,
+		"Season 1/Show.S01E02.srt": 1 << 10,
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	h := New(slogdiscard.NewDiscardLogger(), e, mockSigner{}, "/api/v1")
+	router := chi.NewRouter()
+	router.Mount("/playlist", h.Routes())
+	router.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(mwAuth.WithKey(r.Context(), storage.APIKey{ID: "k1"})))
+		})
+	}).Mount("/signed", h.Routes())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	get := func(t *testing.T, path string) (*http.Response, string) {
+		t.Helper()
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	// файлы раздачи отсортированы по пути: 0 - Extras/Trailer.mp4, 1 - Season 1/Show.S01E02.mkv,
+	// 2 - Season 1/Show.S01E02.srt, 3 - Season 1/Show.S01E10.mkv, 4 - Season 2/01. Pilot.mkv
+	res, body := get(t, "/playlist/"+strings.ToUpper(seeder.InfoHash)+".m3u8")
+	stream := srv.URL + "/api/v1/stream/" + seeder.InfoHash
+	want := "#EXTM3U\n#PLAYLIST:Show\n" +
+		"#EXTINF:-1 tvg-name=\"S01E02 - Show.S01E02\" group-title=\"Season 1\",S01E02 - Show.S01E02\n" + stream + "/1\n" +
+		"#EXTINF:-1 tvg-name=\"S01E10 - Show.S01E10\" group-title=\"Season 1\",S01E10 - Show.S01E10\n" + stream + "/3\n" +
+		"#EXTINF:-1 tvg-name=\"S02E01 - 01. Pilot\" group-title=\"Season 2\",S02E01 - 01. Pilot\n" + stream + "/4\n" +
+		"#EXTINF:-1 tvg-name=\"Trailer\" group-title=\"Show\",Trailer\n" + stream + "/0\n"
+	if res.StatusCode != http.StatusOK || body != want {
+		t.Errorf("status = %d, playlist:\n%s\nwant:\n%s", res.StatusCode, body, want)
+	}
+	if got := res.Header.Get("Content-Type"); got != "audio/x-mpegurl; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	// с ключом ссылки подписываются путём без префикса API
+	_, body = get(t, "/signed/"+seeder.InfoHash+".m3u")
+	if link := stream + "/1?key_id=k1&signature=%2Fstream%2F" + seeder.InfoHash + "%2F1\n"; !strings.Contains(body, link) {
+		t.Errorf("signed playlist:\n%s\nhas no link %s", body, link)
+	}
+
+	for path, status := range map[string]int{
+		"/playlist/" + seeder.InfoHash + ".txt":                   http.StatusNotFound,
+		"/playlist/0123456789abcdef0123456789abcdef01234567.m3u8": http.StatusNotFound,
+	} {
+		if res, _ := get(t, path); res.StatusCode != status {
+			t.Errorf("GET %s = %d, want %d", path, res.StatusCode, status)
+		}
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	mw, err := mwRealIP.New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = baseURL(r)
+	}))
+
+	tests := []struct {
+		name   string
+		remote string
+		want   string
+	}{
+		{"trusted proxy", "10.0.0.2:4000", "https://tv.example.com"},
+		// прямой клиент не может подменить адрес сервера в ссылках плейлиста
+		{"direct client", "203.0.113.7:4000", "http://server.lan:8080"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://server.lan:8080/playlist/x.m3u8", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "tv.example.com")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%s: baseURL() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
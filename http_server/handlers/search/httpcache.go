@@ -0,0 +1,58 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// snapshotETag строит ETag по снимку результатов и параметрам страницы.
+// Пока запись в кэше не обновилась, снимок не меняется и ETag остаётся тем же.
+func snapshotETag(results []map[string]interface{}, source string, page, perPage int) (string, error) {
+	// json.Marshal сортирует ключи map, поэтому одинаковые результаты дают одинаковые байты
+	snapshot, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(snapshot)
+	fmt.Fprintf(h, "|%s|%d|%d", source, page, perPage)
+
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// etagMatches проверяет заголовок If-None-Match, в котором может быть список ETag или "*"
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheHeaders выставляет ETag и Cache-Control. max-age равен оставшемуся времени жизни
+// записи в кэше, если он неизвестен (локальный индекс) - клиент должен перепроверять ответ.
+// Ответы на запросы с API-ключом зависят от политики ключа, поэтому их нельзя хранить в общих кэшах.
+func setCacheHeaders(w http.ResponseWriter, etag string, ttl time.Duration, private bool) {
+	w.Header().Set("ETag", etag)
+
+	visibility := "public"
+	if private {
+		visibility = "private"
+	}
+	if ttl > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(ttl.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", visibility+", no-cache")
+	}
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+)
+
+type mockCache struct {
+	data []byte
+	ttl  time.Duration
+}
+
+func (c *mockCache) Get(_ context.Context, _ string, dest interface{}) bool {
+	return json.Unmarshal(c.data, dest) == nil
+}
+
+func (c *mockCache) Set(_ context.Context, _ string, value interface{}) error {
+	data, err := json.Marshal(value)
+	c.data = data
+	return err
+}
+
+func (c *mockCache) TTL(_ context.Context, _ string) time.Duration {
+	return c.ttl
+}
+
+func TestSearchHandlerConditionalGet(t *testing.T) {
+	cache := &mockCache{
+		data: []byte(`[{"title":"Test1","Seeders":1},{"title":"Test2","Seeders":2},{"title":"Test3","Seeders":3}]`),
+		ttl:  90 * time.Second,
+	}
+	h := New(slogdiscard.NewDiscardLogger(), cache, nil, 0)
+
+	get := func(url, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.SearchHandler(rec, req)
+		return rec
+	}
+
+	first := get("/search?query=test&categories=2000&per_page=2", "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", first.Code, http.StatusOK)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("ETag header is empty")
+	}
+	if got, want := first.Header().Get("Cache-Control"), "public, max-age=90"; got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	if rec := get("/search?query=test&categories=2000&per_page=2", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("conditional GET status = %d with %d bytes, want %d with empty body", rec.Code, rec.Body.Len(), http.StatusNotModified)
+	}
+
+	// другая страница - другой ETag
+	if rec := get("/search?query=test&categories=2000&per_page=2&page=2", etag); rec.Code != http.StatusOK {
+		t.Errorf("another page status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// запись в кэше обновилась - старый ETag больше не подходит
+	cache.data = []byte(`[{"title":"Test1","Seeders":5}]`)
+	if rec := get("/search?query=test&categories=2000&per_page=2", etag); rec.Code != http.StatusOK {
+		t.Errorf("status after cache refresh = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestSearchCancelledWithoutFallback(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), &mockCache{}, nil, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// без отмены запрос ушёл бы в локальный индекс и вернул ErrLocalIndexDisabled
+	if _, err := h.Search(ctx, Params{Query: "ubuntu", Categories: []uint{4000}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search() with cancelled context: error = %v, want %v", err, context.Canceled)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/?query=ubuntu&categories=4000", nil).WithContext(ctx)
+	h.SearchHandler(rec, req)
+	if rec.Body.Len() != 0 {
+		t.Errorf("SearchHandler() wrote %q for a cancelled request", rec.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+	"torrentServer/internal/services/jackett"
+)
+
+// Describe добавляет в документ OpenAPI описание поиска
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("SearchResult", jackett.SimpleResult{})
+	page := doc.AddSchema("PaginatedResponse", PaginatedResponse{})
+	page.Properties["data"] = &openapi.Schema{Type: "array", Items: openapi.Ref("SearchResult")}
+	page.Properties["source"].Enum = []interface{}{SourceJackett, SourceLocal}
+
+	one := 1.0
+	hundred := 100.0
+
+	responses := openapi.ErrorResponses("400", "401", "403", "429", "500")
+	responses["200"] = &openapi.Response{
+		Description: "Page of search results",
+		Headers: map[string]*openapi.Header{
+			"X-Results-Source": {Description: "Where the results come from", Schema: &openapi.Schema{Type: "string"}},
+			"ETag":             {Description: "Version of the results page, send it back in If-None-Match", Schema: &openapi.Schema{Type: "string"}},
+			"Cache-Control":    {Description: "max-age is the time left until the cached Jackett results expire", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: openapi.JSONContent(openapi.Ref("PaginatedResponse")),
+	}
+	responses["304"] = &openapi.Response{Description: "Results page has not changed since the ETag from If-None-Match"}
+
+	doc.AddOperation("GET", "/search", &openapi.Operation{
+		OperationID: "search",
+		Summary:     "Search torrents through Jackett or the local index",
+		Tags:        []string{"search"},
+		Parameters: []openapi.Parameter{
+			{Name: "query", In: "query", Required: true, Description: "Search query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "categories", In: "query", Required: true, Description: "Comma separated Torznab category ids, e.g. 2000,5000",
+				Schema: &openapi.Schema{Type: "string"}},
+			{Name: "safeOnly", In: "query", Description: "1 - only results from safe trackers (Internet Archive)",
+				Schema: &openapi.Schema{Type: "integer", Enum: []interface{}{0, 1}, Default: 0}},
+			{Name: "source", In: "query", Description: "jackett - cache or Jackett with fallback to the local index, local - only the local index",
+				Schema: &openapi.Schema{Type: "string", Enum: []interface{}{SourceJackett, SourceLocal}, Default: SourceJackett}},
+			{Name: "page", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: &one, Default: 1}},
+			{Name: "per_page", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: &one, Maximum: &hundred, Default: 20}},
+			{Name: "If-None-Match", In: "header", Description: "ETag of a previously received page", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: responses,
+	})
+}
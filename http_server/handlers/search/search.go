@@ -3,22 +3,68 @@ package search
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	cache "torrentServer/cache"
+	"github.com/go-chi/chi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
 	getTorrents "torrentServer/internal/handlers/request"
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/lib/tracing"
+	"torrentServer/internal/storage"
 )
 
-var (
-	cacheTTL   time.Duration = 3600
-	redisCache               = cache.NewRedisCache(os.Getenv("REDIS_ADDR"), cacheTTL)
+// Cache - кэш результатов поиска (RedisCache)
+type Cache interface {
+	Get(ctx context.Context, key string, dest interface{}) bool
+	Set(ctx context.Context, key string, value interface{}) error
+	TTL(ctx context.Context, key string) time.Duration // сколько ещё будет жить запись, 0 - неизвестно
+}
+
+// SearchHistory запоминает выполненные запросы, по ним прогревается кэш
+type SearchHistory interface {
+	SaveSearch(query string, categories []uint, safeOnly int) error
+}
+
+type Handler struct {
+	log     *slog.Logger
+	cache   Cache
+	history SearchHistory
+	// сколько ждать Jackett при поиске, 0 - пока не отменён контекст запроса
+	jackettTimeout time.Duration
+}
+
+func New(log *slog.Logger, cache Cache, history SearchHistory, jackettTimeout time.Duration) *Handler {
+	return &Handler{
+		log:            log.With(slog.String("component", "handlers/search")),
+		cache:          cache,
+		history:        history,
+		jackettTimeout: jackettTimeout,
+	}
+}
+
+// Routes возвращает группу маршрутов поиска для монтирования в основной роутер
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.SearchHandler)
+	return r
+}
+
+// Источники результатов поиска
+const (
+	SourceJackett = "jackett"
+	SourceLocal   = "local"
 )
 
 type PaginatedResponse struct {
@@ -27,40 +73,161 @@ type PaginatedResponse struct {
 	PerPage    int                      `json:"per_page"`
 	TotalItems int                      `json:"total_items"`
 	TotalPages int                      `json:"total_pages"`
+	Source     string                   `json:"source"`            // jackett или local
+	Warning    string                   `json:"warning,omitempty"` // причина, по которой ответ собран из локального индекса
 }
 
-func SearchHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+// Params - параметры поиска, общие для HTTP и gRPC
+type Params struct {
+	Query      string
+	Categories []uint
+	SafeOnly   int
+	Source     string // SourceJackett (по умолчанию) или SourceLocal
+}
+
+// Result - все найденные раздачи, до пагинации
+type Result struct {
+	Results []map[string]interface{}
+	Source  string
+	Warning string        // причина, по которой ответ собран из локального индекса
+	TTL     time.Duration // сколько ещё результаты пролежат в кэше, 0 - не кэшируются
+}
+
+var ErrInvalidSource = errors.New("invalid source parameter")
+
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	// контекст запроса отменяется, если клиент отключился, и передаётся дальше в Jackett
+	ctx, span := tracing.Tracer().Start(r.Context(), "SearchHandler")
+	defer span.End()
 
 	// Парсинг параметров
 	query, categories, safeOnly, err := parseRequestParams(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		span.SetStatus(codes.Error, err.Error())
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
 		return
 	}
 
-	// Получаем данные (из кэша или Jackett)
-	results, err := getOrFetchResults(ctx, query, categories, safeOnly)
+	res, err := h.Search(ctx, Params{
+		Query:      query,
+		Categories: categories,
+		SafeOnly:   safeOnly,
+		Source:     r.URL.Query().Get("source"),
+	})
+	if errors.Is(err, ErrInvalidSource) {
+		span.SetStatus(codes.Error, err.Error())
+		resp.WriteErrorDetails(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error(),
+			map[string]interface{}{"source": r.URL.Query().Get("source"), "allowed": []string{SourceJackett, SourceLocal}})
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		// клиент отключился или ответ 504 отправит middleware/timeout
+		span.SetStatus(codes.Error, ctx.Err().Error())
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		span.SetStatus(codes.Error, "failed to get search results")
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeSearchFailed, err.Error())
 		return
 	}
 
 	// Применяем пагинацию
 	page, perPage := parsePaginationParams(r)
-	paginatedData, totalPages := applyPagination(results, page, perPage)
+	paginatedData, totalPages := Paginate(res.Results, page, perPage)
+
+	_, withKey := mwAuth.KeyFromContext(ctx)
+	etag, err := snapshotETag(res.Results, res.Source, page, perPage)
+	if err != nil {
+		h.log.Error("failed to build etag", sl.Err(err))
+	} else {
+		setCacheHeaders(w, etag, res.TTL, withKey)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			span.SetAttributes(attribute.Bool("http.not_modified", true))
+			w.Header().Set("X-Results-Source", res.Source)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
 
 	// Формируем ответ
 	response := PaginatedResponse{
 		Data:       paginatedData,
 		Page:       page,
 		PerPage:    perPage,
-		TotalItems: len(results),
+		TotalItems: len(res.Results),
 		TotalPages: totalPages,
+		Source:     res.Source,
+		Warning:    res.Warning,
+	}
+
+	w.Header().Set("X-Results-Source", res.Source)
+	resp.JSON(w, http.StatusOK, response)
+}
+
+// Search - конвейер поиска, общий для HTTP и gRPC: политика ключа, история запросов,
+// кэш и Jackett, а если Jackett недоступен - локальный индекс
+func (h *Handler) Search(ctx context.Context, p Params) (Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Search")
+	defer span.End()
+
+	// ключ с политикой safe всегда ищет только по безопасным трекерам
+	if key, ok := mwAuth.KeyFromContext(ctx); ok && key.Policy == storage.PolicySafe {
+		p.SafeOnly = 1
+	}
+	if p.Source == "" {
+		p.Source = SourceJackett
+	}
+	span.SetAttributes(
+		attribute.String("search.query", p.Query),
+		attribute.IntSlice("search.categories", uintsToInts(p.Categories)),
+		attribute.Int("search.safe_only", p.SafeOnly),
+		attribute.String("search.requested_source", p.Source),
+	)
+	if p.Source != SourceJackett && p.Source != SourceLocal {
+		span.SetStatus(codes.Error, ErrInvalidSource.Error())
+		return Result{}, ErrInvalidSource
+	}
+
+	if h.history != nil {
+		if err := h.history.SaveSearch(p.Query, p.Categories, p.SafeOnly); err != nil {
+			h.log.Error("failed to save search history", sl.Err(err))
+		}
+	}
+
+	res := Result{Source: p.Source}
+	var err error
+	if p.Source == SourceLocal {
+		res.Results, err = getLocalResults(p.Query, p.Categories, p.SafeOnly)
+	} else {
+		// Получаем данные (из кэша или Jackett), если Jackett недоступен - ищем в локальном индексе
+		res.Results, err = h.getOrFetchResults(ctx, p.Query, p.Categories, p.SafeOnly)
+		if err != nil && ctx.Err() != nil {
+			// запрос отменён или его время вышло, локальный индекс ответить уже не успеет
+			span.SetStatus(codes.Error, ctx.Err().Error())
+			return Result{}, ctx.Err()
+		}
+		if err != nil {
+			h.log.Warn("jackett request failed, falling back to local index", sl.Err(err))
+			res.Source = SourceLocal
+			res.Warning = "jackett is unavailable, results are served from the local index"
+			res.Results, err = getLocalResults(p.Query, p.Categories, p.SafeOnly)
+		}
+	}
+	span.SetAttributes(attribute.String("search.source", res.Source))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to get search results")
+		h.log.Error("failed to get search results", sl.Err(err))
+		return Result{}, err
+	}
+	span.SetAttributes(attribute.Int("search.total_items", len(res.Results)))
+
+	// результаты из кэша живут, пока не истечёт запись, локальный индекс не кэшируется
+	if res.Source == SourceJackett {
+		res.TTL = h.cache.TTL(ctx, generateCacheKey(p.Query, p.Categories, p.SafeOnly))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return res, nil
 }
 
 // Вспомогательные функции
@@ -88,35 +255,93 @@ func parseRequestParams(r *http.Request) (string, []uint, int, error) {
 	return query, categories, safeOnly, nil
 }
 
-func getOrFetchResults(ctx context.Context, query string, categories []uint, safeOnly int) ([]map[string]interface{}, error) {
+func (h *Handler) getOrFetchResults(ctx context.Context, query string, categories []uint, safeOnly int) ([]map[string]interface{}, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "getOrFetchResults")
+	defer span.End()
+
 	cacheKey := generateCacheKey(query, categories, safeOnly)
 
 	// Пытаемся получить из кэша
 	var results []map[string]interface{}
-	if redisCache.Get(ctx, cacheKey, &results) {
+	if h.cache.Get(ctx, cacheKey, &results) {
+		span.SetAttributes(attribute.String("cache.status", "hit"), attribute.Int("search.results", len(results)))
 		return results, nil
 	}
+	span.SetAttributes(attribute.String("cache.status", "miss"))
+
+	fetchCtx := ctx
+	if h.jackettTimeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, h.jackettTimeout)
+		defer cancel()
+	}
+	results, err := h.fetchAndCache(fetchCtx, cacheKey, query, categories, safeOnly)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to fetch results")
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("search.results", len(results)))
+
+	return results, nil
+}
+
+// RefreshResults запрашивает результаты у Jackett в обход кэша и обновляет кэш.
+// Используется для прогрева кэша популярными запросами.
+func (h *Handler) RefreshResults(ctx context.Context, query string, categories []uint, safeOnly int) (int, error) {
+	results, err := h.fetchAndCache(ctx, generateCacheKey(query, categories, safeOnly), query, categories, safeOnly)
+	if err != nil {
+		return 0, err
+	}
+	return len(results), nil
+}
 
+func (h *Handler) fetchAndCache(ctx context.Context, cacheKey, query string, categories []uint, safeOnly int) ([]map[string]interface{}, error) {
 	// Запрос к Jackett
-	jsonStr, err := getTorrents.RequestSimple(query, categories, safeOnly)
+	jsonStr, complete, err := getTorrents.RequestSimple(ctx, query, categories, safeOnly)
 	if err != nil {
 		return nil, err
 	}
 
 	// Парсим JSON
-	if err := json.Unmarshal([]byte(jsonStr), &results); err != nil {
+	_, span := tracing.Tracer().Start(ctx, "decodeResults")
+	var results []map[string]interface{}
+	err = json.Unmarshal([]byte(jsonStr), &results)
+	span.End()
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse Jackett response")
 	}
 
-	// Сохраняем в кэш
-	if err := redisCache.Set(ctx, cacheKey, results); err != nil {
-		log.Printf("Cache set error: %v", err)
+	// Сохраняем в кэш, выдачу с неразрешёнными ссылками - нет, иначе они останутся без magnet до конца TTL
+	if !complete {
+		h.log.Debug("not caching results with unresolved links", slog.String("query", query))
+		return results, nil
+	}
+	if err := h.cache.Set(ctx, cacheKey, results); err != nil {
+		h.log.Error("failed to cache results", sl.Err(err))
 	}
 
 	return results, nil
 }
 
-func applyPagination(data []map[string]interface{}, page, perPage int) ([]map[string]interface{}, int) {
+// getLocalResults ищет по локальному индексу, результаты не кэшируются,
+// чтобы не перекрывать свежую выдачу Jackett
+func getLocalResults(query string, categories []uint, safeOnly int) ([]map[string]interface{}, error) {
+	jsonStr, err := getTorrents.RequestLocal(query, categories, safeOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse local index response")
+	}
+
+	return results, nil
+}
+
+// Paginate возвращает страницу page по perPage результатов и общее число страниц
+func Paginate(data []map[string]interface{}, page, perPage int) ([]map[string]interface{}, int) {
 	totalItems := len(data)
 	if totalItems == 0 {
 		return []map[string]interface{}{}, 0
@@ -149,6 +374,14 @@ func generateCacheKey(query string, categories []uint, safeOnly int) string {
 	return fmt.Sprintf("query=%s&categories=%v&safeOnly=%d", query, categories, safeOnly)
 }
 
+func uintsToInts(values []uint) []int {
+	ints := make([]int, 0, len(values))
+	for _, v := range values {
+		ints = append(ints, int(v))
+	}
+	return ints
+}
+
 func parsePaginationParams(r *http.Request) (int, int) {
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
@@ -0,0 +1,26 @@
+package stream
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Describe добавляет в документ OpenAPI описание потоковой отдачи файлов
+func Describe(doc *openapi.Document) {
+	responses := openapi.ErrorResponses("400", "401", "403", "404", "409", "429", "500", "504")
+	binary := map[string]*openapi.MediaType{"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}}
+	responses["200"] = &openapi.Response{Description: "Whole file", Content: binary}
+	responses["206"] = &openapi.Response{Description: "Requested byte range", Content: binary}
+	responses["416"] = &openapi.Response{Description: "Range is outside of the file"}
+
+	doc.AddOperation("GET", "/stream/{hash}/{index}", &openapi.Operation{
+		OperationID: "streamFile",
+		Summary:     "Stream a file of an added torrent with Range support, missing pieces are downloaded on demand",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			{Name: "hash", In: "path", Required: true, Description: "Info-hash in hex", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "index", In: "path", Required: true, Description: "File index from the torrent file list", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "Range", In: "header", Description: "Byte range, e.g. bytes=0-1048575", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: responses,
+	})
+}
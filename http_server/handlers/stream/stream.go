@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+// Files открывает файлы раздач движка для чтения
+type Files interface {
+	OpenFile(ctx context.Context, infoHash string, index int) (*engine.FileReader, error)
+}
+
+type Handler struct {
+	log   *slog.Logger
+	files Files
+}
+
+func New(log *slog.Logger, files Files) *Handler {
+	return &Handler{
+		log:   log.With(slog.String("component", "handlers/stream")),
+		files: files,
+	}
+}
+
+// Routes - потоковая отдача файлов раздачи. Маршруты не должны стоять за middleware/timeout:
+// просмотр фильма длится дольше таймаута обычного запроса.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/{hash}/{index}", h.Stream)
+	r.Head("/{hash}/{index}", h.Stream)
+	return r
+}
+
+// Stream отдаёт файл с поддержкой Range, чтобы браузер и VLC могли перематывать.
+// Незагруженные куски скачиваются по мере чтения, воспроизведение начинается до окончания загрузки.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "invalid file index")
+		return
+	}
+
+	f, err := h.files.OpenFile(r.Context(), hash, index)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	// WriteTimeout сервера рассчитан на обычные запросы, поток отдаётся без ограничения по времени
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Warn("failed to reset write deadline", sl.Err(err))
+	}
+
+	name := path.Base(f.Path)
+	// тип определяется по расширению, если оно неизвестно - ServeContent определит его по первым байтам
+	if contentType := ContentType(name); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, strings.ToLower(hash), index))
+
+	http.ServeContent(w, r, name, time.Time{}, f)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, engine.ErrNotFound), errors.Is(err, engine.ErrFileNotFound):
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+	case errors.Is(err, engine.ErrPaused):
+		resp.WriteError(w, r, http.StatusConflict, resp.CodeConflict, err.Error())
+	case errors.Is(err, engine.ErrNoMetadata):
+		resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeNoMetadata, err.Error())
+	case errors.Is(err, context.Canceled):
+		// клиент ушёл, пока ждали метаданные
+	default:
+		h.log.Error("failed to open file", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to open file")
+	}
+}
+
+// типы, которых нет в mime.TypeByExtension на минимальных образах (alpine без /etc/mime.types)
+var contentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+	".avi":  "video/x-msvideo",
+	".mov":  "video/quicktime",
+	".ts":   "video/mp2t",
+	".m2ts": "video/mp2t",
+	".wmv":  "video/x-ms-wmv",
+	".flv":  "video/x-flv",
+	".mpg":  "video/mpeg",
+	".mpeg": "video/mpeg",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".flac": "audio/flac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".mka":  "audio/x-matroska",
+	".srt":  "application/x-subrip",
+	".vtt":  "text/vtt",
+	".ass":  "text/x-ssa",
+}
+
+// ContentType возвращает MIME-тип по расширению файла или пустую строку, если он неизвестен
+func ContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine/enginetest"
+)
+
+func TestStream(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "Show", map[string]int{
+		"Show.S01E01.mkv": 300 << 10,
+		"Show.S01E02.mp4": 200 << 10,
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Mount("/stream", New(slogdiscard.NewDiscardLogger(), e).Routes())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// файлы в раздаче отсортированы по пути
+	mkv := seeder.Files["Show.S01E01.mkv"]
+
+	t.Run("whole file", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/stream/" + seeder.InfoHash + "/0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+
+		if res.StatusCode != http.StatusOK || !bytes.Equal(body, mkv) {
+			t.Errorf("status = %d, body %d bytes, want 200 with %d bytes", res.StatusCode, len(body), len(mkv))
+		}
+		if got := res.Header.Get("Content-Type"); got != "video/x-matroska" {
+			t.Errorf("Content-Type = %q, want video/x-matroska", got)
+		}
+		if got := res.Header.Get("Accept-Ranges"); got != "bytes" {
+			t.Errorf("Accept-Ranges = %q, want bytes", got)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/stream/"+seeder.InfoHash+"/1", nil)
+		req.Header.Set("Range", "bytes=100000-100099")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+
+		want := seeder.Files["Show.S01E02.mp4"][100000:100100]
+		if res.StatusCode != http.StatusPartialContent || !bytes.Equal(body, want) {
+			t.Errorf("status = %d, body %d bytes, want 206 with the requested range", res.StatusCode, len(body))
+		}
+		if got := res.Header.Get("Content-Range"); got != "bytes 100000-100099/204800" {
+			t.Errorf("Content-Range = %q", got)
+		}
+		if got := res.Header.Get("Content-Type"); got != "video/mp4" {
+			t.Errorf("Content-Type = %q, want video/mp4", got)
+		}
+	})
+
+	for _, tt := range []struct {
+		name, path string
+		status     int
+	}{
+		{"unknown torrent", "/stream/0000000000000000000000000000000000000000/0", http.StatusNotFound},
+		{"unknown file", "/stream/" + seeder.InfoHash + "/5", http.StatusNotFound},
+		{"invalid index", "/stream/" + seeder.InfoHash + "/first", http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package subtitles
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+)
+
+// Describe добавляет в документ OpenAPI описание субтитров раздач
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("SubtitleTrack", TrackResponse{})
+
+	hash := openapi.Parameter{Name: "hash", In: "path", Required: true, Description: "Info-hash in hex", Schema: &openapi.Schema{Type: "string"}}
+
+	list := openapi.ErrorResponses("400", "401", "403", "404", "409", "429", "500", "504")
+	list["200"] = &openapi.Response{
+		Description: "Subtitle files of the torrent with the video each one belongs to, matched by file name, folder or episode number",
+		Content:     openapi.JSONContent(&openapi.Schema{Type: "array", Items: openapi.Ref("SubtitleTrack")}),
+	}
+	doc.AddOperation("GET", "/subtitles/{hash}", &openapi.Operation{
+		OperationID: "listSubtitles",
+		Summary:     "List subtitle tracks of a torrent",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			hash,
+			{Name: "video", In: "query", Description: "Only subtitles of the video with this file index", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: list,
+	})
+
+	vtt := openapi.ErrorResponses("400", "401", "403", "404", "409", "415", "429", "500", "504")
+	vtt["200"] = &openapi.Response{
+		Description: "Subtitles converted from SRT, ASS or SSA to WebVTT in UTF-8, the source encoding is detected (UTF-8, UTF-16, Windows-1251, KOI8-R, Windows-1252)",
+		Content:     map[string]*openapi.MediaType{"text/vtt": {Schema: &openapi.Schema{Type: "string"}}},
+	}
+	doc.AddOperation("GET", "/subtitles/{hash}/{index}.vtt", &openapi.Operation{
+		OperationID: "getSubtitlesVTT",
+		Summary:     "Subtitle file as WebVTT for HTML5 players",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			hash,
+			{Name: "index", In: "path", Required: true, Description: "File index from the torrent file list", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: vtt,
+	})
+}
@@ -0,0 +1,212 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/lib/subtitles"
+	"torrentServer/internal/services/engine"
+)
+
+// сколько ждать загрузки файла субтитров от пиров
+const readTimeout = time.Minute
+
+// Files - файлы раздач движка (engine.Engine)
+type Files interface {
+	Files(ctx context.Context, infoHash string) ([]engine.File, error)
+	OpenFile(ctx context.Context, infoHash string, index int) (*engine.FileReader, error)
+}
+
+type TrackResponse struct {
+	Index     int    `json:"index"` // индекс файла в раздаче, по нему запрашивается .vtt
+	Path      string `json:"path"`
+	Format    string `json:"format"`             // расширение без точки: srt, ass, ssa, vtt, sub
+	Language  string `json:"language,omitempty"` // ISO 639-1, если указан в имени файла или каталога
+	Label     string `json:"label"`              // подпись дорожки для плеера
+	Forced    bool   `json:"forced"`
+	Video     *int   `json:"video"`     // индекс видео, к которому относятся субтитры, null - не найдено
+	Supported bool   `json:"supported"` // может быть отдан в WebVTT
+}
+
+type Handler struct {
+	log   *slog.Logger
+	files Files
+}
+
+func New(log *slog.Logger, files Files) *Handler {
+	return &Handler{
+		log:   log.With(slog.String("component", "handlers/subtitles")),
+		files: files,
+	}
+}
+
+// Routes - субтитры раздачи. Маршруты не должны стоять за middleware/timeout:
+// файл субтитров может ещё скачиваться.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/{hash}", h.List)
+	r.Get("/{hash}/{index}.vtt", h.VTT)
+	return r
+}
+
+// List возвращает субтитры раздачи и видео, к которым они относятся.
+// С параметром video - только субтитры этого видео.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	video := -1
+	if v := r.URL.Query().Get("video"); v != "" {
+		var err error
+		if video, err = strconv.Atoi(v); err != nil || video < 0 {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "invalid video index")
+			return
+		}
+	}
+
+	files, err := h.files.Files(r.Context(), chi.URLParam(r, "hash"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.JSON(w, http.StatusOK, Tracks(files, video))
+}
+
+// Tracks находит субтитры среди файлов раздачи, video >= 0 оставляет только субтитры этого видео
+func Tracks(files []engine.File, video int) []TrackResponse {
+	var videos []engine.File
+	var paths []string
+	for _, f := range files {
+		if f.Kind == engine.KindVideo {
+			videos = append(videos, f)
+			paths = append(paths, f.Path)
+		}
+	}
+
+	tracks := make([]TrackResponse, 0)
+	for _, f := range files {
+		if f.Kind != engine.KindSubtitle {
+			continue
+		}
+
+		t := TrackResponse{
+			Index:     f.Index,
+			Path:      f.Path,
+			Format:    strings.TrimPrefix(strings.ToLower(path.Ext(f.Path)), "."),
+			Forced:    subtitles.Forced(f.Path),
+			Supported: subtitles.Supported(f.Path),
+		}
+		var videoPath string
+		if i := subtitles.MatchVideo(f.Path, paths); i >= 0 {
+			t.Video = &videos[i].Index
+			videoPath = videos[i].Path
+		}
+		if video >= 0 && (t.Video == nil || *t.Video != video) {
+			continue
+		}
+
+		t.Language = subtitles.Language(f.Path, videoPath)
+		t.Label = subtitles.LanguageName[t.Language]
+		if t.Label == "" {
+			t.Label = path.Base(f.Path)
+		}
+		if t.Forced {
+			t.Label += " (forced)"
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// VTT отдаёт файл субтитров в WebVTT, кодировка исходного файла определяется автоматически
+func (h *Handler) VTT(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "invalid file index")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	// вид файла проверяется по списку файлов: OpenFile открыл бы поток и поднял приоритет
+	// кусков, а для видео это загрузка, которую никто не будет читать
+	files, err := h.files.Files(ctx, hash)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	i := slices.IndexFunc(files, func(f engine.File) bool { return f.Index == index })
+	if i < 0 {
+		h.writeError(w, r, engine.ErrFileNotFound)
+		return
+	}
+	file := files[i]
+	if file.Kind != engine.KindSubtitle {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "file is not subtitles")
+		return
+	}
+	if !subtitles.Supported(file.Path) || file.Size > subtitles.MaxSize {
+		resp.WriteError(w, r, http.StatusUnsupportedMediaType, resp.CodeUnsupported,
+			fmt.Sprintf("%s subtitles can not be converted to WebVTT", path.Ext(file.Path)))
+		return
+	}
+
+	f, err := h.files.OpenFile(ctx, hash, index)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	vtt, charset, err := subtitles.ToVTT(f.Path, data)
+	if errors.Is(err, subtitles.ErrInvalid) {
+		resp.WriteError(w, r, http.StatusUnsupportedMediaType, resp.CodeUnsupported, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to convert subtitles", slog.String("path", f.Path), sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to convert subtitles")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("X-Source-Charset", charset)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d-vtt"`, strings.ToLower(hash), index))
+	w.WriteHeader(http.StatusOK)
+	w.Write(vtt)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, engine.ErrNotFound), errors.Is(err, engine.ErrFileNotFound):
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+	case errors.Is(err, engine.ErrPaused):
+		resp.WriteError(w, r, http.StatusConflict, resp.CodeConflict, err.Error())
+	case errors.Is(err, engine.ErrNoMetadata), errors.Is(err, context.DeadlineExceeded):
+		resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeNoMetadata, "timed out waiting for torrent data")
+	case errors.Is(err, context.Canceled):
+		// клиент ушёл
+	default:
+		h.log.Error("failed to read subtitles", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to read subtitles")
+	}
+}
@@ -0,0 +1,151 @@
+package subtitles
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"golang.org/x/text/encoding/charmap"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/services/engine/enginetest"
+)
+
+func TestSubtitles(t *testing.T) {
+	srt, _ := charmap.Windows1251.NewEncoder().String("1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Привет, как дела?</i>\r\n")
+	seeder := enginetest.NewSeederFiles(t, "Show", map[string][]byte{
+		"Show.S01E01.mkv":          make([]byte, 64<<10),
+		"Show.S01E02.mkv":          make([]byte, 64<<10),
+		"Subs/Rus/Show.S01E02.srt": []byte(srt),
+		"Show.S01E01.eng.sub":      []byte("{1}{25}Hello"),
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Mount("/subtitles", New(slogdiscard.NewDiscardLogger(), e).Routes())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	get := func(t *testing.T, path string) (*http.Response, []byte) {
+		t.Helper()
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, body
+	}
+
+	// файлы раздачи отсортированы по пути: 0 - Show.S01E01.eng.sub, 1 - Show.S01E01.mkv,
+	// 2 - Show.S01E02.mkv, 3 - Subs/Rus/Show.S01E02.srt
+	t.Run("list", func(t *testing.T) {
+		res, body := get(t, "/subtitles/"+seeder.InfoHash)
+		var tracks []TrackResponse
+		if err := json.Unmarshal(body, &tracks); err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", res.StatusCode, body)
+		}
+		if len(tracks) != 2 {
+			t.Fatalf("tracks = %+v, want 2", tracks)
+		}
+
+		sub, srt := tracks[0], tracks[1]
+		if sub.Index != 0 || sub.Video == nil || *sub.Video != 1 || sub.Language != "en" || sub.Supported || sub.Format != "sub" {
+			t.Errorf("sub track = %+v", sub)
+		}
+		if srt.Index != 3 || srt.Video == nil || *srt.Video != 2 || srt.Language != "ru" || !srt.Supported || srt.Label != "Русский" {
+			t.Errorf("srt track = %+v", srt)
+		}
+
+		_, body = get(t, "/subtitles/"+seeder.InfoHash+"?video=2")
+		if err := json.Unmarshal(body, &tracks); err != nil || len(tracks) != 1 || tracks[0].Index != 3 {
+			t.Errorf("tracks of video 2 = %s", body)
+		}
+	})
+
+	t.Run("vtt", func(t *testing.T) {
+		res, body := get(t, "/subtitles/"+seeder.InfoHash+"/3.vtt")
+		want := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\n<i>Привет, как дела?</i>\n"
+		if res.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("status = %d, body = %q, want %q", res.StatusCode, body, want)
+		}
+		if got := res.Header.Get("Content-Type"); got != "text/vtt; charset=utf-8" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := res.Header.Get("X-Source-Charset"); got != "windows-1251" {
+			t.Errorf("X-Source-Charset = %q, want windows-1251", got)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			path   string
+			status int
+		}{
+			{"/subtitles/" + seeder.InfoHash + "/0.vtt", http.StatusUnsupportedMediaType},
+			{"/subtitles/" + seeder.InfoHash + "/1.vtt", http.StatusNotFound},
+			{"/subtitles/" + seeder.InfoHash + "/9.vtt", http.StatusNotFound},
+			{"/subtitles/0123456789abcdef0123456789abcdef01234567", http.StatusNotFound},
+			{"/subtitles/" + seeder.InfoHash + "?video=x", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			if res, body := get(t, tt.path); res.StatusCode != tt.status {
+				t.Errorf("GET %s = %d %s, want %d", tt.path, res.StatusCode, body, tt.status)
+			}
+		}
+	})
+}
+
+// openedFiles запоминает файлы, открытые через OpenFile
+type openedFiles struct {
+	*engine.Engine
+	opened []int
+}
+
+func (f *openedFiles) OpenFile(ctx context.Context, infoHash string, index int) (*engine.FileReader, error) {
+	f.opened = append(f.opened, index)
+	return f.Engine.OpenFile(ctx, infoHash, index)
+}
+
+func TestVTTDoesNotOpenOtherFiles(t *testing.T) {
+	seeder := enginetest.NewSeederFiles(t, "Show", map[string][]byte{
+		"Show.S01E01.mkv": make([]byte, 64<<10),
+		"Show.S01E01.srt": []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"),
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	if _, err := e.Add(seeder.Magnet, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	files := &openedFiles{Engine: e}
+
+	router := chi.NewRouter()
+	router.Mount("/subtitles", New(slogdiscard.NewDiscardLogger(), files).Routes())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// 0 - видео, 1 - субтитры
+	for _, tt := range []struct {
+		index  string
+		status int
+	}{{"0", http.StatusNotFound}, {"1", http.StatusOK}} {
+		res, err := http.Get(srv.URL + "/subtitles/" + seeder.InfoHash + "/" + tt.index + ".vtt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("GET %s.vtt = %d, want %d", tt.index, res.StatusCode, tt.status)
+		}
+	}
+	if len(files.opened) != 1 || files.opened[0] != 1 {
+		t.Errorf("opened files = %v, want only subtitles 1", files.opened)
+	}
+}
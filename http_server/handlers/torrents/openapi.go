@@ -0,0 +1,124 @@
+package torrents
+
+import (
+	"torrentServer/internal/lib/api/openapi"
+	"torrentServer/internal/services/engine"
+)
+
+// Describe добавляет в документ OpenAPI описание управления раздачами
+func Describe(doc *openapi.Document) {
+	doc.AddSchema("AddTorrentRequest", AddRequest{})
+	kinds := []interface{}{engine.KindVideo, engine.KindAudio, engine.KindSubtitle, engine.KindOther}
+	doc.AddSchema("TorrentFile", FileResponse{}).Properties["kind"].Enum = kinds
+	doc.AddSchema("MetadataFile", MetadataFileResponse{}).Properties["kind"].Enum = kinds
+	doc.AddSchema("TorrentMetadata", MetadataResponse{}).Properties["files"] = &openapi.Schema{Type: "array", Items: openapi.Ref("MetadataFile")}
+	torrent := doc.AddSchema("Torrent", TorrentResponse{})
+	torrent.Properties["state"].Enum = []interface{}{engine.StateMetadata, engine.StateDownloading, engine.StateSeeding, engine.StatePaused}
+	torrent.Properties["files"] = &openapi.Schema{Type: "array", Items: openapi.Ref("TorrentFile")}
+	doc.AddSchema("TorrentStream", StreamResponse{})
+	torrent.Properties["streams"] = &openapi.Schema{Type: "array", Items: openapi.Ref("TorrentStream")}
+
+	hash := openapi.Parameter{Name: "hash", In: "path", Required: true, Description: "Info-hash in hex", Schema: &openapi.Schema{Type: "string"}}
+	one := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: openapi.JSONContent(openapi.Ref("Torrent"))}
+	}
+
+	added := openapi.ErrorResponses("400", "401", "403", "429", "500")
+	added["201"] = one("Added torrent, or the current state if it was already added")
+	doc.AddOperation("POST", "/torrents", &openapi.Operation{
+		OperationID: "addTorrent",
+		Summary:     "Add torrent by magnet link (magnetUri of a search result) or info-hash",
+		Tags:        []string{"torrents"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONContent(openapi.Ref("AddTorrentRequest"))},
+		Responses:   added,
+	})
+
+	list := openapi.ErrorResponses("401", "403", "429", "500")
+	list["200"] = &openapi.Response{
+		Description: "All torrents in the order they were added",
+		Content:     openapi.JSONContent(&openapi.Schema{Type: "array", Items: openapi.Ref("Torrent")}),
+	}
+	doc.AddOperation("GET", "/torrents", &openapi.Operation{
+		OperationID: "listTorrents",
+		Summary:     "List torrents",
+		Tags:        []string{"torrents"},
+		Responses:   list,
+	})
+
+	resolved := openapi.ErrorResponses("400", "401", "403", "429", "500", "504")
+	resolved["200"] = &openapi.Response{
+		Description: "Files of the torrent, metadata is fetched from peers (BEP 9) and cached by info-hash",
+		Content:     openapi.JSONContent(openapi.Ref("TorrentMetadata")),
+	}
+	doc.AddOperation("GET", "/torrents/resolve", &openapi.Operation{
+		OperationID: "resolveTorrent",
+		Summary:     "List files of a torrent without adding it",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			{Name: "magnet", In: "query", Description: "Magnet link, e.g. magnetUri of a search result", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "info_hash", In: "query", Description: "Info-hash in hex, used when magnet is empty", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: resolved,
+	})
+
+	upload := doc.AddSchema("TorrentUpload", UploadResponse{})
+	upload.Properties["files"] = &openapi.Schema{Type: "array", Items: openapi.Ref("MetadataFile")}
+	upload.Properties["torrent"] = openapi.Ref("Torrent")
+	uploaded := openapi.ErrorResponses("400", "401", "403", "413", "429", "500")
+	uploaded["200"] = &openapi.Response{
+		Description: "Parsed torrent file",
+		Content:     openapi.JSONContent(openapi.Ref("TorrentUpload")),
+	}
+	uploaded["201"] = &openapi.Response{
+		Description: "Parsed torrent file added to the engine (add=true), torrent holds its state",
+		Content:     openapi.JSONContent(openapi.Ref("TorrentUpload")),
+	}
+	binary := &openapi.Schema{Type: "string", Format: "binary"}
+	doc.AddOperation("POST", "/torrents/upload", &openapi.Operation{
+		OperationID: "uploadTorrent",
+		Summary:     "Inspect a .torrent file and optionally add it",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			{Name: "add", In: "query", Description: "Add the torrent to the engine", Schema: &openapi.Schema{Type: "boolean", Default: false}},
+			{Name: "paused", In: "query", Description: "Add without starting the download", Schema: &openapi.Schema{Type: "boolean", Default: false}},
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+			"application/x-bittorrent": {Schema: binary},
+			"multipart/form-data": {Schema: &openapi.Schema{
+				Type:       "object",
+				Properties: map[string]*openapi.Schema{UploadField: binary},
+				Required:   []string{UploadField},
+			}},
+		}},
+		Responses: uploaded,
+	})
+
+	for _, op := range []struct{ method, path, id, summary string }{
+		{"GET", "/torrents/{hash}", "getTorrent", "Get torrent state and files"},
+		{"POST", "/torrents/{hash}/start", "startTorrent", "Resume downloading and seeding"},
+		{"POST", "/torrents/{hash}/pause", "pauseTorrent", "Pause downloading and seeding"},
+	} {
+		responses := openapi.ErrorResponses("401", "403", "404", "429", "500")
+		responses["200"] = one("Torrent state")
+		doc.AddOperation(op.method, op.path, &openapi.Operation{
+			OperationID: op.id,
+			Summary:     op.summary,
+			Tags:        []string{"torrents"},
+			Parameters:  []openapi.Parameter{hash},
+			Responses:   responses,
+		})
+	}
+
+	removed := openapi.ErrorResponses("401", "403", "404", "429", "500")
+	removed["204"] = &openapi.Response{Description: "Torrent removed"}
+	doc.AddOperation("DELETE", "/torrents/{hash}", &openapi.Operation{
+		OperationID: "removeTorrent",
+		Summary:     "Remove torrent",
+		Tags:        []string{"torrents"},
+		Parameters: []openapi.Parameter{
+			hash,
+			{Name: "delete_data", In: "query", Description: "Also delete downloaded files", Schema: &openapi.Schema{Type: "boolean", Default: false}},
+		},
+		Responses: removed,
+	})
+}
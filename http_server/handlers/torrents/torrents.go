@@ -0,0 +1,293 @@
+package torrents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+// Engine - встроенный BitTorrent-клиент (engine.Engine)
+type Engine interface {
+	Add(source string, paused bool) (engine.Status, error)
+	AddMetaInfo(data []byte, paused bool) (engine.Status, error)
+	Start(infoHash string) (engine.Status, error)
+	Pause(infoHash string) (engine.Status, error)
+	Remove(infoHash string, deleteData bool) error
+	Get(infoHash string) (engine.Status, error)
+	List() []engine.Status
+	Resolve(ctx context.Context, source string) (engine.Metadata, error)
+}
+
+// AddRequest - раздача добавляется по magnetUri из результата поиска или по info-hash
+type AddRequest struct {
+	Magnet   string `json:"magnet"`
+	InfoHash string `json:"info_hash"`
+	Paused   bool   `json:"paused"` // добавить, не начиная загрузку
+}
+
+type TorrentResponse struct {
+	InfoHash       string  `json:"info_hash"`
+	Name           string  `json:"name"`
+	State          string  `json:"state"` // metadata, downloading, seeding или paused
+	Size           int64   `json:"size"`
+	BytesCompleted int64   `json:"bytes_completed"`
+	Progress       float64 `json:"progress"` // от 0 до 1
+	Peers          int     `json:"peers"`
+	Seeders        int     `json:"seeders"`
+	Downloaded     int64   `json:"downloaded"` // за все запуски сервера
+	Uploaded       int64   `json:"uploaded"`
+	Ratio          float64 `json:"ratio"`
+	SeedingTime    int64   `json:"seeding_time"` // секунды раздачи после загрузки
+	// требования трекера из выдачи поиска, по ним политика раздачи останавливает раздачу; 0 - требования нет
+	MinimumRatio    float64          `json:"minimum_ratio"`
+	MinimumSeedTime int64            `json:"minimum_seed_time"` // секунды
+	Files           []FileResponse   `json:"files"`
+	Streams         []StreamResponse `json:"streams"` // открытые через /stream потоки
+	AddedAt         time.Time        `json:"added_at"`
+}
+
+// StreamResponse - буфер потока: сколько скачано впереди позиции чтения
+type StreamResponse struct {
+	FileIndex int       `json:"file_index"`
+	Path      string    `json:"path"`
+	Position  int64     `json:"position"`
+	BytesRead int64     `json:"bytes_read"`
+	Readahead int64     `json:"readahead"`
+	Buffered  int64     `json:"buffered"`
+	Health    float64   `json:"health"` // от 0 до 1, 1 - окно упреждающей загрузки заполнено
+	OpenedAt  time.Time `json:"opened_at"`
+}
+
+type FileResponse struct {
+	Index          int    `json:"index"`
+	Path           string `json:"path"`
+	Size           int64  `json:"size"`
+	BytesCompleted int64  `json:"bytes_completed"`
+	Kind           string `json:"kind"`     // video, audio, subtitle или other
+	Playable       bool   `json:"playable"` // видео или аудио, можно открыть через /stream
+}
+
+// MetadataResponse - содержимое раздачи, полученное без её добавления
+type MetadataResponse struct {
+	InfoHash    string                 `json:"info_hash"`
+	Name        string                 `json:"name"`
+	Size        int64                  `json:"size"`
+	PieceLength int64                  `json:"piece_length"`
+	Pieces      int                    `json:"pieces"`
+	Files       []MetadataFileResponse `json:"files"`
+}
+
+type MetadataFileResponse struct {
+	Index    int    `json:"index"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Kind     string `json:"kind"`
+	Playable bool   `json:"playable"`
+}
+
+type Handler struct {
+	log    *slog.Logger
+	engine Engine
+}
+
+func New(log *slog.Logger, engine Engine) *Handler {
+	return &Handler{
+		log:    log.With(slog.String("component", "handlers/torrents")),
+		engine: engine,
+	}
+}
+
+// Routes - управление раздачами встроенного клиента
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Add)
+	r.Get("/", h.List)
+	r.Get("/{hash}", h.Get)
+	r.Post("/{hash}/start", h.Start)
+	r.Post("/{hash}/pause", h.Pause)
+	r.Delete("/{hash}", h.Remove)
+	return r
+}
+
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	var req AddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, "failed to decode request body")
+		return
+	}
+
+	source := req.Magnet
+	if source == "" {
+		source = req.InfoHash
+	}
+
+	status, err := h.engine.Add(source, req.Paused)
+	if errors.Is(err, engine.ErrInvalidSource) {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to add torrent", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to add torrent")
+		return
+	}
+
+	resp.JSON(w, http.StatusCreated, toResponse(status))
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	statuses := h.engine.List()
+
+	list := make([]TorrentResponse, 0, len(statuses))
+	for _, s := range statuses {
+		list = append(list, toResponse(s))
+	}
+
+	resp.JSON(w, http.StatusOK, list)
+}
+
+// Resolve возвращает список файлов раздачи по ?magnet= или ?info_hash=, не добавляя её.
+// Метаданные запрашиваются у пиров, поэтому первый запрос может занять до metadata_timeout:
+// маршрут подключается без middleware/timeout и снимает WriteTimeout сервера.
+func (h *Handler) Resolve(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("magnet")
+	if source == "" {
+		source = r.URL.Query().Get("info_hash")
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Warn("failed to reset write deadline", sl.Err(err))
+	}
+
+	md, err := h.engine.Resolve(r.Context(), source)
+	switch {
+	case errors.Is(err, engine.ErrInvalidSource):
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	case errors.Is(err, engine.ErrNoMetadata):
+		resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeNoMetadata, err.Error())
+		return
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeNoMetadata, "request timed out while waiting for torrent metadata")
+		return
+	case err != nil:
+		h.log.Error("failed to resolve torrent", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to resolve torrent")
+		return
+	}
+
+	mr := MetadataResponse{
+		InfoHash:    md.InfoHash,
+		Name:        md.Name,
+		Size:        md.Size,
+		PieceLength: md.PieceLength,
+		Pieces:      md.Pieces,
+		Files:       make([]MetadataFileResponse, 0, len(md.Files)),
+	}
+	for _, f := range md.Files {
+		mr.Files = append(mr.Files, MetadataFileResponse{
+			Index:    f.Index,
+			Path:     f.Path,
+			Size:     f.Size,
+			Kind:     f.Kind,
+			Playable: engine.Playable(f.Kind),
+		})
+	}
+
+	resp.JSON(w, http.StatusOK, mr)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	status, err := h.engine.Get(chi.URLParam(r, "hash"))
+	h.writeStatus(w, r, status, err)
+}
+
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	status, err := h.engine.Start(chi.URLParam(r, "hash"))
+	h.writeStatus(w, r, status, err)
+}
+
+func (h *Handler) Pause(w http.ResponseWriter, r *http.Request) {
+	status, err := h.engine.Pause(chi.URLParam(r, "hash"))
+	h.writeStatus(w, r, status, err)
+}
+
+// Remove удаляет раздачу, с ?delete_data=true - вместе со скачанными файлами
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) {
+	deleteData, _ := strconv.ParseBool(r.URL.Query().Get("delete_data"))
+
+	err := h.engine.Remove(chi.URLParam(r, "hash"), deleteData)
+	if errors.Is(err, engine.ErrNotFound) {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to remove torrent", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to remove torrent")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) writeStatus(w http.ResponseWriter, r *http.Request, status engine.Status, err error) {
+	if errors.Is(err, engine.ErrNotFound) {
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to update torrent", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to update torrent")
+		return
+	}
+
+	resp.JSON(w, http.StatusOK, toResponse(status))
+}
+
+func toResponse(s engine.Status) TorrentResponse {
+	tr := TorrentResponse{
+		InfoHash:        s.InfoHash,
+		Name:            s.Name,
+		State:           s.State,
+		Size:            s.Size,
+		BytesCompleted:  s.BytesCompleted,
+		Peers:           s.Peers,
+		Seeders:         s.Seeders,
+		Downloaded:      s.Downloaded,
+		Uploaded:        s.Uploaded,
+		Ratio:           s.Ratio,
+		SeedingTime:     int64(s.SeedingTime.Seconds()),
+		MinimumRatio:    s.MinimumRatio,
+		MinimumSeedTime: int64(s.MinimumSeedTime.Seconds()),
+		Files:           make([]FileResponse, 0, len(s.Files)),
+		Streams:         make([]StreamResponse, 0, len(s.Streams)),
+		AddedAt:         s.AddedAt,
+	}
+	if s.Size > 0 {
+		tr.Progress = float64(s.BytesCompleted) / float64(s.Size)
+	}
+	for _, f := range s.Files {
+		tr.Files = append(tr.Files, FileResponse{
+			Index:          f.Index,
+			Path:           f.Path,
+			Size:           f.Size,
+			BytesCompleted: f.BytesCompleted,
+			Kind:           f.Kind,
+			Playable:       engine.Playable(f.Kind),
+		})
+	}
+	for _, st := range s.Streams {
+		tr.Streams = append(tr.Streams, StreamResponse(st))
+	}
+	return tr
+}
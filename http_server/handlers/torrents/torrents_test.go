@@ -0,0 +1,218 @@
+package torrents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/lib/torrentfile"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/services/engine/enginetest"
+)
+
+// brokenEngine - движок, у которого не работает хранилище
+type brokenEngine struct {
+	*engine.Engine
+}
+
+func (brokenEngine) Add(string, bool) (engine.Status, error) {
+	return engine.Status{}, errors.New("disk I/O error")
+}
+
+func (brokenEngine) Remove(string, bool) error {
+	return errors.New("disk I/O error")
+}
+
+func newServer(t *testing.T, e Engine) *httptest.Server {
+	t.Helper()
+
+	h := New(slogdiscard.NewDiscardLogger(), e)
+	router := chi.NewRouter()
+	router.Post("/torrents/upload", h.Upload)
+	router.Mount("/torrents", h.Routes())
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method, url, contentType string, body io.Reader) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res.StatusCode, data
+}
+
+func errorCode(t *testing.T, data []byte) string {
+	t.Helper()
+	var body resp.ErrorResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("decode error response %q: %v", data, err)
+	}
+	return body.Error.Code
+}
+
+func TestAddRemove(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.mkv", map[string]int{"video.mkv": 64 << 10})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	srv := newServer(t, e)
+
+	errorTests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"broken json", `{"magnet":`, http.StatusBadRequest, resp.CodeBadRequest},
+		{"invalid source", `{"magnet":"http://example.com/file.torrent"}`, http.StatusBadRequest, resp.CodeBadRequest},
+		{"empty source", `{}`, http.StatusBadRequest, resp.CodeBadRequest},
+	}
+	for _, tt := range errorTests {
+		status, data := do(t, http.MethodPost, srv.URL+"/torrents", "application/json", strings.NewReader(tt.body))
+		if status != tt.status || errorCode(t, data) != tt.code {
+			t.Errorf("%s: Add() = %d %s, want %d %s", tt.name, status, data, tt.status, tt.code)
+		}
+	}
+
+	status, data := do(t, http.MethodPost, srv.URL+"/torrents", "application/json",
+		strings.NewReader(`{"magnet":"`+seeder.Magnet+`","paused":true}`))
+	var added TorrentResponse
+	if err := json.Unmarshal(data, &added); err != nil || status != http.StatusCreated {
+		t.Fatalf("Add() = %d %s, want 201", status, data)
+	}
+	if added.InfoHash != seeder.InfoHash || added.State != engine.StatePaused {
+		t.Errorf("Add() = %+v, want paused %s", added, seeder.InfoHash)
+	}
+
+	if status, data := do(t, http.MethodDelete, srv.URL+"/torrents/0000000000000000000000000000000000000000", "", nil); status != http.StatusNotFound || errorCode(t, data) != resp.CodeNotFound {
+		t.Errorf("Remove(unknown) = %d %s, want 404", status, data)
+	}
+	if status, data := do(t, http.MethodDelete, srv.URL+"/torrents/"+seeder.InfoHash, "", nil); status != http.StatusNoContent {
+		t.Errorf("Remove() = %d %s, want 204", status, data)
+	}
+	if status, _ := do(t, http.MethodGet, srv.URL+"/torrents/"+seeder.InfoHash, "", nil); status != http.StatusNotFound {
+		t.Errorf("Get() after Remove() = %d, want 404", status)
+	}
+
+	// внутренние ошибки движка не раскрываются клиенту
+	broken := newServer(t, brokenEngine{e})
+	status, data = do(t, http.MethodPost, broken.URL+"/torrents", "application/json", strings.NewReader(`{"magnet":"`+seeder.Magnet+`"}`))
+	if status != http.StatusInternalServerError || errorCode(t, data) != resp.CodeInternal || bytes.Contains(data, []byte("disk")) {
+		t.Errorf("Add() with broken storage = %d %s, want 500 without details", status, data)
+	}
+	status, data = do(t, http.MethodDelete, broken.URL+"/torrents/"+seeder.InfoHash, "", nil)
+	if status != http.StatusInternalServerError || errorCode(t, data) != resp.CodeInternal {
+		t.Errorf("Remove() with broken storage = %d %s, want 500", status, data)
+	}
+}
+
+func multipartBody(t *testing.T, field string, data []byte) (string, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("comment", "uploaded from test")
+	fw, err := mw.CreateFormFile(field, "video.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	return mw.FormDataContentType(), &buf
+}
+
+func TestUpload(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "Show", map[string]int{
+		"Show.S01E01.mkv": 64 << 10,
+		"Show.S01E01.srt": 1 << 10,
+	})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+	srv := newServer(t, e)
+
+	upload := func(t *testing.T, query, contentType string, body io.Reader) (int, UploadResponse, []byte) {
+		t.Helper()
+		status, data := do(t, http.MethodPost, srv.URL+"/torrents/upload"+query, contentType, body)
+		var ur UploadResponse
+		if status < 300 {
+			if err := json.Unmarshal(data, &ur); err != nil {
+				t.Fatalf("decode upload response %q: %v", data, err)
+			}
+		}
+		return status, ur, data
+	}
+
+	contentType, form := multipartBody(t, UploadField, seeder.MetaInfo)
+	bodies := []struct {
+		name        string
+		contentType string
+		body        io.Reader
+	}{
+		{"raw body", "application/x-bittorrent", bytes.NewReader(seeder.MetaInfo)},
+		{"multipart form", contentType, form},
+	}
+	for _, b := range bodies {
+		status, ur, data := upload(t, "", b.contentType, b.body)
+		if status != http.StatusOK {
+			t.Fatalf("%s: Upload() = %d %s, want 200", b.name, status, data)
+		}
+		if ur.InfoHash != seeder.InfoHash || ur.Name != "Show" || len(ur.Files) != 2 || ur.Torrent != nil {
+			t.Errorf("%s: Upload() = %+v", b.name, ur)
+		}
+		if !strings.HasPrefix(ur.Magnet, "magnet:?xt=urn:btih:"+seeder.InfoHash) {
+			t.Errorf("%s: magnet = %q", b.name, ur.Magnet)
+		}
+	}
+	// без add=true раздача только разбирается
+	if list := e.List(); len(list) != 0 {
+		t.Errorf("List() after upload = %d torrents, want 0", len(list))
+	}
+
+	contentType, form = multipartBody(t, "torrent", seeder.MetaInfo)
+	errorTests := []struct {
+		name        string
+		contentType string
+		body        io.Reader
+		status      int
+	}{
+		{"form without file field", contentType, form, http.StatusBadRequest},
+		{"empty body", "application/x-bittorrent", strings.NewReader(""), http.StatusBadRequest},
+		{"not a torrent", "application/x-bittorrent", strings.NewReader("<html></html>"), http.StatusBadRequest},
+		{"too large", "application/x-bittorrent", bytes.NewReader(make([]byte, torrentfile.MaxSize+128<<10)), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range errorTests {
+		status, _, data := upload(t, "", tt.contentType, tt.body)
+		if status != tt.status || errorCode(t, data) != resp.CodeBadRequest {
+			t.Errorf("%s: Upload() = %d %s, want %d", tt.name, status, data, tt.status)
+		}
+	}
+
+	status, ur, data := upload(t, "?add=true&paused=true", "application/x-bittorrent", bytes.NewReader(seeder.MetaInfo))
+	if status != http.StatusCreated || ur.Torrent == nil {
+		t.Fatalf("Upload(add=true) = %d %s, want 201 with torrent", status, data)
+	}
+	if ur.Torrent.InfoHash != seeder.InfoHash || ur.Torrent.State != engine.StatePaused || len(ur.Torrent.Files) != 2 {
+		t.Errorf("Upload(add=true) torrent = %+v, want paused with files from .torrent", ur.Torrent)
+	}
+	if _, err := e.Get(seeder.InfoHash); err != nil {
+		t.Errorf("Get() after Upload(add=true) error = %v", err)
+	}
+}
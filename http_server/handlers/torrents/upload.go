@@ -0,0 +1,163 @@
+package torrents
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/lib/torrentfile"
+	"torrentServer/internal/services/engine"
+)
+
+// UploadField - поле multipart-формы с .torrent файлом
+const UploadField = "file"
+
+// uploadTimeout - время на приём файла до torrentfile.MaxSize от медленного клиента
+const uploadTimeout = 2 * time.Minute
+
+// UploadResponse - разобранный .torrent файл
+type UploadResponse struct {
+	InfoHash     string                 `json:"info_hash"`
+	Name         string                 `json:"name"`
+	Magnet       string                 `json:"magnet"`
+	Size         int64                  `json:"size"`
+	PieceLength  int64                  `json:"piece_length"`
+	Pieces       int                    `json:"pieces"`
+	Private      bool                   `json:"private"`
+	Trackers     []string               `json:"trackers"`
+	Comment      string                 `json:"comment,omitempty"`
+	CreatedBy    string                 `json:"created_by,omitempty"`
+	CreationDate *time.Time             `json:"creation_date,omitempty"`
+	Files        []MetadataFileResponse `json:"files"`
+	Torrent      *TorrentResponse       `json:"torrent,omitempty"` // состояние в движке, если передан add=true
+}
+
+// Upload разбирает .torrent файл из поля file multipart-формы или из тела запроса
+// (application/x-bittorrent). С ?add=true раздача сразу добавляется в движок,
+// ?paused=true добавляет её без начала загрузки.
+// Файл может передаваться дольше таймаута обычных запросов: маршрут подключается
+// без middleware/timeout и продлевает дедлайны сервера до uploadTimeout.
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(uploadTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		h.log.Warn("failed to extend read deadline", sl.Err(err))
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		h.log.Warn("failed to extend write deadline", sl.Err(err))
+	}
+
+	add, _ := strconv.ParseBool(r.URL.Query().Get("add"))
+	paused, _ := strconv.ParseBool(r.URL.Query().Get("paused"))
+
+	data, err := readTorrent(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			resp.WriteError(w, r, http.StatusRequestEntityTooLarge, resp.CodeBadRequest, "torrent file is too large")
+			return
+		}
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+
+	mi, err := torrentfile.Parse(data)
+	if err != nil {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+
+	ur := UploadResponse{
+		InfoHash:    mi.InfoHash,
+		Name:        mi.Name,
+		Magnet:      mi.Magnet(),
+		Size:        mi.Size,
+		PieceLength: mi.PieceLength,
+		Pieces:      mi.Pieces,
+		Private:     mi.Private,
+		Trackers:    mi.Trackers,
+		Comment:     mi.Comment,
+		CreatedBy:   mi.CreatedBy,
+		Files:       make([]MetadataFileResponse, 0, len(mi.Files)),
+	}
+	if ur.Trackers == nil {
+		ur.Trackers = []string{}
+	}
+	if !mi.CreationDate.IsZero() {
+		ur.CreationDate = &mi.CreationDate
+	}
+	for i, f := range mi.Files {
+		kind := engine.FileKind(f.Path)
+		ur.Files = append(ur.Files, MetadataFileResponse{
+			Index:    i,
+			Path:     f.Path,
+			Size:     f.Size,
+			Kind:     kind,
+			Playable: engine.Playable(kind),
+		})
+	}
+
+	if !add {
+		resp.JSON(w, http.StatusOK, ur)
+		return
+	}
+
+	status, err := h.engine.AddMetaInfo(data, paused)
+	if errors.Is(err, engine.ErrInvalidSource) {
+		resp.WriteError(w, r, http.StatusBadRequest, resp.CodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to add torrent file", sl.Err(err))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to add torrent")
+		return
+	}
+	tr := toResponse(status)
+	ur.Torrent = &tr
+
+	resp.JSON(w, http.StatusCreated, ur)
+}
+
+// readTorrent читает файл из multipart-формы или всё тело запроса
+func readTorrent(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// запас на заголовки частей формы
+	r.Body = http.MaxBytesReader(w, r.Body, torrentfile.MaxSize+64<<10)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, errors.New("request body is empty")
+		}
+		return data, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("form has no " + UploadField + " field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != UploadField {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(part)
+		part.Close()
+		return data, err
+	}
+}
@@ -0,0 +1,143 @@
+// http-server/middleware/auth/auth.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	authService "torrentServer/internal/services/auth"
+	"torrentServer/internal/storage"
+)
+
+const (
+	HeaderAPIKey = "X-API-Key"
+	ParamAPIKey  = "api_key"
+
+	APIPrefix = "/api/v1"
+)
+
+type Authenticator interface {
+	Authenticate(rawKey, path string) (storage.APIKey, error)
+}
+
+// SignedAuthenticator проверяет и ключи, и подписанные ссылки (services/auth.Service)
+type SignedAuthenticator interface {
+	Authenticator
+	AuthenticateSigned(params url.Values, path string) (storage.APIKey, error)
+}
+
+// Refunder возвращает в квоту ключа запрос, отклонённый после авторизации (services/auth.Service)
+type Refunder interface {
+	Refund(key storage.APIKey, path string) error
+}
+
+type ctxKey struct{}
+
+type pathKey struct{}
+
+// KeyFromContext возвращает ключ, с которым пришёл запрос
+func KeyFromContext(ctx context.Context) (storage.APIKey, bool) {
+	key, ok := ctx.Value(ctxKey{}).(storage.APIKey)
+	return key, ok
+}
+
+// WithKey сохраняет ключ в контексте. Используется и перехватчиками gRPC,
+// чтобы обработчики одинаково видели ключ в обоих API.
+func WithKey(ctx context.Context, key storage.APIKey) context.Context {
+	return context.WithValue(ctx, ctxKey{}, key)
+}
+
+// WithPath сохраняет путь без версии API, с которым ключ прошёл авторизацию:
+// по нему Refund определяет, расходовал ли запрос квоту
+func WithPath(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, pathKey{}, path)
+}
+
+// Refund возвращает в квоту ключа запрос из контекста, если его отклонили после авторизации
+func Refund(ctx context.Context, quota Refunder) error {
+	key, ok := KeyFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	path, _ := ctx.Value(pathKey{}).(string)
+	return quota.Refund(key, path)
+}
+
+func New(log *slog.Logger, auth SignedAuthenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		log.Info("auth middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rawKey := r.Header.Get(HeaderAPIKey)
+			if rawKey == "" {
+				rawKey = r.URL.Query().Get(ParamAPIKey)
+			}
+
+			// права ключа задаются без версии API: "/search" открывает и /search, и /api/v1/search
+			path := strings.TrimPrefix(r.URL.Path, APIPrefix)
+			if path == "" {
+				path = "/"
+			}
+
+			var key storage.APIKey
+			var err error
+			if query := r.URL.Query(); rawKey == "" && query.Has(authService.ParamSignature) {
+				key, err = auth.AuthenticateSigned(query, path)
+			} else {
+				key, err = auth.Authenticate(rawKey, path)
+			}
+			if err != nil {
+				entry := log.With(
+					slog.String("path", r.URL.Path),
+					slog.String("key_id", key.ID),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				switch {
+				case errors.Is(err, authService.ErrNoKey), errors.Is(err, authService.ErrInvalidKey),
+					errors.Is(err, authService.ErrBadSignature), errors.Is(err, authService.ErrExpiredURL):
+					entry.Info("unauthorized request", sl.Err(err))
+					resp.WriteError(w, r, http.StatusUnauthorized, resp.CodeUnauthorized, err.Error())
+				case errors.Is(err, authService.ErrForbidden):
+					entry.Info("forbidden request", sl.Err(err))
+					resp.WriteError(w, r, http.StatusForbidden, resp.CodeForbidden, err.Error())
+				case errors.Is(err, authService.ErrQuotaExceeded):
+					entry.Info("quota exceeded", sl.Err(err))
+					retryAfter := secondsUntilTomorrow()
+					w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+					resp.WriteErrorDetails(w, r, http.StatusTooManyRequests, resp.CodeQuotaExceeded, err.Error(),
+						map[string]interface{}{"daily_quota": key.DailyQuota, "retry_after_seconds": retryAfter})
+				default:
+					entry.Error("failed to authenticate request", sl.Err(err))
+					resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to authenticate request")
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPath(WithKey(r.Context(), key), path)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// secondsUntilTomorrow - через сколько секунд обнулится суточная квота (сутки считаются по UTC)
+func secondsUntilTomorrow() int {
+	now := time.Now().UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return int(tomorrow.Sub(now).Seconds()) + 1
+}
@@ -0,0 +1,62 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	mwMetrics "torrentServer/http_server/midleware/metrics"
+	"torrentServer/internal/lib/metrics"
+)
+
+func TestRouteLabels(t *testing.T) {
+	torrents := chi.NewRouter()
+	torrents.Get("/{hash}", func(w http.ResponseWriter, r *http.Request) {})
+	torrents.Delete("/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r := chi.NewRouter()
+	r.Use(mwMetrics.New())
+	r.Mount("/api/v1/metrics-test", torrents)
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/metrics-test/aaa"},
+		{http.MethodGet, "/api/v1/metrics-test/bbb"},
+		{http.MethodDelete, "/api/v1/metrics-test/ccc"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	// разные хэши попадают в одну метку шаблона маршрута
+	const route = "/api/v1/metrics-test/{hash}"
+	if n := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(route, http.MethodGet, "200")); n != 2 {
+		t.Errorf("GET %s 200 = %v, want 2", route, n)
+	}
+	if n := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(route, http.MethodDelete, "404")); n != 1 {
+		t.Errorf("DELETE %s 404 = %v, want 1", route, n)
+	}
+	if n := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/api/v1/metrics-test/aaa", http.MethodGet, "200")); n != 0 {
+		t.Errorf("request path used as a label: %v", n)
+	}
+	if n := testutil.CollectAndCount(metrics.HTTPRequestDuration); n < 2 {
+		t.Errorf("duration series = %d, want at least 2", n)
+	}
+}
+
+func TestUnmatchedRoute(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(mwMetrics.New())
+	r.Get("/known", func(w http.ResponseWriter, r *http.Request) {})
+
+	before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404"))
+	for _, path := range []string{"/scan/1", "/scan/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if n := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")) - before; n != 2 {
+		t.Errorf("unmatched 404 = %v, want 2", n)
+	}
+}
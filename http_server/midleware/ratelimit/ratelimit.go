@@ -0,0 +1,120 @@
+// http-server/middleware/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/middleware"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	"torrentServer/internal/config"
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/ratelimit"
+)
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit config.Limit) (ratelimit.Result, error)
+}
+
+type bucket struct {
+	key   string
+	limit config.Limit
+}
+
+// PerIP ограничивает частоту запросов по IP клиента. Ставится перед middleware авторизации,
+// чтобы запросы с неверными ключами тоже расходовали лимит и ключи нельзя было перебирать.
+// Если Redis недоступен, запросы пропускаются.
+func PerIP(log *slog.Logger, limiter Limiter, cfg config.RateLimit) func(next http.Handler) http.Handler {
+	return newMiddleware(log, limiter, "ip", func(r *http.Request) (bucket, bool) {
+		return bucket{"ip:" + clientIP(r), cfg.PerIP}, true
+	}, nil)
+}
+
+// PerKey ограничивает частоту запросов по API-ключу. Ставится после middleware авторизации,
+// запросы без ключа (авторизация выключена) этим лимитом не ограничиваются.
+// Авторизация уже учла запрос в суточной квоте ключа, отклонённый запрос возвращается в неё через quota.
+func PerKey(log *slog.Logger, limiter Limiter, cfg config.RateLimit, quota mwAuth.Refunder) func(next http.Handler) http.Handler {
+	return newMiddleware(log, limiter, "key", func(r *http.Request) (bucket, bool) {
+		key, ok := mwAuth.KeyFromContext(r.Context())
+		return bucket{"key:" + key.ID, cfg.PerKey}, ok
+	}, func(ctx context.Context) error {
+		return mwAuth.Refund(ctx, quota)
+	})
+}
+
+// Stream ограничивает частоту запросов к потокам по IP клиента отдельным счётчиком вместо PerIP и PerKey:
+// при перемотке плееры шлют десятки Range-запросов, которые не должны съедать лимит API.
+// Ставится перед middleware авторизации, как и PerIP.
+func Stream(log *slog.Logger, limiter Limiter, cfg config.RateLimit) func(next http.Handler) http.Handler {
+	return newMiddleware(log, limiter, "stream", func(r *http.Request) (bucket, bool) {
+		return bucket{"stream:ip:" + clientIP(r), cfg.Stream}, true
+	}, nil)
+}
+
+// rejected вызывается для отклонённого запроса, nil - ничего делать не нужно
+func newMiddleware(log *slog.Logger, limiter Limiter, kind string, bucketOf func(r *http.Request) (bucket, bool), rejected func(ctx context.Context) error) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("limit", kind),
+		)
+
+		log.Info("rate limit middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			c, ok := bucketOf(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := limiter.Allow(ctx, c.key, c.limit)
+			if err != nil {
+				log.Error("failed to check rate limit", sl.Err(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if res.Limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			}
+			if !res.Allowed {
+				log.Info("rate limit exceeded",
+					slog.String("bucket", c.key),
+					slog.String("request_id", middleware.GetReqID(ctx)),
+				)
+				if rejected != nil {
+					if err := rejected(ctx); err != nil {
+						log.Error("failed to refund rejected request", sl.Err(err))
+					}
+				}
+				retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				resp.WriteErrorDetails(w, r, http.StatusTooManyRequests, resp.CodeRateLimited, "rate limit exceeded",
+					map[string]interface{}{"retry_after_seconds": retryAfter})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// clientIP - адрес клиента без порта (RemoteAddr уже исправлен middleware/realip для доверенных прокси)
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,112 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mwAuth "torrentServer/http_server/midleware/auth"
+	mwRateLimit "torrentServer/http_server/midleware/ratelimit"
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/ratelimit"
+	"torrentServer/internal/storage"
+)
+
+// counter разрешает limit.Burst запросов на счётчик
+type counter struct {
+	used map[string]int
+}
+
+func (c *counter) Allow(_ context.Context, key string, limit config.Limit) (ratelimit.Result, error) {
+	c.used[key]++
+	if c.used[key] > limit.Burst {
+		return ratelimit.Result{Limit: limit.Burst, RetryAfter: time.Second}, nil
+	}
+	return ratelimit.Result{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst - c.used[key]}, nil
+}
+
+// auth пропускает только ключ "good"
+func auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(mwAuth.HeaderAPIKey) != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ctx := mwAuth.WithPath(mwAuth.WithKey(r.Context(), storage.APIKey{ID: "k1"}), "/search")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// refunds запоминает запросы, возвращённые в квоту
+type refunds []string
+
+func (r *refunds) Refund(key storage.APIKey, path string) error {
+	*r = append(*r, key.ID+" "+path)
+	return nil
+}
+
+func TestInvalidKeysSpendIPLimit(t *testing.T) {
+	limiter := &counter{used: map[string]int{}}
+	cfg := config.RateLimit{
+		PerIP:  config.Limit{Burst: 3},
+		PerKey: config.Limit{Burst: 2},
+	}
+	log := slogdiscard.NewDiscardLogger()
+	h := mwRateLimit.PerIP(log, limiter, cfg)(auth(mwRateLimit.PerKey(log, limiter, cfg, &refunds{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search", nil)
+		req.RemoteAddr = "203.0.113.7:5555"
+		req.Header.Set(mwAuth.HeaderAPIKey, key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// перебор ключей расходует лимит по IP
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusOK, http.StatusTooManyRequests} {
+		key := "guess"
+		if i == 2 {
+			key = "good"
+		}
+		if got := do(key); got != want {
+			t.Errorf("request %d with key %q: status = %d, want %d", i, key, got, want)
+		}
+	}
+	if limiter.used["key:k1"] != 1 {
+		t.Errorf("key bucket used %d times, want 1", limiter.used["key:k1"])
+	}
+}
+
+func TestPerKeyLimit(t *testing.T) {
+	limiter := &counter{used: map[string]int{}}
+	cfg := config.RateLimit{
+		PerIP:  config.Limit{Burst: 100},
+		PerKey: config.Limit{Burst: 1},
+	}
+	log := slogdiscard.NewDiscardLogger()
+	quota := &refunds{}
+	h := mwRateLimit.PerIP(log, limiter, cfg)(auth(mwRateLimit.PerKey(log, limiter, cfg, quota)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search", nil)
+		req.Header.Set(mwAuth.HeaderAPIKey, "good")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+		if want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+		}
+	}
+	// отклонённый лимитом запрос не расходует суточную квоту
+	if len(*quota) != 1 || (*quota)[0] != "k1 /search" {
+		t.Errorf("refunds = %v, want one for k1 /search", *quota)
+	}
+}
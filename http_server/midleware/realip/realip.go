@@ -0,0 +1,104 @@
+// http-server/middleware/realip/realip.go
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// New заменяет RemoteAddr адресом клиента из X-Forwarded-For или X-Real-IP, но только если запрос
+// пришёл от доверенного прокси (адрес или подсеть из trusted). Остальным клиентам заголовки
+// не верятся: иначе каждый запрос с новым заголовком получал бы свой лимит по IP.
+func New(trusted []string) (func(next http.Handler) http.Handler, error) {
+	prefixes := make([]netip.Prefix, 0, len(trusted))
+	for _, s := range trusted {
+		p, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		prefixes = append(prefixes, p)
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, p := range prefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(prefixes) > 0 && fromTrusted(r, isTrusted) {
+				r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, true))
+				if ip, ok := clientIP(r, isTrusted); ok {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}, nil
+}
+
+// FromTrustedProxy сообщает, что запрос пришёл от доверенного прокси, то есть его заголовкам
+// X-Forwarded-* можно верить. Без middleware New всегда false.
+func FromTrustedProxy(r *http.Request) bool {
+	trusted, _ := r.Context().Value(ctxKey{}).(bool)
+	return trusted
+}
+
+// fromTrusted проверяет, что RemoteAddr - доверенный прокси
+func fromTrusted(r *http.Request, isTrusted func(netip.Addr) bool) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	return err == nil && isTrusted(remote)
+}
+
+// clientIP - адрес клиента из заголовков доверенного прокси. X-Forwarded-For читается справа
+// налево: первый адрес, не принадлежащий доверенным прокси, добавил последний доверенный.
+func clientIP(r *http.Request, isTrusted func(netip.Addr) bool) (string, bool) {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return "", false
+			}
+			if !isTrusted(addr) || i == 0 {
+				return addr.Unmap().String(), true
+			}
+		}
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String(), true
+	}
+
+	return "", false
+}
+
+// parsePrefix принимает подсеть "10.0.0.0/8" или отдельный адрес
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,61 @@
+package realip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mwRealIP "torrentServer/http_server/midleware/realip"
+)
+
+func TestRealIP(t *testing.T) {
+	mw, err := mwRealIP.New([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var got string
+	var trusted bool
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+		trusted = mwRealIP.FromTrustedProxy(r)
+	}))
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+		trusted bool
+	}{
+		{"direct client spoofs header", "203.0.113.7:4000", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.7:4000", false},
+		{"direct client spoofs real ip", "203.0.113.7:4000", map[string]string{"X-Real-IP": "1.2.3.4"}, "203.0.113.7:4000", false},
+		{"trusted proxy", "10.0.0.2:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1", true},
+		// клиент дописал свой адрес, прокси добавил настоящий справа
+		{"spoofed chain", "10.0.0.2:4000", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"}, "198.51.100.1", true},
+		{"chain of trusted proxies", "10.0.0.2:4000", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.3"}, "198.51.100.1", true},
+		{"x-real-ip from proxy", "[::1]:4000", map[string]string{"X-Real-IP": "2001:db8::1"}, "2001:db8::1", true},
+		{"proxy without headers", "10.0.0.2:4000", nil, "10.0.0.2:4000", true},
+		{"garbage header", "10.0.0.2:4000", map[string]string{"X-Forwarded-For": "not-an-ip"}, "10.0.0.2:4000", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%s: RemoteAddr = %q, want %q", tt.name, got, tt.want)
+		}
+		if trusted != tt.trusted {
+			t.Errorf("%s: FromTrustedProxy() = %v, want %v", tt.name, trusted, tt.trusted)
+		}
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	if _, err := mwRealIP.New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New() with invalid subnet: want error")
+	}
+}
@@ -0,0 +1,52 @@
+// http-server/middleware/recoverer/recoverer.go
+package recoverer
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/middleware"
+
+	resp "torrentServer/internal/lib/api/response"
+)
+
+// New перехватывает панику в обработчике: приложение не падает, паника со стеком пишется в лог,
+// а клиент получает 500 в едином формате ошибок, если ответ ещё не начат
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/recoverer"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				// так net/http прерывает ответ, сервер обработает её сам
+				if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(p)
+				}
+
+				log.Error("panic in http handler",
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.Any("panic", p),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if ww.Status() == 0 && r.Header.Get("Connection") != "Upgrade" {
+					resp.WriteError(ww, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
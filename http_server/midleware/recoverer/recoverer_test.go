@@ -0,0 +1,54 @@
+package recoverer_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+
+	mwRecoverer "torrentServer/http_server/midleware/recoverer"
+	resp "torrentServer/internal/lib/api/response"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRecoverer(t *testing.T) {
+	h := middleware.RequestID(mwRecoverer.New(slogdiscard.NewDiscardLogger())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	var body resp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if body.Error.Code != resp.CodeInternal || body.Error.RequestID != "req-42" {
+		t.Errorf("error = %+v, want internal_error with request_id", body.Error)
+	}
+}
+
+func TestRecovererAfterResponseStarted(t *testing.T) {
+	h := mwRecoverer.New(slogdiscard.NewDiscardLogger())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("partial"))
+			panic("boom")
+		}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// начатый ответ не дописывается ошибкой
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want untouched 200 partial", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,33 @@
+// http-server/middleware/timeout/timeout.go
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+
+	resp "torrentServer/internal/lib/api/response"
+)
+
+// New отменяет контекст запроса через timeout, как middleware.Timeout из chi. Если обработчик
+// вернулся по истёкшему контексту, не начав ответ, клиент получает 504 в едином формате ошибок.
+func New(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if ww.Status() == 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				resp.WriteError(ww, r, http.StatusGatewayTimeout, resp.CodeTimeout, "request timed out")
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
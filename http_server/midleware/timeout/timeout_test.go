@@ -0,0 +1,52 @@
+package timeout_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mwTimeout "torrentServer/http_server/midleware/timeout"
+	resp "torrentServer/internal/lib/api/response"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		code    string
+	}{
+		{"handler gave up", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}, http.StatusGatewayTimeout, resp.CodeTimeout},
+		// обработчик сам ответил ошибкой после таймаута, второй ответ не пишется
+		{"handler answered", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			resp.WriteError(w, r, http.StatusBadGateway, resp.CodeSearchFailed, "jackett timed out")
+		}, http.StatusBadGateway, resp.CodeSearchFailed},
+		{"in time", func(w http.ResponseWriter, r *http.Request) {
+			resp.JSON(w, http.StatusOK, resp.ErrorResponse{})
+		}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mwTimeout.New(20*time.Millisecond)(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		var body resp.ErrorResponse
+		dec := json.NewDecoder(rec.Body)
+		if err := dec.Decode(&body); err != nil {
+			t.Fatalf("%s: decode error = %v", tt.name, err)
+		}
+		if body.Error.Code != tt.code {
+			t.Errorf("%s: code = %q, want %q", tt.name, body.Error.Code, tt.code)
+		}
+		if dec.More() {
+			t.Errorf("%s: second response written", tt.name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	mwTracing "torrentServer/http_server/midleware/tracing"
+)
+
+func TestServerSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(mwTracing.New())
+	r.Get("/torrents/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/torrents/abc", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	req.Header.Set(middleware.RequestIDHeader, "req-7")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "HTTP GET /torrents/{hash}" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q %v, want server span named by route", span.Name(), span.SpanKind())
+	}
+	// спан продолжает трейс клиента и доступен обработчику через контекст
+	if span.Parent().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" || !span.Parent().IsRemote() {
+		t.Errorf("parent = %v, want remote parent from traceparent", span.Parent())
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("handler span = %v, want %v", handlerSpan.SpanID(), span.SpanContext().SpanID())
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"http.route":                attribute.StringValue("/torrents/{hash}"),
+		"url.path":                  attribute.StringValue("/torrents/abc"),
+		"request_id":                attribute.StringValue("req-7"),
+		"http.response.status_code": attribute.IntValue(http.StatusBadGateway),
+	}
+	for _, kv := range span.Attributes() {
+		if v, ok := want[kv.Key]; ok {
+			if v != kv.Value {
+				t.Errorf("%s = %v, want %v", kv.Key, kv.Value.Emit(), v.Emit())
+			}
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing attributes: %v", want)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for 5xx", span.Status())
+	}
+}
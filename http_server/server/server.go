@@ -0,0 +1,55 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	mwLogger "torrentServer/http_server/midleware/logger"
+	mwMetrics "torrentServer/http_server/midleware/metrics"
+	mwRealIP "torrentServer/http_server/midleware/realip"
+	mwRecoverer "torrentServer/http_server/midleware/recoverer"
+	mwTracing "torrentServer/http_server/midleware/tracing"
+	"torrentServer/internal/config"
+	resp "torrentServer/internal/lib/api/response"
+)
+
+// NewRouter создаёт роутер с общим для всех маршрутов набором middleware.
+// Группы маршрутов монтируются в него через Mount/Group.
+func NewRouter(log *slog.Logger, trustedProxies []string) (*chi.Mux, error) {
+	realIP, err := mwRealIP.New(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID) // добавляет request_id к каждому запросу
+	router.Use(realIP)               // адрес клиента из заголовков только от доверенных прокси
+	router.Use(mwLogger.New(log))
+	router.Use(mwRecoverer.New(log)) // если внутри обработчика случится паника, приложение не упадёт
+	router.Use(mwMetrics.New())
+	router.Use(mwTracing.New())
+
+	router.NotFound(resp.NotFound)
+	router.MethodNotAllowed(resp.MethodNotAllowed)
+
+	router.Handle("/metrics", promhttp.Handler())
+
+	return router, nil
+}
+
+// New создаёт http-сервер с адресом и таймаутами из конфига
+func New(cfg config.HTTPServer, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Timeout,
+		ReadTimeout:       cfg.Timeout,
+		WriteTimeout:      cfg.Timeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
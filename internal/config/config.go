@@ -13,12 +13,103 @@ type Config struct {
 	Env         string `yaml:"env" env-default:"development"`
 	StoragePath string `yaml:"storage_path" env-required:"ture"`
 	HTTPServer  `yaml:"http_server"`
+	GRPCServer  `yaml:"grpc_server"`
+	Redis       `yaml:"redis"`
+	CacheWarmer `yaml:"cache_warmer"`
+	Auth        `yaml:"auth"`
+	RateLimit   `yaml:"rate_limit"`
+	Tracing     `yaml:"tracing"`
+	Engine      `yaml:"engine"`
+	Quota       `yaml:"storage_quota"`
+	DLNA        `yaml:"dlna"`
+	Seeding     `yaml:"seeding"`
+	Bandwidth   `yaml:"bandwidth"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"0.0.0.0:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"5s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// сколько поиск ждёт Jackett; меньше timeout, чтобы успеть ответить из локального индекса
+	SearchTimeout time.Duration `yaml:"search_timeout" env-default:"3s"`
+	// сколько ждать завершения обрабатываемых запросов при остановке сервера
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+	// адреса и подсети прокси, которым верятся X-Forwarded-For и X-Real-IP; пусто - адрес клиента берётся из соединения
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// GRPCServer - gRPC API поиска на отдельном порту, ключи и лимиты общие с HTTP
+type GRPCServer struct {
+	Enabled    bool   `yaml:"enabled" env-default:"false"`
+	Address    string `yaml:"address" env-default:"0.0.0.0:9090"`
+	Reflection bool   `yaml:"reflection" env-default:"false"` // описание сервисов для grpcurl и подобных клиентов, включать для отладки
+}
+
+type Redis struct {
+	Address  string        `yaml:"address" env:"REDIS_ADDR" env-default:"localhost:6379"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"1h"`
+}
+
+// CacheWarmer - фоновое обновление кэша для популярных запросов
+type CacheWarmer struct {
+	Enabled       bool          `yaml:"enabled" env-default:"false"`
+	Interval      time.Duration `yaml:"interval" env-default:"30m"`
+	TopN          int           `yaml:"top_n" env-default:"10"`            // сколько запросов из истории поиска обновлять
+	HistoryWindow time.Duration `yaml:"history_window" env-default:"168h"` // за какой период учитывать историю
+	Concurrency   int           `yaml:"concurrency" env-default:"2"`       // сколько запросов к Jackett выполнять одновременно
+	Queries       []WarmQuery   `yaml:"queries"`                           // запросы, которые обновляются всегда
+}
+
+type WarmQuery struct {
+	Query      string `yaml:"query"`
+	Categories []uint `yaml:"categories"`
+	SafeOnly   int    `yaml:"safe_only"`
+}
+
+// Auth - доступ к API по ключам. Ключи выпускаются через /admin/keys
+// или задаются в конфиге (например, ключ администратора).
+type Auth struct {
+	Enabled bool     `yaml:"enabled" env-default:"false"`
+	Keys    []APIKey `yaml:"keys"`
+	// секрет для подписи ссылок в плейлистах; если пуст, генерируется при запуске
+	// и выданные ссылки перестают работать после перезапуска
+	SigningKey   string        `yaml:"signing_key" env:"AUTH_SIGNING_KEY"`
+	SignedURLTTL time.Duration `yaml:"signed_url_ttl" env-default:"24h"` // сколько действует подписанная ссылка
+}
+
+type APIKey struct {
+	Name       string   `yaml:"name"`
+	Key        string   `yaml:"key"`
+	Endpoints  []string `yaml:"endpoints"`   // префиксы путей, "*" - все
+	DailyQuota int      `yaml:"daily_quota"` // 0 - без ограничений
+	Policy     string   `yaml:"policy"`      // full или safe
+}
+
+// RateLimit - ограничение частоты запросов, счётчики хранятся в Redis
+// и общие для всех реплик torrentServer
+type RateLimit struct {
+	Enabled bool  `yaml:"enabled" env-default:"false"`
+	PerIP   Limit `yaml:"per_ip"`
+	PerKey  Limit `yaml:"per_key"`
+	// отдельный лимит по IP для /stream вместо per_ip и per_key: плееры шлют десятки Range-запросов при перемотке
+	Stream Limit `yaml:"stream"`
+}
+
+// Limit - token bucket: Requests запросов за Per, но не больше Burst подряд
+type Limit struct {
+	Requests int           `yaml:"requests"` // 0 - без ограничений
+	Per      time.Duration `yaml:"per" env-default:"1m"`
+	Burst    int           `yaml:"burst"`
+}
+
+// Tracing - экспорт спанов OpenTelemetry
+type Tracing struct {
+	Enabled     bool    `yaml:"enabled" env-default:"false"`
+	Exporter    string  `yaml:"exporter" env-default:"stdout"` // otlp или stdout
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env-default:"true"`
+	ServiceName string  `yaml:"service_name" env-default:"torrent-server"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
 }
 
 func MustLoad() *Config {
@@ -37,5 +128,94 @@ func MustLoad() *Config {
 		log.Fatalf("Error reading config file: %s", err)
 	}
 
+	if cfg.HTTPServer.SearchTimeout <= 0 || cfg.HTTPServer.SearchTimeout >= cfg.HTTPServer.Timeout {
+		log.Fatalf("http_server.search_timeout must be positive and less than http_server.timeout, got %s", cfg.HTTPServer.SearchTimeout)
+	}
+
 	return &cfg
 }
+
+// Engine - встроенный BitTorrent-клиент для раздач из результатов поиска
+type Engine struct {
+	Enabled    bool   `yaml:"enabled" env-default:"false"`
+	DataDir    string `yaml:"data_dir"` // по умолчанию каталог torrents рядом с storage_path
+	ListenPort int    `yaml:"listen_port" env-default:"42069"`
+	NoDHT      bool   `yaml:"no_dht" env-default:"false"`
+	Seed       bool   `yaml:"seed" env-default:"true"` // продолжать раздавать после завершения загрузки
+	// сколько ждать метаданные раздачи от пиров, прежде чем отдать клиенту ошибку
+	MetadataTimeout time.Duration `yaml:"metadata_timeout" env-default:"30s"`
+	// окно упреждающей загрузки от позиции каждого потока, куски в нём качаются по порядку
+	ReadaheadMB int `yaml:"readahead_mb" env-default:"32"`
+	// сколько в начале и конце файла загружать при открытии потока (moov в MP4, cues в MKV)
+	HeadTailMB int `yaml:"head_tail_mb" env-default:"4"`
+	// как часто отправлять подписчикам событий скорости и прогресс раздач
+	StatsInterval time.Duration `yaml:"stats_interval" env-default:"1s"`
+}
+
+// Quota - ограничение места под данные раздач движка. При превышении удаляются
+// скачанные раздачи, которые дольше всех не смотрели; раздачи с открытыми потоками не трогаются.
+type Quota struct {
+	Enabled   bool          `yaml:"enabled" env-default:"false"`
+	MaxSizeMB int64         `yaml:"max_size_mb" env-default:"51200"`
+	Interval  time.Duration `yaml:"interval" env-default:"1m"` // как часто проверять занятое место
+}
+
+// Seeding - политика раздачи скачанных раздач: раздача продолжается, пока не выполнены
+// требования трекера из выдачи Jackett (минимальный рейтинг и время раздачи), затем
+// ставится на паузу или удаляется. Раздачи, которые сейчас смотрят, не трогаются.
+type Seeding struct {
+	Enabled  bool          `yaml:"enabled" env-default:"false"`
+	Action   string        `yaml:"action" env-default:"pause"` // pause, remove или delete (вместе с данными)
+	Interval time.Duration `yaml:"interval" env-default:"1m"`  // как часто проверять раздачи
+	// требования для раздач, для которых трекер их не указал; если оба 0, такие раздачи не останавливаются
+	DefaultRatio    float64       `yaml:"default_ratio"`
+	DefaultSeedTime time.Duration `yaml:"default_seed_time"`
+	// глобальные ограничения: при достижении любого раздача останавливается,
+	// даже если требования трекера ещё не выполнены; 0 - без ограничения
+	MaxRatio    float64       `yaml:"max_ratio"`
+	MaxSeedTime time.Duration `yaml:"max_seed_time"`
+}
+
+// DLNA - медиасервер UPnP для телевизоров в локальной сети: объявляет себя по SSDP,
+// показывает активные раздачи и их видео. Работает на отдельном порту без ключей API,
+// поэтому порт не должен быть доступен из интернета.
+type DLNA struct {
+	Enabled      bool   `yaml:"enabled" env-default:"false"`
+	Address      string `yaml:"address" env-default:"0.0.0.0:8200"` // описание устройства, SOAP и потоки
+	FriendlyName string `yaml:"friendly_name" env-default:"torrentServer"`
+	// адрес, который получают телевизоры в LOCATION; по умолчанию адрес интерфейса,
+	// через который виден телевизор (в Docker нужно указать адрес хоста)
+	AdvertiseHost string        `yaml:"advertise_host"`
+	SSDPAddress   string        `yaml:"ssdp_address" env-default:"239.255.255.250:1900"`
+	Interface     string        `yaml:"interface"`                 // интерфейс для multicast, по умолчанию выбирает система
+	MaxAge        time.Duration `yaml:"max_age" env-default:"30m"` // срок объявления, NOTIFY повторяется каждые max_age/3
+}
+
+// Bandwidth - ограничения скорости движка в КБ/с (1 КБ = 1024 байта), 0 - без ограничения.
+// Меняются на ходу через /admin/bandwidth до перезапуска сервера, если включена авторизация.
+type Bandwidth struct {
+	DownloadKBps int `yaml:"download_kbps"`
+	UploadKBps   int `yaml:"upload_kbps"`
+	// на каждое соединение с пиром, действует и на уже открытые соединения
+	PeerDownloadKBps int `yaml:"peer_download_kbps"`
+	PeerUploadKBps   int `yaml:"peer_upload_kbps"`
+	// средняя скорость каждой раздачи, а не строгое ограничение: раз в stats_interval раздача,
+	// превысившая его, останавливается, пока среднее не опустится ниже. Раздачи с открытыми
+	// потоками по загрузке не ограничиваются.
+	TorrentDownloadKBps int `yaml:"torrent_download_kbps"`
+	TorrentUploadKBps   int `yaml:"torrent_upload_kbps"`
+	// загрузка каждой раздачи без потоков, пока открыт хотя бы один поток, чтобы полоса доставалась потокам
+	BackgroundDownloadKBps int                 `yaml:"background_download_kbps"`
+	Schedules              []BandwidthSchedule `yaml:"schedules"` // первое подходящее расписание заменяет download_kbps и upload_kbps
+}
+
+// BandwidthSchedule - другие ограничения клиента в заданные часы по местному времени сервера
+type BandwidthSchedule struct {
+	Name string   `yaml:"name"`
+	Days []string `yaml:"days"` // mon, tue, ..., sun; пусто - каждый день
+	// "09:00" - "18:00"; если to раньше from, интервал заканчивается на следующий день, если равны - весь день
+	From         string `yaml:"from"`
+	To           string `yaml:"to"`
+	DownloadKBps int    `yaml:"download_kbps"`
+	UploadKBps   int    `yaml:"upload_kbps"`
+}
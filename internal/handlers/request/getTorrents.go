@@ -3,9 +3,15 @@ package getTorrents
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
+	"time"
 	jackett "torrentServer/internal/services/jackett"
+	"torrentServer/internal/storage"
 )
 
 var (
@@ -13,6 +19,37 @@ var (
 	once            sync.Once
 )
 
+// ResultSaver сохраняет все раздачи, пришедшие от Jackett, в локальное хранилище
+type ResultSaver interface {
+	SaveTorrents(torrents []storage.Torrent) error
+}
+
+// LocalIndex ищет по раздачам, сохранённым в локальном хранилище
+type LocalIndex interface {
+	SearchTorrents(query string, filter storage.TorrentFilter, limit int) ([]storage.Torrent, error)
+}
+
+// Сколько результатов максимум отдаёт локальный индекс
+const localSearchLimit = 500
+
+// safeTracker - при safeOnly=1 локальный индекс отдаёт только раздачи с этого трекера, как и Jackett
+const safeTracker = "Internet Archive"
+
+var (
+	resultSaver ResultSaver
+	localIndex  LocalIndex
+)
+
+var ErrLocalIndexDisabled = errors.New("local index is not configured")
+
+func SetResultSaver(s ResultSaver) {
+	resultSaver = s
+}
+
+func SetLocalIndex(i LocalIndex) {
+	localIndex = i
+}
+
 var (
 	apiURL = os.Getenv("JACKETT_API_URL")
 	apiKey = os.Getenv("JACKETT_API_KEY")
@@ -28,8 +65,7 @@ func GetJackettInstance() *jackett.Jackett {
 	return jackettInstance
 }
 
-func RequestAll(query string, categories []uint, safeOnly bool) (string, error) {
-	ctx := context.Background()
+func RequestAll(ctx context.Context, query string, categories []uint, safeOnly bool) (string, error) {
 	j := GetJackettInstance()
 	resp, err := j.Fetch(ctx, &jackett.FetchRequest{
 		Categories: categories,
@@ -38,6 +74,8 @@ func RequestAll(query string, categories []uint, safeOnly bool) (string, error)
 	if err != nil {
 		return "", err
 	}
+	j.ResolveLinks(ctx, resp.Results)
+	saveResults(resp.Results)
 
 	jsonBytes, err := json.MarshalIndent(resp.Results, "", "  ")
 	if err != nil {
@@ -47,21 +85,116 @@ func RequestAll(query string, categories []uint, safeOnly bool) (string, error)
 	return string(jsonBytes), nil
 }
 
-func RequestSimple(query string, categories []uint, safeOnly int) (string, error) {
-	ctx := context.Background()
+// RequestSimple возвращает выдачу Jackett и false, если не все ссылки на .torrent успели разрешиться
+func RequestSimple(ctx context.Context, query string, categories []uint, safeOnly int) (string, bool, error) {
 	j := GetJackettInstance()
 	resp, err := j.Fetch(ctx, &jackett.FetchRequest{
 		Categories: categories,
 		Query:      query,
 	})
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
+	complete := j.ResolveLinks(ctx, resp.Results)
+	saveResults(resp.Results)
 
 	simpleRes, err := j.FilterResults(resp.Results, safeOnly)
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(simpleRes), complete, nil
+}
+
+// RequestLocal ищет по локальному индексу и возвращает результаты в том же формате, что и RequestSimple
+func RequestLocal(query string, categories []uint, safeOnly int) (string, error) {
+	if localIndex == nil {
+		return "", ErrLocalIndexDisabled
+	}
+
+	// фильтры применяются в запросе к индексу, до ограничения localSearchLimit
+	filter := storage.TorrentFilter{Categories: categories}
+	if safeOnly == 1 {
+		filter.Tracker = safeTracker
+	}
+	torrents, err := localIndex.SearchTorrents(query, filter, localSearchLimit)
 	if err != nil {
 		return "", err
 	}
 
-	return string(simpleRes), nil
+	simpleResults := make([]jackett.SimpleResult, 0, len(torrents))
+	for _, t := range torrents {
+		tracker := filter.Tracker
+		if tracker == "" && len(t.Trackers) > 0 {
+			tracker = t.Trackers[0]
+		}
+		simpleResults = append(simpleResults, jackett.SimpleResult{
+			Title:       t.Title,
+			Category:    t.Category,
+			MagnetUri:   t.MagnetUri,
+			Seeders:     t.Seeders,
+			Size:        t.Size,
+			Peers:       t.Peers,
+			Description: t.Description,
+			Tracker:     tracker,
+		})
+	}
+
+	jsonBytes, err := json.Marshal(simpleResults)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// saveResults записывает выдачу в хранилище, раздачи без info-hash пропускаются
+func saveResults(results []jackett.Result) {
+	if resultSaver == nil {
+		return
+	}
+
+	now := time.Now()
+	torrents := make([]storage.Torrent, 0, len(results))
+	for _, r := range results {
+		infoHash := r.InfoHash
+		if infoHash == "" {
+			infoHash = infoHashFromMagnet(r.MagnetUri)
+		}
+		if infoHash == "" {
+			continue
+		}
+		torrents = append(torrents, storage.Torrent{
+			InfoHash:    strings.ToLower(infoHash),
+			Title:       r.Title,
+			Description: r.Description,
+			Category:    r.Category,
+			MagnetUri:   r.MagnetUri,
+			Size:        r.Size,
+			Trackers:    []string{r.Tracker},
+			Seeders:     r.Seeders,
+			Peers:       r.Peers,
+			LastSeen:    now,
+			// Jackett отдаёт минимальное время раздачи в секундах
+			MinimumRatio:    float64(r.MinimumRatio),
+			MinimumSeedTime: time.Duration(r.MinimumSeedTime) * time.Second,
+		})
+	}
+
+	if err := resultSaver.SaveTorrents(torrents); err != nil {
+		log.Printf("Storage save error: %v", err)
+	}
+}
+
+func infoHashFromMagnet(magnet string) string {
+	u, err := url.Parse(magnet)
+	if err != nil || u.Scheme != "magnet" {
+		return ""
+	}
+	for _, xt := range u.Query()["xt"] {
+		if hash, ok := strings.CutPrefix(xt, "urn:btih:"); ok {
+			return hash
+		}
+	}
+	return ""
 }
@@ -11,7 +11,7 @@ import (
 
 type JackettClient interface {
 	Fetch(ctx context.Context, req *jackett.FetchRequest) (*jackett.FetchResponse, error)
-	FilterResults(results []jackett.Result) ([]byte, error)
+	FilterResults(results []jackett.Result, safeOnly int) ([]byte, error)
 }
 
 func GetJackettInstance() *jackett.Jackett {
@@ -32,7 +32,7 @@ func (m *mockJackettClient) Fetch(ctx context.Context, req *jackett.FetchRequest
 	return m.fetchFunc(ctx, req)
 }
 
-func (m *mockJackettClient) FilterResults(results []jackett.Result) ([]byte, error) {
+func (m *mockJackettClient) FilterResults(results []jackett.Result, safeOnly int) ([]byte, error) {
 	return m.filterResultsFunc(results)
 }
 
@@ -48,8 +48,7 @@ func getClient() JackettClient {
 }
 
 // Обновлённые функции для теста (пример для RequestSimple)
-func RequestSimple(query string, categories []uint) (string, error) {
-	ctx := context.Background()
+func RequestSimple(ctx context.Context, query string, categories []uint) (string, error) {
 	j := getClient()
 	resp, err := j.Fetch(ctx, &jackett.FetchRequest{
 		Categories: categories,
@@ -59,7 +58,7 @@ func RequestSimple(query string, categories []uint) (string, error) {
 		return "", err
 	}
 
-	simpleRes, err := j.FilterResults(resp.Results)
+	simpleRes, err := j.FilterResults(resp.Results, 0)
 	if err != nil {
 		return "", err
 	}
@@ -67,8 +66,7 @@ func RequestSimple(query string, categories []uint) (string, error) {
 	return string(simpleRes), nil
 }
 
-func RequestAll(query string, categories []uint) (string, error) {
-	ctx := context.Background()
+func RequestAll(ctx context.Context, query string, categories []uint) (string, error) {
 	j := getClient()
 	resp, err := j.Fetch(ctx, &jackett.FetchRequest{
 		Categories: categories,
@@ -104,7 +102,7 @@ func TestRequestSimple_Success(t *testing.T) {
 	}
 	defer func() { testJackettClient = nil }()
 
-	res, err := RequestSimple("query", []uint{1, 2})
+	res, err := RequestSimple(context.Background(), "query", []uint{1, 2})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -124,7 +122,7 @@ func TestRequestSimple_FetchError(t *testing.T) {
 	}
 	defer func() { testJackettClient = nil }()
 
-	_, err := RequestSimple("query", []uint{1})
+	_, err := RequestSimple(context.Background(), "query", []uint{1})
 	if err == nil || err.Error() != "fetch error" {
 		t.Errorf("expected fetch error, got %v", err)
 	}
@@ -143,7 +141,7 @@ func TestRequestSimple_FilterResultsError(t *testing.T) {
 	}
 	defer func() { testJackettClient = nil }()
 
-	_, err := RequestSimple("query", []uint{1})
+	_, err := RequestSimple(context.Background(), "query", []uint{1})
 	if err == nil || err.Error() != "filter error" {
 		t.Errorf("expected filter error, got %v", err)
 	}
@@ -163,7 +161,7 @@ func TestRequestAll_Success(t *testing.T) {
 	}
 	defer func() { testJackettClient = nil }()
 
-	res, err := RequestAll("query", []uint{1, 2})
+	res, err := RequestAll(context.Background(), "query", []uint{1, 2})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -186,7 +184,7 @@ func TestRequestAll_FetchError(t *testing.T) {
 	}
 	defer func() { testJackettClient = nil }()
 
-	_, err := RequestAll("query", []uint{1})
+	_, err := RequestAll(context.Background(), "query", []uint{1})
 	if err == nil || err.Error() != "fetch error" {
 		t.Errorf("expected fetch error, got %v", err)
 	}
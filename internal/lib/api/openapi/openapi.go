@@ -0,0 +1,153 @@
+package openapi
+
+// Document - корень документа OpenAPI 3.0
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, path или header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+// Ref ссылается на схему из components
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// JSONContent - тело ответа или запроса application/json со схемой s
+func JSONContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+func New(title, version, basePath string) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Servers: []Server{{URL: basePath}},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+}
+
+// AddSchema генерирует схему по значению v и кладёт её в components под именем name
+func (d *Document) AddSchema(name string, v interface{}) *Schema {
+	s := SchemaOf(v)
+	d.Components.Schemas[name] = s
+	return s
+}
+
+func (d *Document) AddOperation(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+
+	switch method {
+	case "GET":
+		item.Get = op
+	case "POST":
+		item.Post = op
+	case "DELETE":
+		item.Delete = op
+	}
+}
+
+// ErrorResponses - типовые ответы с ошибкой в едином формате
+func ErrorResponses(statuses ...string) map[string]*Response {
+	descriptions := map[string]string{
+		"400": "Invalid parameters",
+		"401": "API key is missing or invalid",
+		"403": "API key is not allowed to access this endpoint",
+		"404": "Not found",
+		"409": "Conflict",
+		"413": "Request body is too large",
+		"415": "Unsupported file format",
+		"429": "Rate limit or daily quota exceeded",
+		"500": "Internal error",
+		"504": "Timed out waiting for torrent metadata",
+	}
+
+	responses := make(map[string]*Response, len(statuses))
+	for _, status := range statuses {
+		r := &Response{
+			Description: descriptions[status],
+			Content:     JSONContent(Ref("ErrorResponse")),
+		}
+		if status == "429" {
+			r.Headers = map[string]*Header{
+				"Retry-After": {Description: "Seconds until the request can be retried", Schema: &Schema{Type: "integer"}},
+			}
+		}
+		responses[status] = r
+	}
+	return responses
+}
@@ -0,0 +1,66 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// Коды ошибок API
+const (
+	CodeBadRequest    = "bad_request"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeNotFound      = "not_found"
+	CodeMethod        = "method_not_allowed"
+	CodeConflict      = "conflict"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeRateLimited   = "rate_limited"
+	CodeSearchFailed  = "search_failed"
+	CodeNoMetadata    = "metadata_timeout"
+	CodeTimeout       = "timeout"
+	CodeUnsupported   = "unsupported_format"
+	CodeInternal      = "internal_error"
+)
+
+// ErrorResponse - единый формат ошибок API
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// JSON отправляет ответ со статусом status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError отправляет ошибку в едином формате, request_id берётся из контекста запроса
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteErrorDetails(w, r, status, code, message, nil)
+}
+
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	JSON(w, status, ErrorResponse{Error: Error{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+	}})
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusNotFound, CodeNotFound, "route not found")
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusMethodNotAllowed, CodeMethod, "method not allowed")
+}
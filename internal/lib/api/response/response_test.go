@@ -0,0 +1,76 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+
+	resp "torrentServer/internal/lib/api/response"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.NotFound(resp.NotFound)
+	r.MethodNotAllowed(resp.MethodNotAllowed)
+	r.Get("/search", func(w http.ResponseWriter, r *http.Request) {
+		resp.WriteErrorDetails(w, r, http.StatusBadRequest, resp.CodeBadRequest, "invalid source parameter",
+			map[string]string{"source": "ftp"})
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		status  int
+		code    string
+		details bool
+	}{
+		{"handler error", http.MethodGet, "/search", http.StatusBadRequest, resp.CodeBadRequest, true},
+		{"unknown route", http.MethodGet, "/missing", http.StatusNotFound, resp.CodeNotFound, false},
+		{"wrong method", http.MethodPost, "/search", http.StatusMethodNotAllowed, resp.CodeMethod, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-42")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type = %q", tt.name, ct)
+		}
+		var body resp.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: decode error = %v", tt.name, err)
+		}
+		if body.Error.Code != tt.code || body.Error.Message == "" {
+			t.Errorf("%s: error = %+v, want code %q", tt.name, body.Error, tt.code)
+		}
+		if body.Error.RequestID != "req-42" {
+			t.Errorf("%s: request_id = %q, want req-42", tt.name, body.Error.RequestID)
+		}
+		if (body.Error.Details != nil) != tt.details {
+			t.Errorf("%s: details = %v", tt.name, body.Error.Details)
+		}
+	}
+}
+
+func TestErrorWithoutRequestID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	resp.WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusInternalServerError, resp.CodeInternal, "boom")
+
+	var body map[string]map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["error"]["request_id"]; ok {
+		t.Errorf("request_id present without RequestID middleware: %v", body)
+	}
+}
@@ -0,0 +1,240 @@
+// Package bencode кодирует и разбирает данные в формате bencode (BEP 3).
+//
+// Значения представлены типами Go так:
+//   - целое число - int64
+//   - байтовая строка - string (может содержать произвольные байты)
+//   - список - []any
+//   - словарь - map[string]any
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+var ErrSyntax = errors.New("bencode: invalid syntax")
+
+// максимальная вложенность списков и словарей, защищает от переполнения стека
+const maxDepth = 64
+
+// Unmarshal разбирает одно значение, после него в data не должно быть других байтов
+func Unmarshal(data []byte) (any, error) {
+	d := decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w: trailing data at offset %d", ErrSyntax, d.pos)
+	}
+	return v, nil
+}
+
+// RawDict разбирает словарь верхнего уровня и возвращает закодированные значения его ключей
+// как есть. Нужен, когда важны исходные байты, например для подсчёта info-hash по словарю info.
+func RawDict(data []byte) (map[string][]byte, error) {
+	d := decoder{data: data}
+	if d.peek() != 'd' {
+		return nil, fmt.Errorf("%w: expected dictionary", ErrSyntax)
+	}
+	d.pos++
+
+	raw := make(map[string][]byte)
+	for d.peek() != 'e' {
+		key, err := d.key(func(k string) bool { _, ok := raw[k]; return ok })
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		if _, err := d.value(1); err != nil {
+			return nil, err
+		}
+		raw[key] = data[start:d.pos]
+	}
+	d.pos++
+
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w: trailing data at offset %d", ErrSyntax, d.pos)
+	}
+	return raw, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// peek возвращает текущий байт или 0 в конце данных
+func (d *decoder) peek() byte {
+	if d.pos >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos]
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nesting is too deep", ErrSyntax)
+	}
+
+	switch c := d.peek(); {
+	case c == 'i':
+		d.pos++
+		return d.int('e')
+	case c >= '0' && c <= '9':
+		return d.string()
+	case c == 'l':
+		d.pos++
+		list := []any{}
+		for d.peek() != 'e' {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		d.pos++
+		return list, nil
+	case c == 'd':
+		d.pos++
+		dict := map[string]any{}
+		for d.peek() != 'e' {
+			key, err := d.key(func(k string) bool { _, ok := dict[k]; return ok })
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		d.pos++
+		return dict, nil
+	case d.pos >= len(d.data):
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrSyntax)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrSyntax, c, d.pos)
+	}
+}
+
+// key читает ключ словаря, повторяющийся ключ - ошибка
+func (d *decoder) key(exists func(string) bool) (string, error) {
+	if c := d.peek(); c < '0' || c > '9' {
+		if d.pos >= len(d.data) {
+			return "", fmt.Errorf("%w: unexpected end of data", ErrSyntax)
+		}
+		return "", fmt.Errorf("%w: dictionary key must be a string at offset %d", ErrSyntax, d.pos)
+	}
+	key, err := d.string()
+	if err != nil {
+		return "", err
+	}
+	if exists(key) {
+		return "", fmt.Errorf("%w: duplicate key %q", ErrSyntax, key)
+	}
+	return key, nil
+}
+
+// int читает десятичное число до байта end
+func (d *decoder) int(end byte) (int64, error) {
+	i := bytes.IndexByte(d.data[d.pos:], end)
+	if i < 0 {
+		return 0, fmt.Errorf("%w: unterminated integer at offset %d", ErrSyntax, d.pos)
+	}
+	s := string(d.data[d.pos : d.pos+i])
+	// i-0e, ведущие нули и пустое число запрещены спецификацией
+	if s == "" || s == "-" || s == "-0" || (len(s) > 1 && s[0] == '0') || (len(s) > 2 && s[:2] == "-0") {
+		return 0, fmt.Errorf("%w: invalid integer %q", ErrSyntax, s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid integer %q", ErrSyntax, s)
+	}
+	d.pos += i + 1
+	return n, nil
+}
+
+func (d *decoder) string() (string, error) {
+	start := d.pos
+	n, err := d.int(':')
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || n > int64(len(d.data)-d.pos) {
+		return "", fmt.Errorf("%w: invalid string length at offset %d", ErrSyntax, start)
+	}
+	s := string(d.data[d.pos : d.pos+int(n)])
+	d.pos += int(n)
+	return s, nil
+}
+
+// Marshal кодирует значение. Кроме типов, которые возвращает Unmarshal, принимаются
+// []byte, int, uint, []string и map[string]string. Ключи словарей сортируются.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case int64:
+		buf.WriteByte('i')
+		buf.WriteString(strconv.FormatInt(v, 10))
+		buf.WriteByte('e')
+	case int:
+		return encode(buf, int64(v))
+	case uint:
+		return encode(buf, int64(v))
+	case string:
+		buf.WriteString(strconv.Itoa(len(v)))
+		buf.WriteByte(':')
+		buf.WriteString(v)
+	case []byte:
+		return encode(buf, string(v))
+	case []any:
+		buf.WriteByte('l')
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case []string:
+		buf.WriteByte('l')
+		for _, item := range v {
+			encode(buf, item)
+		}
+		buf.WriteByte('e')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('d')
+		for _, k := range keys {
+			encode(buf, k)
+			if err := encode(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case map[string]string:
+		m := make(map[string]any, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return encode(buf, m)
+	default:
+		return fmt.Errorf("bencode: unsupported type %T", v)
+	}
+	return nil
+}
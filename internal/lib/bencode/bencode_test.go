@@ -0,0 +1,85 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"i42e", int64(42)},
+		{"i-7e", int64(-7)},
+		{"i0e", int64(0)},
+		{"4:spam", "spam"},
+		{"0:", ""},
+		{"le", []any{}},
+		{"l4:spami1ee", []any{"spam", int64(1)}},
+		{"d3:cow3:moo4:spaml1:a1:bee", map[string]any{"cow": "moo", "spam": []any{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		got, err := Unmarshal([]byte(tt.in))
+		if err != nil {
+			t.Errorf("Unmarshal(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Unmarshal(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", "i42", "ie", "i-0e", "i03e", "5:spam", "-1:a", "l", "d3:cowe",
+		"di1e3:mooe", "d1:a1:b1:a1:ce", "i1ei2e", "x",
+	} {
+		if _, err := Unmarshal([]byte(in)); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Unmarshal(%q) error = %v, want %v", in, err, ErrSyntax)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	v := map[string]any{
+		"info":     map[string]any{"name": "a", "length": int64(3)},
+		"announce": "http://tracker/announce",
+		"list":     []any{int64(-1), "x\x00y"},
+	}
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// ключи словаря отсортированы
+	want := "d8:announce23:http://tracker/announce4:infod6:lengthi3e4:name1:ae4:listli-1e3:x\x00yee"
+	if string(data) != want {
+		t.Fatalf("Marshal() = %q, want %q", data, want)
+	}
+
+	got, err := Unmarshal(data)
+	if err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Unmarshal(Marshal(v)) = %#v, %v", got, err)
+	}
+}
+
+func TestRawDict(t *testing.T) {
+	// словарь info намеренно не в каноническом порядке ключей, сырые байты должны сохраниться
+	data := []byte("d8:announce1:x4:infod4:name1:a6:lengthi3eee")
+	raw, err := RawDict(data)
+	if err != nil {
+		t.Fatalf("RawDict() error = %v", err)
+	}
+	if got := string(raw["info"]); got != "d4:name1:a6:lengthi3ee" {
+		t.Errorf("raw info = %q", got)
+	}
+	if got := string(raw["announce"]); got != "1:x" {
+		t.Errorf("raw announce = %q", got)
+	}
+
+	if _, err := RawDict([]byte("l1:ae")); !errors.Is(err, ErrSyntax) {
+		t.Errorf("RawDict(list) error = %v, want %v", err, ErrSyntax)
+	}
+}
@@ -0,0 +1,145 @@
+// Package episode разбирает номера сезона и серии в именах файлов раздач
+// и упорядочивает файлы так, как их ожидает увидеть человек.
+package episode
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Number - сезон и серия. Season = 0, если сезон не указан ни в имени файла, ни в каталогах.
+type Number struct {
+	Season  int
+	Episode int
+}
+
+func (n Number) String() string {
+	if n.Season == 0 {
+		return fmt.Sprintf("E%02d", n.Episode)
+	}
+	return fmt.Sprintf("S%02dE%02d", n.Season, n.Episode)
+}
+
+// Compare упорядочивает номера по сезону, затем по серии
+func (n Number) Compare(o Number) int {
+	if n.Season != o.Season {
+		return n.Season - o.Season
+	}
+	return n.Episode - o.Episode
+}
+
+var (
+	// S01E02, s1.e2, S01 E02
+	seasonEpisode = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])s(\d{1,2})[ ._-]?e(\d{1,3})(?:[^0-9]|$)`)
+	// 1x02
+	crossEpisode = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(\d{1,2})x(\d{2,3})(?:[^0-9]|$)`)
+	// Episode 5, Ep05, E05, Серия 5
+	namedEpisode = regexp.MustCompile(`(?i)(?:^|[^\p{L}0-9])(?:episode|ep|e|серия)[ ._-]*(\d{1,3})(?:[^0-9]|$)`)
+	// 5 серия
+	episodeSuffix = regexp.MustCompile(`(?i)(?:^|[^0-9])(\d{1,3})[ ._-]*серия`)
+	// [Group] Title - 05 [1080p], Title - 05v2
+	dashEpisode = regexp.MustCompile(`(?i)\s-\s(\d{1,3})(?:v\d)?(?:[\s\[(.]|$)`)
+	// 05. Title, 05 - Title, 05
+	leadingEpisode = regexp.MustCompile(`^(\d{1,3})(?:[ ._-]|$)`)
+
+	// Season 2, Сезон 2, S02 в имени каталога
+	seasonDir = regexp.MustCompile(`(?i)(?:^|[^\p{L}0-9])(?:season|сезон|s)[ ._-]*(\d{1,2})(?:[^0-9]|$)`)
+	// 2 сезон, 2nd season
+	seasonSuffix = regexp.MustCompile(`(?i)(?:^|[^0-9])(\d{1,2})(?:st|nd|rd|th)?[ ._-]*(?:season|сезон)`)
+)
+
+// Parse находит сезон и серию по пути файла внутри раздачи. Сезон, которого нет в имени файла,
+// берётся из ближайшего каталога: Season 2/05. Title.mkv - S02E05.
+func Parse(filePath string) (Number, bool) {
+	name := path.Base(filePath)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	if m := seasonEpisode.FindStringSubmatch(name); m != nil {
+		return Number{Season: atoi(m[1]), Episode: atoi(m[2])}, true
+	}
+	if m := crossEpisode.FindStringSubmatch(name); m != nil {
+		return Number{Season: atoi(m[1]), Episode: atoi(m[2])}, true
+	}
+
+	var n Number
+	found := false
+	for _, re := range []*regexp.Regexp{namedEpisode, episodeSuffix, dashEpisode, leadingEpisode} {
+		if m := re.FindStringSubmatch(name); m != nil {
+			n.Episode, found = atoi(m[1]), true
+			break
+		}
+	}
+	if !found {
+		return Number{}, false
+	}
+
+	dirs := strings.Split(path.Dir(filePath), "/")
+	for i := len(dirs) - 1; i >= 0 && n.Season == 0; i-- {
+		for _, re := range []*regexp.Regexp{seasonDir, seasonSuffix} {
+			if m := re.FindStringSubmatch(dirs[i]); m != nil {
+				n.Season = atoi(m[1])
+				break
+			}
+		}
+	}
+	return n, true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// Compare упорядочивает пути файлов: сначала файлы с номером серии по сезону и серии,
+// затем остальные в естественном порядке (2 раньше 10)
+func Compare(a, b string) int {
+	na, okA := Parse(a)
+	nb, okB := Parse(b)
+	switch {
+	case okA && okB:
+		if c := na.Compare(nb); c != 0 {
+			return c
+		}
+	case okA:
+		return -1
+	case okB:
+		return 1
+	}
+	return Natural(a, b)
+}
+
+// Natural сравнивает строки без учёта регистра, последовательности цифр - как числа
+func Natural(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		da, db := digits(a), digits(b)
+		if da > 0 && db > 0 {
+			// числа сравниваются по длине без ведущих нулей, затем посимвольно
+			ta, tb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if len(ta) != len(tb) {
+				return len(ta) - len(tb)
+			}
+			if c := strings.Compare(ta, tb); c != 0 {
+				return c
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+func digits(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return i
+}
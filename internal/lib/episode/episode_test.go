@@ -0,0 +1,68 @@
+package episode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		path string
+		want Number
+		ok   bool
+	}{
+		{"Show.S01E02.1080p.WEB-DL.mkv", Number{1, 2}, true},
+		{"show s2.e10 720p.mp4", Number{2, 10}, true},
+		{"Show 3x07 Title.avi", Number{3, 7}, true},
+		{"Show/Season 2/Episode 5.mkv", Number{2, 5}, true},
+		{"Сериал/Сезон 3/Серия 12.mkv", Number{3, 12}, true},
+		{"Сериал/2 сезон/4 серия.mkv", Number{2, 4}, true},
+		{"Show/S04/05. Pilot.mkv", Number{4, 5}, true},
+		{"[Group] Title - 07v2 [1080p].mkv", Number{0, 7}, true},
+		{"Title.E03.mkv", Number{0, 3}, true},
+		{"Movie.1920x1080.mkv", Number{}, false},
+		{"Movie.2019.1080p.x264.mkv", Number{}, false},
+		{"Extras/Making Of.mkv", Number{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if s := (Number{1, 2}).String(); s != "S01E02" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := (Number{0, 112}).String(); s != "E112" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	paths := []string{
+		"Show/Extras/Trailer 10.mkv",
+		"Show/Season 2/Show.S02E01.mkv",
+		"Show/Season 1/Show.S01E10.mkv",
+		"Show/Extras/Trailer 2.mkv",
+		"Show/Season 1/Show.S01E02.mkv",
+		"Show/Season 1/Show.S01E01.mkv",
+	}
+	slices.SortFunc(paths, Compare)
+
+	want := []string{
+		"Show/Season 1/Show.S01E01.mkv",
+		"Show/Season 1/Show.S01E02.mkv",
+		"Show/Season 1/Show.S01E10.mkv",
+		"Show/Season 2/Show.S02E01.mkv",
+		"Show/Extras/Trailer 2.mkv",
+		"Show/Extras/Trailer 10.mkv",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("sorted = %v, want %v", paths, want)
+	}
+
+	if Natural("file002", "File10") >= 0 || Natural("a", "a1") >= 0 || Natural("b", "a") <= 0 {
+		t.Error("Natural() order mismatch")
+	}
+}
@@ -0,0 +1,69 @@
+// Package metrics - метрики Prometheus, отдаются на /metrics
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "torrent_server"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// result: hit, miss или error
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Search cache lookups by result.",
+	}, []string{"result"})
+
+	JackettFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "jackett_fetch_duration_seconds",
+		Help:      "Latency of Jackett search requests.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	})
+
+	JackettFetchErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jackett_fetch_errors_total",
+		Help:      "Failed Jackett search requests.",
+	})
+
+	IndexerResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jackett_indexer_results_total",
+		Help:      "Results returned by each Jackett indexer.",
+	}, []string{"indexer"})
+
+	IndexerLastResults = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jackett_indexer_last_results",
+		Help:      "Results returned by each Jackett indexer in the last search.",
+	}, []string{"indexer"})
+
+	// status - код статуса индексатора из ответа Jackett
+	IndexerStatus = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jackett_indexer_responses_total",
+		Help:      "Jackett indexer responses by status.",
+	}, []string{"indexer", "status"})
+
+	IndexerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jackett_indexer_errors_total",
+		Help:      "Jackett indexer responses with an error.",
+	}, []string{"indexer"})
+)
@@ -0,0 +1,176 @@
+package subtitles
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// поля реплики по умолчанию, если в [Events] нет строки Format
+var assDefaultFormat = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+// таймкод ASS: 0:01:02.34, дробная часть в сотых долях секунды
+var assTime = regexp.MustCompile(`^\s*(\d+):(\d{1,2}):(\d{1,2})(?:\.(\d{1,3}))?\s*$`)
+
+// ParseASS разбирает Advanced SubStation Alpha и SubStation Alpha. Из разметки
+// сохраняются курсив, жирный и подчёркнутый текст, позиционирование и стили отбрасываются.
+// Реплики с рисованием (\p1) пропускаются: в WebVTT их не показать.
+func ParseASS(text string) ([]Cue, error) {
+	var cues []Cue
+	var inEvents bool
+	format := assDefaultFormat
+
+	for _, line := range strings.Split(strings.TrimPrefix(text, "\uFEFF"), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "format":
+			format = nil
+			for _, f := range strings.Split(value, ",") {
+				format = append(format, strings.ToLower(strings.TrimSpace(f)))
+			}
+		case "dialogue":
+			if cue, ok := assDialogue(format, value); ok {
+				cues = append(cues, cue)
+			}
+		}
+	}
+
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("%w: no dialogue in [Events]", ErrInvalid)
+	}
+	// в ASS реплики могут идти не по порядку, в WebVTT они должны быть упорядочены по началу
+	slices.SortStableFunc(cues, func(a, b Cue) int { return int(a.Start - b.Start) })
+
+	return cues, nil
+}
+
+func assDialogue(format []string, value string) (Cue, bool) {
+	// текст - последнее поле и может содержать запятые
+	fields := strings.SplitN(value, ",", len(format))
+	if len(fields) != len(format) {
+		return Cue{}, false
+	}
+
+	var cue Cue
+	var hasStart, hasEnd bool
+	for i, name := range format {
+		switch name {
+		case "start":
+			cue.Start, hasStart = assClock(fields[i])
+		case "end":
+			cue.End, hasEnd = assClock(fields[i])
+		case "text":
+			var ok bool
+			if cue.Text, ok = assText(fields[i]); !ok {
+				return Cue{}, false
+			}
+		}
+	}
+	if !hasStart || !hasEnd || cue.End <= cue.Start || cue.Text == "" {
+		return Cue{}, false
+	}
+	return cue, true
+}
+
+func assClock(s string) (d time.Duration, ok bool) {
+	m := assTime.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	// 0:00:01.5 - полсекунды, 0:00:01.05 - пять сотых
+	return clock(m[1], m[2], m[3], m[4]), true
+}
+
+var (
+	assBlock = regexp.MustCompile(`\{[^{}]*\}`)
+	// i1, b1, u1 включают стиль, i0 или i без числа - выключают; b700 - жирность шрифта.
+	// Сравнивается всё переопределение целиком, чтобы не спутать \b с \bord и \blur.
+	assStyle   = regexp.MustCompile(`^([biu])(\d*)$`)
+	assDrawing = regexp.MustCompile(`\\p[1-9]`)
+)
+
+// assText переводит текст реплики ASS в разметку WebVTT, ok = false для рисунков
+func assText(s string) (string, bool) {
+	open := map[string]bool{}
+	var order []string // открытые теги в порядке открытия, закрываются в обратном
+
+	var b strings.Builder
+	closeTag := func(tag string) {
+		// теги WebVTT должны быть вложены: закрываем всё, что открыто после tag, и открываем заново
+		i := slices.Index(order, tag)
+		reopen := slices.Clone(order[i+1:])
+		for j := len(order) - 1; j >= i; j-- {
+			b.WriteString("</" + order[j] + ">")
+		}
+		order = order[:i]
+		delete(open, tag)
+		for _, t := range reopen {
+			b.WriteString("<" + t + ">")
+			order = append(order, t)
+		}
+	}
+
+	last := 0
+	for _, loc := range assBlock.FindAllStringIndex(s, -1) {
+		b.WriteString(assPlain(s[last:loc[0]]))
+		last = loc[1]
+
+		block := s[loc[0]:loc[1]]
+		if assDrawing.MatchString(block) {
+			return "", false
+		}
+		for _, override := range strings.Split(strings.Trim(block, "{}"), `\`) {
+			m := assStyle.FindStringSubmatch(strings.TrimSpace(override))
+			if m == nil {
+				continue
+			}
+			tag, on := m[1], m[2] != "" && m[2] != "0"
+			switch {
+			case on && !open[tag]:
+				b.WriteString("<" + tag + ">")
+				open[tag] = true
+				order = append(order, tag)
+			case !on && open[tag]:
+				closeTag(tag)
+			}
+		}
+	}
+	b.WriteString(assPlain(s[last:]))
+	for j := len(order) - 1; j >= 0; j-- {
+		b.WriteString("</" + order[j] + ">")
+	}
+
+	// пустые строки завершили бы реплику WebVTT
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !emptyMarkup(line) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// assPlain экранирует текст и раскрывает переносы строк \N, \n и неразрывный пробел \h
+func assPlain(s string) string {
+	s = escape(s)
+	return strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, "\u00a0").Replace(s)
+}
+
+// emptyMarkup - в строке одни теги без текста
+func emptyMarkup(line string) bool {
+	return strings.TrimSpace(anyTag.ReplaceAllString(line, "")) == ""
+}
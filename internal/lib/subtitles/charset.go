@@ -0,0 +1,106 @@
+package subtitles
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Кодировки, которые определяет Decode
+const (
+	CharsetUTF8        = "utf-8"
+	CharsetUTF16LE     = "utf-16le"
+	CharsetUTF16BE     = "utf-16be"
+	CharsetWindows1251 = "windows-1251"
+	CharsetKOI8R       = "koi8-r"
+	CharsetWindows1252 = "windows-1252"
+)
+
+// Decode переводит текст субтитров в UTF-8 и возвращает исходную кодировку.
+// Кодировка определяется по BOM, затем по валидности UTF-8, а однобайтовые кодировки
+// различаются по частоте байтов: в русском тексте почти каждое слово состоит из байтов
+// выше 0x7F, строчные буквы в windows-1251 лежат в 0xE0-0xFF, в KOI8-R - в 0xC0-0xDF.
+func Decode(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), CharsetUTF8
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data[2:]), CharsetUTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decode(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), data[2:]), CharsetUTF16BE
+	}
+
+	if charset := utf16WithoutBOM(data); charset == CharsetUTF16LE {
+		return decode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data), charset
+	} else if charset == CharsetUTF16BE {
+		return decode(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), data), charset
+	}
+
+	if utf8.Valid(data) {
+		return string(data), CharsetUTF8
+	}
+
+	var letters, high, lower, upper int
+	for _, b := range data {
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z':
+			letters++
+		case b >= 0xE0:
+			high++
+			lower++
+		case b >= 0xC0:
+			high++
+			upper++
+		case b >= 0x80:
+			high++
+		}
+	}
+
+	// в западноевропейских текстах буквы с диакритикой - малая доля от латиницы
+	if high*10 < (letters+high)*3 {
+		return decode(charmap.Windows1252, data), CharsetWindows1252
+	}
+	// в тексте строчных букв больше, чем заглавных
+	if upper > lower {
+		return decode(charmap.KOI8R, data), CharsetKOI8R
+	}
+	return decode(charmap.Windows1251, data), CharsetWindows1251
+}
+
+// utf16WithoutBOM распознаёт UTF-16 без BOM по нулевым байтам: у ASCII-символов
+// (номера, таймкоды) старший байт нулевой, а в тексте в однобайтовых кодировках нулей нет
+func utf16WithoutBOM(data []byte) string {
+	if len(data) < 16 {
+		return ""
+	}
+
+	var even, odd int
+	for i, b := range data {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+
+	// в кириллице старший байт ненулевой, нули дают только пробелы, цифры и знаки препинания
+	switch {
+	case odd > len(data)/16 && even*10 < odd:
+		return CharsetUTF16LE
+	case even > len(data)/16 && odd*10 < even:
+		return CharsetUTF16BE
+	}
+	return ""
+}
+
+func decode(enc encoding.Encoding, data []byte) string {
+	// однобайтовые декодеры не возвращают ошибок, UTF-16 заменяет битые пары на U+FFFD
+	out, _ := enc.NewDecoder().Bytes(data)
+	return string(out)
+}
@@ -0,0 +1,132 @@
+package subtitles
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"torrentServer/internal/lib/episode"
+)
+
+// языковые метки в именах файлов и каталогов: ISO 639-1, ISO 639-2 и названия языков
+var languages = map[string]string{
+	"en": "en", "eng": "en", "english": "en", "англ": "en", "английский": "en",
+	"ru": "ru", "rus": "ru", "russian": "ru", "рус": "ru", "русский": "ru", "русские": "ru",
+	"uk": "uk", "ua": "uk", "ukr": "uk", "ukrainian": "uk", "укр": "uk", "украинский": "uk",
+	"de": "de", "ger": "de", "deu": "de", "german": "de",
+	"fr": "fr", "fre": "fr", "fra": "fr", "french": "fr",
+	"es": "es", "spa": "es", "spanish": "es",
+	"it": "it", "ita": "it", "italian": "it",
+	"pt": "pt", "por": "pt", "portuguese": "pt",
+	"pl": "pl", "pol": "pl", "polish": "pl",
+	"ja": "ja", "jpn": "ja", "japanese": "ja",
+	"zh": "zh", "chi": "zh", "zho": "zh", "chinese": "zh",
+	"ko": "ko", "kor": "ko", "korean": "ko",
+}
+
+// LanguageName - название языка для подписи дорожки в плеере
+var LanguageName = map[string]string{
+	"en": "English", "ru": "Русский", "uk": "Українська", "de": "Deutsch", "fr": "Français",
+	"es": "Español", "it": "Italiano", "pt": "Português", "pl": "Polski", "ja": "日本語",
+	"zh": "中文", "ko": "한국어",
+}
+
+// Language возвращает код языка ISO 639-1 по имени файла субтитров или пустую строку.
+// Метка ищется с конца имени (Movie.rus.srt, 2_English.srt), затем в каталогах (Subs/Rus/01.srt).
+// video - путь видео, к которому относятся субтитры, или пустая строка: его имя в начале имени
+// субтитров не рассматривается, иначе It.2017.srt оказались бы итальянскими.
+func Language(name, video string) string {
+	parts := strings.Split(strings.TrimSuffix(name, path.Ext(name)), "/")
+	if video != "" {
+		base := path.Base(strings.TrimSuffix(video, path.Ext(video)))
+		last := len(parts) - 1
+		if len(parts[last]) >= len(base) && strings.EqualFold(parts[last][:len(base)], base) {
+			parts[last] = parts[last][len(base):]
+		}
+	}
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		words := tokens(parts[i])
+		for j := len(words) - 1; j >= 0; j-- {
+			if lang, ok := languages[words[j]]; ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// Forced - субтитры только для надписей и иноязычных реплик
+func Forced(name string) bool {
+	for _, w := range tokens(strings.TrimSuffix(name, path.Ext(name))) {
+		if w == "forced" || w == "надписи" {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens делит строку на слова в нижнем регистре
+func tokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MatchVideo находит видео, к которому относятся субтитры, и возвращает его индекс в videos или -1.
+// Пути - внутри раздачи через "/". Проверяется по порядку:
+//   - имя субтитров начинается с имени видео: Movie.mkv и Movie.rus.forced.srt;
+//   - субтитры в каталоге с именем видео: Subs/Movie/2_English.srt;
+//   - совпадает сезон и серия: Show.S01E02.mkv и Subs/Rus/s01e02.srt, см. episode.Parse;
+//   - видео в раздаче одно.
+func MatchVideo(subtitle string, videos []string) int {
+	sub := strings.ToLower(strings.TrimSuffix(subtitle, path.Ext(subtitle)))
+	subName := path.Base(sub)
+
+	best, bestLen := -1, 0
+	for i, v := range videos {
+		name := strings.ToLower(path.Base(strings.TrimSuffix(v, path.Ext(v))))
+		// из нескольких подходящих выбирается самое длинное имя: Show.Part2, а не Show
+		if len(name) > bestLen && (subName == name || strings.HasPrefix(subName, name) && isSeparator(subName[len(name)])) {
+			best, bestLen = i, len(name)
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+
+	dirs := strings.Split(path.Dir(sub), "/")
+	for i, v := range videos {
+		name := strings.ToLower(path.Base(strings.TrimSuffix(v, path.Ext(v))))
+		for _, dir := range dirs {
+			if dir == name {
+				return i
+			}
+		}
+	}
+
+	if number, ok := episode.Parse(subtitle); ok {
+		best = -1
+		for i, v := range videos {
+			if n, ok := episode.Parse(v); !ok || n != number {
+				continue
+			}
+			if best >= 0 {
+				return -1 // номер серии не однозначен, например одна серия в двух качествах
+			}
+			best = i
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+
+	if len(videos) == 1 {
+		return 0
+	}
+	return -1
+}
+
+func isSeparator(c byte) bool {
+	return c == '.' || c == '_' || c == '-' || c == ' ' || c == '['
+}
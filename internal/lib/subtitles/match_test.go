@@ -0,0 +1,52 @@
+package subtitles
+
+import "testing"
+
+func TestMatchVideo(t *testing.T) {
+	videos := []string{
+		"Show/Show.S01E01.1080p.mkv",
+		"Show/Show.S01E02.1080p.mkv",
+		"Show/Show.S01E10.1080p.mkv",
+		"Show/Extras/Show.S01E01.1080p.Making.Of.mkv",
+	}
+
+	tests := []struct {
+		subtitle string
+		want     int
+		lang     string
+	}{
+		{"Show/Show.S01E01.1080p.srt", 0, ""},
+		{"Show/Show.S01E02.1080p.rus.srt", 1, "ru"},
+		{"Show/Show.S01E01.1080p.Making.Of.eng.forced.srt", 3, "en"},
+		{"Show/Subs/Show.S01E10.1080p/2_English.srt", 2, "en"},
+		{"Show/Subs/Rus/s01e02.ass", 1, "ru"},
+		{"Show/Субтитры/Русские/Show.1x10.srt", 2, "ru"},
+		// первая серия есть и среди дополнительных материалов
+		{"Show/Subs/s01e01.Ukr.srt", -1, "uk"},
+		{"Show/readme.srt", -1, ""},
+	}
+	for _, tt := range tests {
+		got := MatchVideo(tt.subtitle, videos)
+		if got != tt.want {
+			t.Errorf("MatchVideo(%q) = %d, want %d", tt.subtitle, got, tt.want)
+		}
+		var video string
+		if got >= 0 {
+			video = videos[got]
+		}
+		if lang := Language(tt.subtitle, video); lang != tt.lang {
+			t.Errorf("Language(%q) = %q, want %q", tt.subtitle, lang, tt.lang)
+		}
+	}
+
+	// единственное видео подходит к любым субтитрам, имя видео не принимается за язык
+	if got := MatchVideo("Subs/English.srt", []string{"It.2017.mkv"}); got != 0 {
+		t.Errorf("MatchVideo() with one video = %d, want 0", got)
+	}
+	if lang := Language("It.2017.srt", "It.2017.mkv"); lang != "" {
+		t.Errorf("Language() = %q, want none", lang)
+	}
+	if !Forced("Movie.eng.FORCED.srt") || Forced("Movie.eng.srt") {
+		t.Error("Forced() mismatch")
+	}
+}
@@ -0,0 +1,187 @@
+// Package subtitles переводит субтитры SRT, ASS/SSA и WebVTT в WebVTT для HTML5-плееров
+// и находит видео, к которому относятся субтитры раздачи.
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported subtitle format")
+	ErrInvalid     = errors.New("invalid subtitles")
+)
+
+// MaxSize - предельный размер файла субтитров, больше - это уже не субтитры
+const MaxSize = 10 << 20
+
+// Cue - одна реплика
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string // разметка WebVTT: <i>, <b>, <u>, строки через \n
+}
+
+// Supported сообщает, умеет ли ToVTT переводить файл с таким именем
+func Supported(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".srt", ".ass", ".ssa", ".vtt":
+		return true
+	}
+	return false
+}
+
+// ToVTT переводит субтитры в WebVTT, формат определяется по расширению name.
+// Возвращает исходную кодировку текста, см. Decode.
+func ToVTT(name string, data []byte) ([]byte, string, error) {
+	if !Supported(name) {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupported, path.Ext(name))
+	}
+
+	text, charset := Decode(data)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var cues []Cue
+	var err error
+	switch strings.ToLower(path.Ext(name)) {
+	case ".srt":
+		cues, err = ParseSRT(text)
+	case ".ass", ".ssa":
+		cues, err = ParseASS(text)
+	case ".vtt":
+		if !strings.HasPrefix(text, "WEBVTT") {
+			return nil, charset, fmt.Errorf("%w: no WEBVTT header", ErrInvalid)
+		}
+		return []byte(text), charset, nil
+	}
+	if err != nil {
+		return nil, charset, err
+	}
+
+	return WriteVTT(cues), charset, nil
+}
+
+// WriteVTT собирает документ WebVTT
+func WriteVTT(cues []Cue) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, c := range cues {
+		b.WriteString("\n")
+		b.WriteString(formatTime(c.Start))
+		b.WriteString(" --> ")
+		b.WriteString(formatTime(c.End))
+		b.WriteString("\n")
+		b.WriteString(c.Text)
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+func formatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// таймкод SRT: 00:01:02,345, встречаются точка вместо запятой и меньше цифр
+var srtTime = regexp.MustCompile(`^\s*(\d+):(\d{1,2}):(\d{1,2})(?:[,.](\d{1,3}))?\s*-->\s*(\d+):(\d{1,2}):(\d{1,2})(?:[,.](\d{1,3}))?`)
+
+// ParseSRT разбирает SubRip. Блоки без таймкода пропускаются, номера реплик не нужны.
+func ParseSRT(text string) ([]Cue, error) {
+	var cues []Cue
+	lines := strings.Split(strings.TrimPrefix(text, "\uFEFF"), "\n")
+	for i := 0; i < len(lines); i++ {
+		m := srtTime.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+
+		var body []string
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+			// строка из одних тегов после очистки пуста, а пустая строка в WebVTT завершает реплику
+			if line := srtText(lines[i]); line != "" {
+				body = append(body, line)
+			}
+		}
+		if len(body) == 0 {
+			continue
+		}
+
+		cues = append(cues, Cue{
+			Start: clock(m[1], m[2], m[3], m[4]),
+			End:   clock(m[5], m[6], m[7], m[8]),
+			Text:  strings.Join(body, "\n"),
+		})
+	}
+
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("%w: no cues", ErrInvalid)
+	}
+	return cues, nil
+}
+
+// clock переводит часы, минуты, секунды и дробную часть секунды в длительность
+func clock(h, m, s, frac string) time.Duration {
+	hours, _ := strconv.Atoi(h)
+	minutes, _ := strconv.Atoi(m)
+	seconds, _ := strconv.Atoi(s)
+	// дробная часть: "5" - 500 мс, "05" - 50 мс
+	ms, _ := strconv.Atoi((frac + "000")[:3])
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(ms)*time.Millisecond
+}
+
+var (
+	// теги, которые есть и в SRT, и в WebVTT
+	srtTag = regexp.MustCompile(`(?i)<(/?)([biu])\s*>`)
+	// прочие теги SRT (<font color=...>) WebVTT не понимает
+	anyTag = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+	// ASS-переопределения вроде {\an8}, которые вставляют в SRT
+	assOverride = regexp.MustCompile(`\{\\[^{}]*\}`)
+	entity      = regexp.MustCompile(`^&(?:[a-zA-Z]+|#\d+|#x[0-9a-fA-F]+);`)
+)
+
+// srtText оставляет в строке только разметку, допустимую в WebVTT
+func srtText(line string) string {
+	line = assOverride.ReplaceAllString(strings.TrimRight(line, " \t"), "")
+
+	// теги b, i, u переживают экранирование в виде маркеров
+	line = srtTag.ReplaceAllStringFunc(line, func(tag string) string {
+		m := srtTag.FindStringSubmatch(tag)
+		return "\x00" + m[1] + strings.ToLower(m[2]) + "\x01"
+	})
+	line = anyTag.ReplaceAllString(line, "")
+	line = escape(line)
+	line = strings.NewReplacer("\x00", "<", "\x01", ">").Replace(line)
+
+	return line
+}
+
+// escape экранирует текст реплики: & и < в WebVTT начинают сущность и тег,
+// а "-->" в тексте ломает разбор таймкодов
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			if entity.MatchString(s[i:]) {
+				b.WriteByte(c)
+			} else {
+				b.WriteString("&amp;")
+			}
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
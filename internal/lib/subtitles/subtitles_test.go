@@ -0,0 +1,96 @@
+package subtitles
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestSRTToVTT(t *testing.T) {
+	srt := "1\r\n00:00:01,500 --> 00:00:03,000\r\n<I>Привет</I>, <font color=\"#ff0000\">мир</font> & A<B\r\n\r\n" +
+		"2\r\n00:01:02.5 --> 00:01:04,25 X1:10 X2:20\r\n{\\an8}Сверху\r\n{\\an8}\r\nи ещё --> строка\r\n\r\n" +
+		"мусор без таймкода\r\n\r\n" +
+		"3\r\n01:00:00,000 --> 01:00:01,000\r\n\r\n"
+
+	got, charset, err := ToVTT("movie.srt", []byte(srt))
+	if err != nil {
+		t.Fatalf("ToVTT() error = %v", err)
+	}
+	want := "WEBVTT\n\n" +
+		"00:00:01.500 --> 00:00:03.000\n<i>Привет</i>, мир &amp; A&lt;B\n\n" +
+		"00:01:02.500 --> 00:01:04.250\nСверху\nи ещё --&gt; строка\n"
+	if string(got) != want || charset != CharsetUTF8 {
+		t.Errorf("ToVTT() = %q (%s), want\n%q", got, charset, want)
+	}
+}
+
+func TestASSToVTT(t *testing.T) {
+	ass := "[Script Info]\nTitle: test\n\n" +
+		"[V4+ Styles]\nFormat: Name, Fontname\nStyle: Default,Arial\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 0,0:00:05.00,0:00:06.50,Default,,0,0,0,,Second, with commas\n" +
+		"Dialogue: 0,0:00:01.5,0:00:02.05,Default,,0,0,0,,{\\i1\\bord2}First{\\b1} line{\\i0} &\\Nnext\\hword\n" +
+		"Comment: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,comment\n" +
+		"Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,{\\p1}m 0 0 l 100 0 100 100\n" +
+		"Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,{\\pos(10,10)\\blur3}Sign\n"
+
+	got, _, err := ToVTT("movie.ass", []byte(ass))
+	if err != nil {
+		t.Fatalf("ToVTT() error = %v", err)
+	}
+	want := "WEBVTT\n\n" +
+		"00:00:01.500 --> 00:00:02.050\n<i>First<b> line</b></i><b> &amp;\nnext\u00a0word</b>\n\n" +
+		"00:00:05.000 --> 00:00:06.500\nSecond, with commas\n\n" +
+		"00:00:07.000 --> 00:00:08.000\nSign\n"
+	if string(got) != want {
+		t.Errorf("ToVTT() = %q, want\n%q", got, want)
+	}
+}
+
+func TestToVTTErrors(t *testing.T) {
+	if _, _, err := ToVTT("movie.sub", []byte("{1}{2}text")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ToVTT(.sub) error = %v, want ErrUnsupported", err)
+	}
+	if _, _, err := ToVTT("movie.srt", []byte("not subtitles")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToVTT(junk) error = %v, want ErrInvalid", err)
+	}
+	if got, _, err := ToVTT("movie.vtt", []byte("\xEF\xBB\xBFWEBVTT\r\n\r\n00:01.000 --> 00:02.000\r\nhi\r\n")); err != nil || string(got) != "WEBVTT\n\n00:01.000 --> 00:02.000\nhi\n" {
+		t.Errorf("ToVTT(.vtt) = %q, %v", got, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	const ru = "1\n00:00:01,000 --> 00:00:02,000\nСъешь же ещё этих мягких французских булок, да выпей чаю.\n"
+	const fr = "1\n00:00:01,000 --> 00:00:02,000\nJ'ai été à l'école, ça va très bien.\n"
+
+	cp1251, _ := charmap.Windows1251.NewEncoder().String(ru)
+	koi8, _ := charmap.KOI8R.NewEncoder().String(ru)
+	latin, _ := charmap.Windows1252.NewEncoder().String(fr)
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(ru)
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().String(ru)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		charset string
+	}{
+		{"utf-8", ru, ru, CharsetUTF8},
+		{"utf-8 bom", "\xEF\xBB\xBF" + ru, ru, CharsetUTF8},
+		{"windows-1251", cp1251, ru, CharsetWindows1251},
+		{"koi8-r", koi8, ru, CharsetKOI8R},
+		{"windows-1252", latin, fr, CharsetWindows1252},
+		{"utf-16le bom", utf16, ru, CharsetUTF16LE},
+		{"utf-16be without bom", utf16be, ru, CharsetUTF16BE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, charset := Decode([]byte(tt.data))
+			if got != tt.want || charset != tt.charset {
+				t.Errorf("Decode() = %q (%s), want %q (%s)", got, charset, tt.want, tt.charset)
+			}
+		})
+	}
+}
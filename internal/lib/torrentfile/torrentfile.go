@@ -0,0 +1,201 @@
+// Package torrentfile разбирает .torrent файлы (BEP 3, BEP 12) с помощью пакета bencode.
+package torrentfile
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"torrentServer/internal/lib/bencode"
+)
+
+var ErrInvalid = errors.New("invalid torrent file")
+
+// MaxSize - предельный размер .torrent файла, который имеет смысл разбирать
+const MaxSize = 10 << 20
+
+// File - файл внутри раздачи, порядок совпадает с порядком в словаре info
+type File struct {
+	Path string // путь внутри раздачи через "/", для однофайловой раздачи - имя
+	Size int64
+}
+
+type MetaInfo struct {
+	InfoHash     string // hex, sha1 от закодированного словаря info
+	Name         string
+	Size         int64
+	PieceLength  int64
+	Pieces       int
+	Private      bool
+	Trackers     []string // announce и announce-list без повторов
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time // нулевое, если не указано
+	Files        []File
+}
+
+// Parse разбирает содержимое .torrent файла
+func Parse(data []byte) (*MetaInfo, error) {
+	raw, err := bencode.RawDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	infoBytes, ok := raw["info"]
+	if !ok {
+		return nil, fmt.Errorf("%w: no info dictionary", ErrInvalid)
+	}
+	v, err := bencode.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	root := v.(map[string]any)
+	info, ok := root["info"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: info is not a dictionary", ErrInvalid)
+	}
+
+	sum := sha1.Sum(infoBytes)
+	mi := &MetaInfo{
+		InfoHash:  hex.EncodeToString(sum[:]),
+		Comment:   str(root, "comment"),
+		CreatedBy: str(root, "created by"),
+	}
+	if date, ok := root["creation date"].(int64); ok && date > 0 {
+		mi.CreationDate = time.Unix(date, 0).UTC()
+	}
+	mi.Trackers = trackers(root)
+
+	if err := mi.parseInfo(info); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return mi, nil
+}
+
+func (mi *MetaInfo) parseInfo(info map[string]any) error {
+	// name.utf-8 - нестандартное, но распространённое поле с именем в UTF-8
+	mi.Name = str(info, "name.utf-8")
+	if mi.Name == "" {
+		mi.Name = str(info, "name")
+	}
+	if mi.Name == "" {
+		return errors.New("info has no name")
+	}
+
+	pieceLength, _ := info["piece length"].(int64)
+	if pieceLength <= 0 {
+		return errors.New("invalid piece length")
+	}
+	mi.PieceLength = pieceLength
+
+	pieces, _ := info["pieces"].(string)
+	if len(pieces)%sha1.Size != 0 {
+		return errors.New("pieces length is not a multiple of 20")
+	}
+	mi.Pieces = len(pieces) / sha1.Size
+
+	private, _ := info["private"].(int64)
+	mi.Private = private == 1
+
+	if length, ok := info["length"].(int64); ok {
+		if length < 0 {
+			return errors.New("negative file length")
+		}
+		mi.Files = []File{{Path: mi.Name, Size: length}}
+		mi.Size = length
+		return mi.checkPieces()
+	}
+
+	files, ok := info["files"].([]any)
+	if !ok || len(files) == 0 {
+		return errors.New("info has neither length nor files")
+	}
+	for i, f := range files {
+		fd, ok := f.(map[string]any)
+		if !ok {
+			return fmt.Errorf("file %d is not a dictionary", i)
+		}
+		length, ok := fd["length"].(int64)
+		if !ok || length < 0 {
+			return fmt.Errorf("file %d has invalid length", i)
+		}
+		parts := strList(fd, "path.utf-8")
+		if len(parts) == 0 {
+			parts = strList(fd, "path")
+		}
+		if len(parts) == 0 {
+			return fmt.Errorf("file %d has no path", i)
+		}
+		for _, p := range parts {
+			// элементы пути не могут выходить за каталог раздачи
+			if p == "" || p == "." || p == ".." || strings.ContainsAny(p, "/\\") {
+				return fmt.Errorf("file %d has invalid path element %q", i, p)
+			}
+		}
+		mi.Files = append(mi.Files, File{Path: path.Join(parts...), Size: length})
+		mi.Size += length
+	}
+	return mi.checkPieces()
+}
+
+// checkPieces сверяет число кусков с общим размером раздачи
+func (mi *MetaInfo) checkPieces() error {
+	want := (mi.Size + mi.PieceLength - 1) / mi.PieceLength
+	if int64(mi.Pieces) != want {
+		return fmt.Errorf("%d pieces for %d bytes, want %d", mi.Pieces, mi.Size, want)
+	}
+	return nil
+}
+
+// Magnet возвращает magnet-ссылку с именем и трекерами раздачи
+func (mi *MetaInfo) Magnet() string {
+	q := url.Values{}
+	q.Set("dn", mi.Name)
+	q["tr"] = mi.Trackers
+	// xt не экранируется, так ссылку понимают все клиенты
+	return "magnet:?xt=urn:btih:" + mi.InfoHash + "&" + q.Encode()
+}
+
+func trackers(root map[string]any) []string {
+	var list []string
+	seen := map[string]bool{}
+	add := func(tr string) {
+		if tr != "" && !seen[tr] {
+			seen[tr] = true
+			list = append(list, tr)
+		}
+	}
+
+	add(str(root, "announce"))
+	tiers, _ := root["announce-list"].([]any)
+	for _, tier := range tiers {
+		urls, _ := tier.([]any)
+		for _, u := range urls {
+			s, _ := u.(string)
+			add(s)
+		}
+	}
+	return list
+}
+
+func str(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func strList(m map[string]any, key string) []string {
+	items, _ := m[key].([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil
+		}
+		list = append(list, s)
+	}
+	return list
+}
@@ -0,0 +1,122 @@
+package torrentfile
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+
+	"torrentServer/internal/lib/bencode"
+)
+
+func encode(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := bencode.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseMultiFile(t *testing.T) {
+	info := map[string]any{
+		"name":         "Show",
+		"piece length": int64(16),
+		"pieces":       strings.Repeat("x", 20*3),
+		"files": []any{
+			map[string]any{"length": int64(30), "path": []any{"S01", "e01.mkv"}},
+			map[string]any{"length": int64(10), "path": []any{"e01.srt"}},
+		},
+	}
+	data := encode(t, map[string]any{
+		"announce":      "http://a/announce",
+		"announce-list": []any{[]any{"http://a/announce", "http://b/announce"}, []any{"udp://c:80"}},
+		"comment":       "test",
+		"created by":    "torrentServer",
+		"creation date": int64(1700000000),
+		"info":          info,
+	})
+
+	mi, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// info-hash сверяем с реализацией anacrolix
+	ref, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ref.HashInfoBytes().HexString(); mi.InfoHash != want {
+		t.Errorf("InfoHash = %s, want %s", mi.InfoHash, want)
+	}
+
+	if mi.Name != "Show" || mi.Size != 40 || mi.PieceLength != 16 || mi.Pieces != 3 || mi.Private {
+		t.Errorf("unexpected info: %+v", mi)
+	}
+	if len(mi.Files) != 2 || mi.Files[0] != (File{Path: "S01/e01.mkv", Size: 30}) || mi.Files[1] != (File{Path: "e01.srt", Size: 10}) {
+		t.Errorf("Files = %+v", mi.Files)
+	}
+	if want := []string{"http://a/announce", "http://b/announce", "udp://c:80"}; strings.Join(mi.Trackers, " ") != strings.Join(want, " ") {
+		t.Errorf("Trackers = %v, want %v", mi.Trackers, want)
+	}
+	if mi.Comment != "test" || mi.CreatedBy != "torrentServer" || !mi.CreationDate.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected optional fields: %+v", mi)
+	}
+
+	m, err := metainfo.ParseMagnetUri(mi.Magnet())
+	if err != nil {
+		t.Fatalf("ParseMagnetUri() error = %v", err)
+	}
+	if m.InfoHash.HexString() != mi.InfoHash || m.DisplayName != "Show" || len(m.Trackers) != 3 {
+		t.Errorf("magnet = %+v", m)
+	}
+}
+
+func TestParseSingleFile(t *testing.T) {
+	data := encode(t, map[string]any{"info": map[string]any{
+		"name": "movie.mp4", "length": int64(20), "piece length": int64(16),
+		"pieces": strings.Repeat("x", 40), "private": int64(1),
+	}})
+
+	mi, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(mi.Files) != 1 || mi.Files[0] != (File{Path: "movie.mp4", Size: 20}) || !mi.Private || len(mi.Trackers) != 0 {
+		t.Errorf("unexpected info: %+v", mi)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	valid := func() map[string]any {
+		return map[string]any{"name": "a", "length": int64(20), "piece length": int64(16), "pieces": strings.Repeat("x", 40)}
+	}
+	tests := map[string]func(info map[string]any) any{
+		"no info": func(map[string]any) any { return map[string]any{"announce": "x"} },
+		"wrong pieces": func(info map[string]any) any {
+			info["pieces"] = strings.Repeat("x", 20)
+			return map[string]any{"info": info}
+		},
+		"no length":     func(info map[string]any) any { delete(info, "length"); return map[string]any{"info": info} },
+		"no name":       func(info map[string]any) any { delete(info, "name"); return map[string]any{"info": info} },
+		"info not dict": func(map[string]any) any { return map[string]any{"info": "x"} },
+		"path traversal": func(info map[string]any) any {
+			delete(info, "length")
+			info["files"] = []any{map[string]any{"length": int64(20), "path": []any{"..", "etc"}}}
+			return map[string]any{"info": info}
+		},
+	}
+	for name, build := range tests {
+		if _, err := Parse(encode(t, build(valid()))); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Parse() error = %v, want %v", name, err, ErrInvalid)
+		}
+	}
+
+	if _, err := Parse([]byte("not bencode")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Parse(garbage) error = %v, want %v", err, ErrInvalid)
+	}
+}
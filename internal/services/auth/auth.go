@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/storage"
+)
+
+var (
+	ErrNoKey          = errors.New("api key is required")
+	ErrInvalidKey     = errors.New("invalid api key")
+	ErrForbidden      = errors.New("api key is not allowed to access this endpoint")
+	ErrQuotaExceeded  = errors.New("daily quota exceeded")
+	ErrInvalidPolicy  = errors.New("policy must be full or safe")
+	ErrConfigKey      = errors.New("keys from config can not be revoked")
+	ErrEmptyName      = errors.New("name is required")
+	ErrEmptyEndpoints = errors.New("at least one endpoint is required")
+	ErrBadSignature   = errors.New("invalid url signature")
+	ErrExpiredURL     = errors.New("signed url has expired")
+)
+
+// Префикс идентификаторов ключей, заданных в конфиге
+const configKeyPrefix = "config:"
+
+// unmeteredPrefixes - пути, запросы к которым не расходуют суточную квоту: при воспроизведении
+// и перемотке плееры шлют к потоку десятки Range-запросов, квота ограничивает вызовы API
+var unmeteredPrefixes = []string{"/stream/"}
+
+// Параметры подписанной ссылки, см. Sign
+const (
+	ParamKeyID     = "key_id"
+	ParamExpires   = "expires"
+	ParamSignature = "signature"
+)
+
+type KeyStorage interface {
+	SaveAPIKey(key storage.APIKey) error
+	APIKeyByHash(hash string) (storage.APIKey, error)
+	APIKeyByID(id string) (storage.APIKey, error)
+	APIKeys() ([]storage.APIKey, error)
+	RevokeAPIKey(id string) error
+	IncrementAPIKeyUsage(keyID string, day time.Time) (int, error)
+	DecrementAPIKeyUsage(keyID string, day time.Time) error
+}
+
+// Service проверяет API-ключи и управляет ими.
+// Ключи из конфига действуют наравне с ключами из хранилища, но не могут быть отозваны.
+type Service struct {
+	keys       KeyStorage
+	configKeys map[string]storage.APIKey // по хэшу ключа
+
+	signingKey   []byte
+	signedURLTTL time.Duration
+}
+
+func New(cfg config.Auth, keys KeyStorage) (*Service, error) {
+	configKeys := make(map[string]storage.APIKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("config key %q: key is empty", k.Name)
+		}
+		policy, err := normalizePolicy(k.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("config key %q: %w", k.Name, err)
+		}
+		hash := HashKey(k.Key)
+		configKeys[hash] = storage.APIKey{
+			ID:         configKeyPrefix + k.Name,
+			Name:       k.Name,
+			KeyHash:    hash,
+			Endpoints:  k.Endpoints,
+			DailyQuota: k.DailyQuota,
+			Policy:     policy,
+		}
+	}
+
+	signingKey := []byte(cfg.SigningKey)
+	if len(signingKey) == 0 {
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Service{
+		keys:         keys,
+		configKeys:   configKeys,
+		signingKey:   signingKey,
+		signedURLTTL: cfg.SignedURLTTL,
+	}, nil
+}
+
+// Authenticate проверяет ключ, доступ к пути и суточную квоту.
+// Каждый успешный вызов расходует один запрос из квоты.
+func (s *Service) Authenticate(rawKey, path string) (storage.APIKey, error) {
+	if rawKey == "" {
+		return storage.APIKey{}, ErrNoKey
+	}
+
+	hash := HashKey(rawKey)
+	key, ok := s.configKeys[hash]
+	if !ok {
+		var err error
+		key, err = s.keys.APIKeyByHash(hash)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return storage.APIKey{}, ErrInvalidKey
+		}
+		if err != nil {
+			return storage.APIKey{}, err
+		}
+	}
+
+	return s.authorize(key, path)
+}
+
+// Sign подписывает путь (без версии API) от имени ключа. Запрос с полученными параметрами
+// проходит без самого ключа, пока не истечёт signed_url_ttl: так ссылки из плейлиста открываются
+// в плеерах, которые не умеют передавать заголовки. Права и квота ключа проверяются при каждом запросе.
+func (s *Service) Sign(key storage.APIKey, path string) url.Values {
+	expires := strconv.FormatInt(time.Now().Add(s.signedURLTTL).Unix(), 10)
+	return url.Values{
+		ParamKeyID:     {key.ID},
+		ParamExpires:   {expires},
+		ParamSignature: {s.signature(key.ID, expires, path)},
+	}
+}
+
+// AuthenticateSigned проверяет подписанную ссылку, а затем ключ, от имени которого она выдана, как Authenticate
+func (s *Service) AuthenticateSigned(params url.Values, path string) (storage.APIKey, error) {
+	keyID, expires := params.Get(ParamKeyID), params.Get(ParamExpires)
+	if !hmac.Equal([]byte(params.Get(ParamSignature)), []byte(s.signature(keyID, expires, path))) {
+		return storage.APIKey{}, ErrBadSignature
+	}
+	if unix, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > unix {
+		return storage.APIKey{}, ErrExpiredURL
+	}
+
+	key, err := s.keyByID(keyID)
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+
+	return s.authorize(key, path)
+}
+
+func (s *Service) signature(keyID, expires, path string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(keyID + "\n" + expires + "\n" + path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) keyByID(id string) (storage.APIKey, error) {
+	if strings.HasPrefix(id, configKeyPrefix) {
+		for _, k := range s.configKeys {
+			if k.ID == id {
+				return k, nil
+			}
+		}
+		// ключ убрали из конфига
+		return storage.APIKey{}, ErrInvalidKey
+	}
+
+	key, err := s.keys.APIKeyByID(id)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+	return key, err
+}
+
+// authorize проверяет, что ключ не отозван, имеет доступ к пути и не исчерпал суточную квоту
+// (если запрос к пути её расходует, см. Metered)
+func (s *Service) authorize(key storage.APIKey, path string) (storage.APIKey, error) {
+	if !key.RevokedAt.IsZero() {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+
+	if !Allowed(key, path) {
+		return key, ErrForbidden
+	}
+
+	if key.DailyQuota > 0 && Metered(path) {
+		used, err := s.keys.IncrementAPIKeyUsage(key.ID, time.Now())
+		if err != nil {
+			return storage.APIKey{}, err
+		}
+		if used > key.DailyQuota {
+			return key, ErrQuotaExceeded
+		}
+	}
+
+	return key, nil
+}
+
+// Refund возвращает в суточную квоту запрос, который authorize учёл, но который отклонили позже
+// (лимит частоты запросов): отклонённые запросы квоту не расходуют
+func (s *Service) Refund(key storage.APIKey, path string) error {
+	if key.DailyQuota <= 0 || !Metered(path) {
+		return nil
+	}
+	return s.keys.DecrementAPIKeyUsage(key.ID, time.Now())
+}
+
+// Issue выпускает новый ключ. Сам ключ возвращается только здесь, в хранилище попадает его хэш.
+func (s *Service) Issue(name string, endpoints []string, dailyQuota int, policy string) (storage.APIKey, string, error) {
+	if name == "" {
+		return storage.APIKey{}, "", ErrEmptyName
+	}
+	if len(endpoints) == 0 {
+		return storage.APIKey{}, "", ErrEmptyEndpoints
+	}
+	policy, err := normalizePolicy(policy)
+	if err != nil {
+		return storage.APIKey{}, "", err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return storage.APIKey{}, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return storage.APIKey{}, "", err
+	}
+	rawKey := "ts_" + secret
+
+	key := storage.APIKey{
+		ID:         id,
+		Name:       name,
+		KeyHash:    HashKey(rawKey),
+		Endpoints:  endpoints,
+		DailyQuota: max(dailyQuota, 0),
+		Policy:     policy,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.keys.SaveAPIKey(key); err != nil {
+		return storage.APIKey{}, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+// List возвращает ключи из конфига и из хранилища, включая отозванные
+func (s *Service) List() ([]storage.APIKey, error) {
+	stored, err := s.keys.APIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]storage.APIKey, 0, len(s.configKeys)+len(stored))
+	for _, k := range s.configKeys {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b storage.APIKey) int { return strings.Compare(a.ID, b.ID) })
+
+	return append(keys, stored...), nil
+}
+
+func (s *Service) Revoke(id string) error {
+	if strings.HasPrefix(id, configKeyPrefix) {
+		return ErrConfigKey
+	}
+	return s.keys.RevokeAPIKey(id)
+}
+
+// Metered сообщает, расходует ли запрос к пути (без версии API) суточную квоту ключа
+func Metered(path string) bool {
+	for _, p := range unmeteredPrefixes {
+		if strings.HasPrefix(path, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowed проверяет, разрешён ли ключу доступ к пути
+func Allowed(key storage.APIKey, path string) bool {
+	for _, e := range key.Endpoints {
+		if e == "*" {
+			return true
+		}
+		e = strings.TrimSuffix(e, "/")
+		if path == e || strings.HasPrefix(path, e+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func HashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizePolicy(policy string) (string, error) {
+	switch policy {
+	case "", storage.PolicyFull:
+		return storage.PolicyFull, nil
+	case storage.PolicySafe:
+		return storage.PolicySafe, nil
+	default:
+		return "", ErrInvalidPolicy
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
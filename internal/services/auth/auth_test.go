@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/storage"
+	"torrentServer/internal/storage/sqlite"
+)
+
+func TestAuthenticate(t *testing.T) {
+	st, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error: %v", err)
+	}
+	defer st.Close()
+
+	s, err := New(config.Auth{Keys: []config.APIKey{
+		{Name: "admin", Key: "secret", Endpoints: []string{"*"}},
+	}}, st)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	key, rawKey, err := s.Issue("frontend", []string{"/search"}, 2, storage.PolicySafe)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		path string
+		want error
+	}{
+		{"", "/search", ErrNoKey},
+		{"unknown", "/search", ErrInvalidKey},
+		{"secret", "/admin/keys", nil},
+		{rawKey, "/search", nil},
+		{rawKey, "/searchx", ErrForbidden},
+		{rawKey, "/admin/keys", ErrForbidden},
+		{rawKey, "/search", nil},
+		{rawKey, "/search", ErrQuotaExceeded},
+	}
+	for _, test := range tests {
+		got, err := s.Authenticate(test.key, test.path)
+		if !errors.Is(err, test.want) {
+			t.Errorf("Authenticate(%q, %q) error = %v, want %v", test.key, test.path, err, test.want)
+		}
+		if err == nil && test.key == rawKey && got.Policy != storage.PolicySafe {
+			t.Errorf("Authenticate(%q, %q).Policy = %q, want %q", test.key, test.path, got.Policy, storage.PolicySafe)
+		}
+	}
+
+	if err := s.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if _, err := s.Authenticate(rawKey, "/search"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Authenticate() with revoked key error = %v, want %v", err, ErrInvalidKey)
+	}
+	if err := s.Revoke("config:admin"); !errors.Is(err, ErrConfigKey) {
+		t.Errorf("Revoke(config:admin) error = %v, want %v", err, ErrConfigKey)
+	}
+}
+
+func TestStreamWithoutQuota(t *testing.T) {
+	st, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error: %v", err)
+	}
+	defer st.Close()
+
+	s, err := New(config.Auth{}, st)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	_, rawKey, err := s.Issue("player", []string{"/search", "/stream"}, 1, storage.PolicyFull)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	// Range-запросы плеера не расходуют квоту
+	for i := 0; i < 5; i++ {
+		if _, err := s.Authenticate(rawKey, "/stream/abc/0"); err != nil {
+			t.Fatalf("Authenticate(/stream) #%d error = %v", i, err)
+		}
+	}
+	if _, err := s.Authenticate(rawKey, "/search"); err != nil {
+		t.Errorf("Authenticate(/search) error = %v", err)
+	}
+	if _, err := s.Authenticate(rawKey, "/search"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("second Authenticate(/search) error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	// исчерпанная квота не мешает досмотреть
+	if _, err := s.Authenticate(rawKey, "/stream/abc/0"); err != nil {
+		t.Errorf("Authenticate(/stream) after quota error = %v", err)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	st, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error: %v", err)
+	}
+	defer st.Close()
+
+	s, err := New(config.Auth{}, st)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	key, rawKey, err := s.Issue("client", []string{"/search"}, 1, storage.PolicyFull)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	// запрос, отклонённый после авторизации, возвращается в квоту
+	if _, err := s.Authenticate(rawKey, "/search"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if err := s.Refund(key, "/search"); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if _, err := s.Authenticate(rawKey, "/search"); err != nil {
+		t.Errorf("Authenticate() after refund error = %v", err)
+	}
+	if _, err := s.Authenticate(rawKey, "/search"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("third Authenticate() error = %v, want %v", err, ErrQuotaExceeded)
+	}
+}
+
+func TestSign(t *testing.T) {
+	st, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error: %v", err)
+	}
+	defer st.Close()
+
+	cfg := config.Auth{
+		Keys:         []config.APIKey{{Name: "admin", Key: "secret", Endpoints: []string{"*"}}},
+		SigningKey:   "signing-secret",
+		SignedURLTTL: time.Hour,
+	}
+	s, err := New(cfg, st)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	player, _, err := s.Issue("player", []string{"/stream", "/playlist"}, 0, storage.PolicyFull)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+	admin, err := s.Authenticate("secret", "/")
+	if err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+
+	params := s.Sign(player, "/stream/abc/1")
+	if key, err := s.AuthenticateSigned(params, "/stream/abc/1"); err != nil || key.ID != player.ID {
+		t.Errorf("AuthenticateSigned() = %q, %v, want key %q", key.ID, err, player.ID)
+	}
+	if _, err := s.AuthenticateSigned(params, "/stream/abc/2"); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("AuthenticateSigned() for another path error = %v, want %v", err, ErrBadSignature)
+	}
+	if _, err := s.AuthenticateSigned(s.Sign(admin, "/admin/keys"), "/admin/keys"); err != nil {
+		t.Errorf("AuthenticateSigned() with config key error = %v", err)
+	}
+
+	// срок действия входит в подпись, продлить ссылку нельзя
+	forged := url.Values{}
+	for k, v := range params {
+		forged[k] = v
+	}
+	forged.Set(ParamExpires, strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10))
+	if _, err := s.AuthenticateSigned(forged, "/stream/abc/1"); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("AuthenticateSigned() with changed expiry error = %v, want %v", err, ErrBadSignature)
+	}
+
+	// подпись другим секретом (например, до перезапуска без signing_key) недействительна
+	other, _ := New(config.Auth{SigningKey: "other", SignedURLTTL: time.Hour}, st)
+	if _, err := other.AuthenticateSigned(params, "/stream/abc/1"); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("AuthenticateSigned() with another secret error = %v, want %v", err, ErrBadSignature)
+	}
+
+	expired := &Service{keys: st, signingKey: []byte("signing-secret"), signedURLTTL: -time.Minute}
+	if _, err := s.AuthenticateSigned(expired.Sign(player, "/stream/abc/1"), "/stream/abc/1"); !errors.Is(err, ErrExpiredURL) {
+		t.Errorf("AuthenticateSigned() with expired url error = %v, want %v", err, ErrExpiredURL)
+	}
+
+	// права ключа проверяются и для подписанной ссылки
+	if _, err := s.AuthenticateSigned(s.Sign(player, "/admin/keys"), "/admin/keys"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AuthenticateSigned() outside of key endpoints error = %v, want %v", err, ErrForbidden)
+	}
+	if err := s.Revoke(player.ID); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if _, err := s.AuthenticateSigned(params, "/stream/abc/1"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("AuthenticateSigned() with revoked key error = %v, want %v", err, ErrInvalidKey)
+	}
+}
@@ -0,0 +1,238 @@
+// Package bandwidth - ограничения скорости движка: постоянные из конфига и расписания,
+// которые в заданные часы заменяют ограничения клиента, например днём в рабочие дни.
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/services/engine"
+)
+
+// как часто проверять расписания, точность расписаний - минута
+const checkInterval = time.Minute
+
+var (
+	ErrNegativeLimit   = errors.New("bandwidth limits must not be negative")
+	ErrInvalidSchedule = errors.New("invalid bandwidth schedule")
+)
+
+// Engine - встроенный клиент (engine.Engine)
+type Engine interface {
+	SetLimits(l engine.Limits)
+}
+
+// Status - настроенные ограничения и то, что действует сейчас
+type Status struct {
+	Config   config.Bandwidth
+	Schedule string // имя действующего расписания, пусто - действуют постоянные ограничения
+	Limits   engine.Limits
+}
+
+// Manager применяет ограничения к движку и раз в минуту переключает расписания
+type Manager struct {
+	log    *slog.Logger
+	engine Engine
+	now    func() time.Time
+
+	mu        sync.Mutex
+	cfg       config.Bandwidth
+	schedules []schedule
+	status    Status
+	applied   bool
+}
+
+type schedule struct {
+	config.BandwidthSchedule
+	days     [7]bool // по time.Weekday
+	from, to int     // минуты от полуночи
+}
+
+func New(log *slog.Logger, cfg config.Bandwidth, engine Engine) (*Manager, error) {
+	schedules, err := parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		log:       log.With(slog.String("component", "services/bandwidth")),
+		engine:    engine,
+		now:       time.Now,
+		cfg:       cfg,
+		schedules: schedules,
+	}, nil
+}
+
+// Run применяет ограничения сразу и затем проверяет расписания раз в минуту, пока не отменён ctx
+func (m *Manager) Run(ctx context.Context) {
+	m.log.Info("bandwidth limits started", slog.Int("schedules", len(m.cfg.Schedules)))
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		m.Apply()
+
+		select {
+		case <-ctx.Done():
+			m.log.Info("bandwidth limits stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply выбирает ограничения по текущему времени и передаёт их движку, если они изменились
+func (m *Manager) Apply() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.current(m.now())
+	if m.applied && status.Limits == m.status.Limits && status.Schedule == m.status.Schedule {
+		m.status = status
+		return status
+	}
+
+	m.engine.SetLimits(status.Limits)
+	if !m.applied || status.Schedule != m.status.Schedule {
+		m.log.Info("bandwidth limits applied",
+			slog.String("schedule", status.Schedule),
+			slog.Int64("download", status.Limits.Download),
+			slog.Int64("upload", status.Limits.Upload),
+		)
+	}
+	m.status, m.applied = status, true
+
+	return status
+}
+
+// Status возвращает последние применённые ограничения
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Update заменяет ограничения и расписания до перезапуска сервера и сразу применяет их
+func (m *Manager) Update(cfg config.Bandwidth) (Status, error) {
+	schedules, err := parse(cfg)
+	if err != nil {
+		return Status{}, err
+	}
+
+	m.mu.Lock()
+	m.cfg, m.schedules = cfg, schedules
+	m.mu.Unlock()
+
+	return m.Apply(), nil
+}
+
+// current - ограничения в момент now: первое подходящее расписание заменяет ограничения клиента,
+// ограничения раздач действуют всегда. Вызывается под m.mu.
+func (m *Manager) current(now time.Time) Status {
+	status := Status{
+		Config: m.cfg,
+		Limits: engine.Limits{
+			Download:           kbps(m.cfg.DownloadKBps),
+			Upload:             kbps(m.cfg.UploadKBps),
+			PeerDownload:       kbps(m.cfg.PeerDownloadKBps),
+			PeerUpload:         kbps(m.cfg.PeerUploadKBps),
+			TorrentDownload:    kbps(m.cfg.TorrentDownloadKBps),
+			TorrentUpload:      kbps(m.cfg.TorrentUploadKBps),
+			BackgroundDownload: kbps(m.cfg.BackgroundDownloadKBps),
+		},
+	}
+	for _, s := range m.schedules {
+		if s.active(now) {
+			status.Schedule = s.Name
+			status.Limits.Download = kbps(s.DownloadKBps)
+			status.Limits.Upload = kbps(s.UploadKBps)
+			break
+		}
+	}
+	return status
+}
+
+// active сообщает, попадает ли t в расписание. Интервал через полночь относится к дню, в который начался.
+func (s schedule) active(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	prev := (day + 6) % 7
+
+	switch {
+	case s.from == s.to:
+		return s.days[day]
+	case s.from < s.to:
+		return s.days[day] && m >= s.from && m < s.to
+	default:
+		return s.days[day] && m >= s.from || s.days[prev] && m < s.to
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parse(cfg config.Bandwidth) ([]schedule, error) {
+	for _, v := range []int{
+		cfg.DownloadKBps, cfg.UploadKBps, cfg.PeerDownloadKBps, cfg.PeerUploadKBps,
+		cfg.TorrentDownloadKBps, cfg.TorrentUploadKBps, cfg.BackgroundDownloadKBps,
+	} {
+		if v < 0 {
+			return nil, ErrNegativeLimit
+		}
+	}
+
+	schedules := make([]schedule, 0, len(cfg.Schedules))
+	for i, c := range cfg.Schedules {
+		if c.DownloadKBps < 0 || c.UploadKBps < 0 {
+			return nil, ErrNegativeLimit
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("schedule %d", i+1)
+		}
+
+		s := schedule{BandwidthSchedule: c}
+		var err error
+		if s.from, err = parseClock(c.From); err != nil {
+			return nil, fmt.Errorf("%w %q: from: %v", ErrInvalidSchedule, c.Name, err)
+		}
+		if s.to, err = parseClock(c.To); err != nil {
+			return nil, fmt.Errorf("%w %q: to: %v", ErrInvalidSchedule, c.Name, err)
+		}
+
+		if len(c.Days) == 0 {
+			s.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, d := range c.Days {
+			wd, ok := weekdays[d]
+			if !ok {
+				return nil, fmt.Errorf("%w %q: unknown day %q", ErrInvalidSchedule, c.Name, d)
+			}
+			s.days[wd] = true
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+// parseClock разбирает время "15:04" в минуты от полуночи
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("want HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func kbps(v int) int64 {
+	return int64(v) << 10
+}
@@ -0,0 +1,114 @@
+package bandwidth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+)
+
+type mockEngine struct {
+	limits []engine.Limits
+}
+
+func (e *mockEngine) SetLimits(l engine.Limits) {
+	e.limits = append(e.limits, l)
+}
+
+func TestSchedules(t *testing.T) {
+	cfg := config.Bandwidth{
+		DownloadKBps:           0,
+		UploadKBps:             1024,
+		TorrentUploadKBps:      100,
+		BackgroundDownloadKBps: 256,
+		Schedules: []config.BandwidthSchedule{
+			{Name: "office", Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "09:00", To: "18:00", DownloadKBps: 2048, UploadKBps: 128},
+			// пятничная ночь продолжается в субботу
+			{Name: "night", Days: []string{"fri"}, From: "23:00", To: "07:00", DownloadKBps: 0, UploadKBps: 0},
+			{Name: "weekend", Days: []string{"sat", "sun"}, From: "00:00", To: "00:00", DownloadKBps: 4096, UploadKBps: 512},
+		},
+	}
+	e := &mockEngine{}
+	m, err := New(slogdiscard.NewDiscardLogger(), cfg, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// 2026-10-19 - понедельник
+	tests := []struct {
+		at       string
+		schedule string
+		download int64
+		upload   int64
+	}{
+		{"2026-10-19 08:59", "", 0, 1024 << 10},
+		{"2026-10-19 09:00", "office", 2048 << 10, 128 << 10},
+		{"2026-10-19 17:59", "office", 2048 << 10, 128 << 10},
+		{"2026-10-19 18:00", "", 0, 1024 << 10},
+		{"2026-10-23 23:30", "night", 0, 0},
+		{"2026-10-24 06:59", "night", 0, 0}, // первое подходящее расписание выигрывает
+		{"2026-10-24 07:00", "weekend", 4096 << 10, 512 << 10},
+		{"2026-10-25 23:59", "weekend", 4096 << 10, 512 << 10},
+		{"2026-10-20 03:00", "", 0, 1024 << 10}, // ночь только с пятницы
+	}
+	for _, tt := range tests {
+		at, err := time.ParseInLocation("2006-01-02 15:04", tt.at, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.now = func() time.Time { return at }
+
+		s := m.Apply()
+		if s.Schedule != tt.schedule || s.Limits.Download != tt.download || s.Limits.Upload != tt.upload {
+			t.Errorf("%s: Apply() = %q %d/%d, want %q %d/%d", tt.at,
+				s.Schedule, s.Limits.Download, s.Limits.Upload, tt.schedule, tt.download, tt.upload)
+		}
+		// ограничения раздач от расписания не зависят
+		if s.Limits.TorrentUpload != 100<<10 || s.Limits.BackgroundDownload != 256<<10 {
+			t.Errorf("%s: torrent limits = %+v", tt.at, s.Limits)
+		}
+	}
+
+	// движку передаются только изменения
+	if len(e.limits) != 6 {
+		t.Errorf("SetLimits called %d times, want 6", len(e.limits))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	e := &mockEngine{}
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Bandwidth{}, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m.Apply()
+
+	s, err := m.Update(config.Bandwidth{DownloadKBps: 10})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if s.Limits.Download != 10<<10 || e.limits[len(e.limits)-1].Download != 10<<10 {
+		t.Errorf("Update() limits = %+v, engine = %+v", s.Limits, e.limits)
+	}
+
+	invalid := []struct {
+		cfg  config.Bandwidth
+		want error
+	}{
+		{config.Bandwidth{UploadKBps: -1}, ErrNegativeLimit},
+		{config.Bandwidth{Schedules: []config.BandwidthSchedule{{From: "9", To: "18:00"}}}, ErrInvalidSchedule},
+		{config.Bandwidth{Schedules: []config.BandwidthSchedule{{Days: []string{"monday"}, From: "09:00", To: "18:00"}}}, ErrInvalidSchedule},
+	}
+	for _, tt := range invalid {
+		if _, err := m.Update(tt.cfg); !errors.Is(err, tt.want) {
+			t.Errorf("Update(%+v) error = %v, want %v", tt.cfg, err, tt.want)
+		}
+	}
+	// неверная конфигурация не заменяет действующую
+	if got := m.Status().Limits.Download; got != 10<<10 {
+		t.Errorf("download after invalid updates = %d, want %d", got, 10<<10)
+	}
+}
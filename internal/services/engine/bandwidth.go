@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minBurst - наименьший размер пачки для ограничителей клиента: anacrolix/torrent резервирует
+// сразу целый блок (16 КБ) и падает, если пачка меньше блока
+const minBurst = 64 << 10
+
+// Limits - ограничения скорости в байтах в секунду, 0 - без ограничения.
+// Download и Upload действуют на клиент целиком, Peer* - на каждое соединение с пиром,
+// остальные - на каждую раздачу.
+type Limits struct {
+	Download int64
+	Upload   int64
+	// средняя скорость раздачи, см. throttle; раздачи с открытыми потоками по загрузке не ограничиваются
+	TorrentDownload int64
+	TorrentUpload   int64
+	// загрузка раздач без потоков, пока открыт хотя бы один поток: остальная полоса достаётся потокам
+	BackgroundDownload int64
+	// на каждое соединение с пиром, см. limitedConn
+	PeerDownload int64
+	PeerUpload   int64
+}
+
+// SetLimits меняет ограничения скорости на ходу
+func (e *Engine) SetLimits(l Limits) {
+	setRate(e.downloadLimiter, l.Download)
+	setRate(e.uploadLimiter, l.Upload)
+	e.peerLimits.set(l.PeerDownload, l.PeerUpload)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.limits = l
+	now, streaming := time.Now(), e.streaming()
+	for _, en := range e.torrents {
+		e.throttle(en, now, streaming)
+	}
+}
+
+// Limits возвращает действующие ограничения скорости
+func (e *Engine) Limits() Limits {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.limits
+}
+
+func setRate(l *rate.Limiter, bytesPerSec int64) {
+	if bytesPerSec <= 0 {
+		l.SetLimit(rate.Inf)
+		return
+	}
+	l.SetBurst(max(int(bytesPerSec), minBurst))
+	l.SetLimit(rate.Limit(bytesPerSec))
+}
+
+// bucket - token bucket раздачи с ёмкостью в одну секунду трафика
+type bucket struct {
+	tokens  float64
+	total   int64 // счётчик байт на прошлой проверке
+	limited bool
+	started bool
+}
+
+// update списывает переданное с прошлой проверки и сообщает, изменилось ли limited
+func (b *bucket) update(limit, total int64, dt float64) bool {
+	if !b.started {
+		// первая проверка только запоминает счётчик, переданное раньше не учитывается
+		b.started, b.total, b.tokens = true, total, float64(limit)
+		return false
+	}
+	used := total - b.total
+	b.total = total
+
+	was := b.limited
+	if limit <= 0 {
+		b.tokens, b.limited = 0, false
+		return was
+	}
+	b.tokens = min(b.tokens+float64(limit)*dt, float64(limit)) - float64(used)
+	b.limited = b.tokens < 0
+	return b.limited != was
+}
+
+// throttle ограничивает скорость раздачи. anacrolix/torrent умеет ограничивать только клиент
+// целиком, поэтому раздача, исчерпавшая свой bucket, перестаёт качать или отдавать до следующей
+// проверки: скорость держится около ограничения в среднем, с точностью до stats_interval.
+// streaming - открыт ли хоть один поток в любой раздаче. Вызывается под e.mu.
+func (e *Engine) throttle(en *entry, now time.Time, streaming bool) {
+	var dt float64
+	if !en.throttledAt.IsZero() {
+		dt = now.Sub(en.throttledAt).Seconds()
+	}
+	en.throttledAt = now
+
+	down := e.limits.TorrentDownload
+	if en.picker != nil && en.picker.count() > 0 {
+		down = 0
+	} else if streaming && e.limits.BackgroundDownload > 0 && (down == 0 || e.limits.BackgroundDownload < down) {
+		down = e.limits.BackgroundDownload
+	}
+
+	stats := en.t.Stats()
+	downChanged := en.down.update(down, stats.BytesReadUsefulData.Int64(), dt)
+	upChanged := en.up.update(e.limits.TorrentUpload, stats.BytesWrittenData.Int64(), dt)
+	if en.paused {
+		return
+	}
+	switch {
+	case !downChanged:
+	case en.down.limited:
+		en.t.DisallowDataDownload()
+	default:
+		en.t.AllowDataDownload()
+	}
+	switch {
+	case !upChanged:
+	case en.up.limited:
+		en.t.DisallowDataUpload()
+	default:
+		en.t.AllowDataUpload()
+	}
+}
+
+// streaming сообщает, открыт ли хотя бы один поток, вызывается под e.mu
+func (e *Engine) streaming() bool {
+	for _, en := range e.torrents {
+		if en.picker != nil && en.picker.count() > 0 {
+			return true
+		}
+	}
+	return false
+}
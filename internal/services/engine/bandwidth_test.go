@@ -0,0 +1,32 @@
+package engine
+
+import "testing"
+
+func TestBucket(t *testing.T) {
+	const limit = 100
+
+	var b bucket
+	steps := []struct {
+		total   int64 // счётчик байт раздачи
+		limited bool
+		changed bool
+	}{
+		{1000, false, false}, // переданное до первой проверки не учитывается
+		{1050, false, false}, // в пределах ограничения
+		{1300, true, true},   // за секунду передано 250 при ограничении 100
+		{1300, true, false},  // раздача остановлена, долг ещё не погашен
+		{1300, false, true},  // долг погашен, раздача снова качает
+		{1350, false, false},
+	}
+	for i, s := range steps {
+		if changed := b.update(limit, s.total, 1); changed != s.changed || b.limited != s.limited {
+			t.Errorf("step %d: limited = %v, changed = %v, want %v, %v", i, b.limited, changed, s.limited, s.changed)
+		}
+	}
+
+	// снятое ограничение сразу отпускает раздачу
+	b.update(limit, 2000, 1)
+	if changed := b.update(0, 2000, 1); !changed || b.limited {
+		t.Errorf("without limit: limited = %v, changed = %v", b.limited, changed)
+	}
+}
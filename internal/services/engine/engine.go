@@ -0,0 +1,976 @@
+// Package engine - встроенный BitTorrent-клиент: добавление раздач по magnet-ссылке
+// или info-hash, запуск, пауза и удаление. Состояние раздач хранится в SessionStore
+// и восстанавливается при запуске сервера.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/storage"
+)
+
+var (
+	ErrNotFound      = errors.New("torrent is not added to the engine")
+	ErrInvalidSource = errors.New("magnet link or info-hash is required")
+	ErrFileNotFound  = errors.New("file not found in torrent")
+	ErrPaused        = errors.New("torrent is paused")
+	ErrNoMetadata    = errors.New("torrent metadata is not received from peers yet")
+	ErrStreaming     = errors.New("torrent has open streams")
+
+	ErrInvalidStatsInterval = errors.New("engine stats_interval must be positive")
+)
+
+// SessionStore хранит раздачи движка между перезапусками
+type SessionStore interface {
+	SaveSession(session storage.Session) error
+	Sessions() ([]storage.Session, error)
+	DeleteSession(infoHash string) error
+	SetSessionWatched(infoHash string, at time.Time) error
+	SaveSessionStats(infoHash string, uploaded, downloaded int64, seedingTime time.Duration) error
+}
+
+// MetaInfoCache хранит метаданные раздач по info-hash, в том числе тех, что не добавлены в движок
+type MetaInfoCache interface {
+	SaveMetaInfo(infoHash string, metainfo []byte) error
+	MetaInfo(infoHash string) ([]byte, error)
+}
+
+// TorrentIndex - раздачи из выдачи поиска, из них берутся требования трекера к раздаче
+type TorrentIndex interface {
+	Torrent(infoHash string) (storage.Torrent, error)
+}
+
+type Store interface {
+	SessionStore
+	MetaInfoCache
+	TorrentIndex
+}
+
+// Состояния раздачи
+const (
+	StateMetadata    = "metadata" // метаданные (список файлов) ещё не получены от пиров
+	StateDownloading = "downloading"
+	StateSeeding     = "seeding"
+	StatePaused      = "paused"
+)
+
+// Status - состояние раздачи в движке
+type Status struct {
+	InfoHash       string
+	Name           string
+	State          string
+	Size           int64 // 0, пока нет метаданных
+	BytesCompleted int64
+	Peers          int
+	Seeders        int
+	Downloaded     int64 // полезные данные, полученные от пиров за все запуски
+	Uploaded       int64
+	Ratio          float64       // Uploaded к Downloaded, см. ratio
+	SeedingTime    time.Duration // сколько раздача была скачана целиком и не на паузе
+	// требования трекера из выдачи поиска, 0 - требования нет
+	MinimumRatio    float64
+	MinimumSeedTime time.Duration
+	Files           []File
+	Streams         []Stream // открытые потоки, см. OpenFile
+	AddedAt         time.Time
+}
+
+// Stream - открытый FileReader и состояние его буфера
+type Stream struct {
+	FileIndex int
+	Path      string
+	Position  int64 // позиция чтения в файле
+	BytesRead int64
+	Readahead int64   // размер окна упреждающей загрузки
+	Buffered  int64   // сколько байт подряд от позиции уже скачано, не больше окна
+	Health    float64 // Buffered / окно до конца файла, 1 - окно заполнено
+	OpenedAt  time.Time
+}
+
+type File struct {
+	Index          int
+	Path           string
+	Size           int64
+	BytesCompleted int64
+	Kind           string // KindVideo, KindAudio, KindSubtitle или KindOther
+}
+
+// Metadata - содержимое раздачи из словаря info (.torrent)
+type Metadata struct {
+	InfoHash    string
+	Name        string
+	Size        int64
+	PieceLength int64
+	Pieces      int
+	Files       []File // BytesCompleted не заполняется
+}
+
+type Engine struct {
+	log     *slog.Logger
+	client  *torrent.Client
+	store   Store
+	dataDir string
+
+	metadataTimeout time.Duration
+	readahead       int64
+	headTail        int64
+
+	// ограничители скорости клиента целиком, см. SetLimits
+	downloadLimiter *rate.Limiter
+	uploadLimiter   *rate.Limiter
+	peerLimits      *peerLimits
+	sockets         *peerSockets
+
+	mu       sync.Mutex
+	torrents map[string]*entry // по info-hash в нижнем регистре
+	limits   Limits
+
+	resolving singleflight.Group
+
+	events    *bus
+	done      chan struct{} // закрывается в Close
+	closeOnce sync.Once
+}
+
+type entry struct {
+	t       *torrent.Torrent
+	magnet  string
+	paused  bool
+	addedAt time.Time
+	picker  *picker // создаётся при первом OpenFile, когда известны метаданные
+
+	watchedAt time.Time // последнее открытие или закрытие потока
+
+	// требования трекера и счётчики для политики раздачи, см. seeding.go
+	minRatio       float64
+	minSeedTime    time.Duration
+	baseUploaded   int64 // отдано и скачано за прошлые запуски
+	baseDownloaded int64
+	seedingTime    time.Duration
+	accountedAt    time.Time
+
+	// ограничение скорости раздачи, см. bandwidth.go
+	down, up    bucket
+	throttledAt time.Time
+
+	// для событий: последнее отправленное состояние и выборка для расчёта скоростей
+	lastState      string
+	lastStats      Stats
+	sampledAt      time.Time
+	sampledRead    int64
+	sampledWritten int64
+	downloadRate   int64
+	uploadRate     int64
+}
+
+func New(log *slog.Logger, cfg config.Engine, store Store) (*Engine, error) {
+	const op = "engine.New"
+
+	log = log.With(slog.String("component", "engine"))
+
+	if cfg.StatsInterval <= 0 {
+		return nil, fmt.Errorf("%s: %w, got %s", op, ErrInvalidStatsInterval, cfg.StatsInterval)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	clientCfg := torrent.NewDefaultClientConfig()
+	clientCfg.DataDir = cfg.DataDir
+	clientCfg.ListenPort = cfg.ListenPort
+	// сокеты для пиров открывает listenPeers, чтобы ограничивать скорость каждого соединения
+	clientCfg.NoDHT = true
+	clientCfg.DisableTCP = true
+	clientCfg.DisableUTP = true
+	clientCfg.Seed = cfg.Seed
+	// сервер работает в docker или за NAT, порт пробрасывается явно
+	clientCfg.NoDefaultPortForwarding = true
+	clientCfg.Logger = newClientLogger(log)
+	downloadLimiter, uploadLimiter := rate.NewLimiter(rate.Inf, minBurst), rate.NewLimiter(rate.Inf, minBurst)
+	clientCfg.DownloadRateLimiter = downloadLimiter
+	clientCfg.UploadRateLimiter = uploadLimiter
+
+	client, err := torrent.NewClient(clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	peerLimits := &peerLimits{}
+	sockets, err := listenPeers(client, clientCfg, cfg.NoDHT, peerLimits)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	e := &Engine{
+		log:      log,
+		client:   client,
+		store:    store,
+		dataDir:  cfg.DataDir,
+		torrents: make(map[string]*entry),
+		events:   newBus(),
+		done:     make(chan struct{}),
+
+		downloadLimiter: downloadLimiter,
+		uploadLimiter:   uploadLimiter,
+		peerLimits:      peerLimits,
+		sockets:         sockets,
+
+		metadataTimeout: cfg.MetadataTimeout,
+		readahead:       int64(cfg.ReadaheadMB) << 20,
+		headTail:        int64(cfg.HeadTailMB) << 20,
+	}
+	if err := e.restore(); err != nil {
+		client.Close()
+		sockets.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	go e.runEvents(cfg.StatsInterval)
+
+	log.Info("engine started", slog.String("data_dir", cfg.DataDir), slog.Int("torrents", len(e.torrents)))
+
+	return e, nil
+}
+
+// Add добавляет раздачу по magnet-ссылке или info-hash (40 hex-символов).
+// Если раздача уже добавлена, возвращается её текущее состояние.
+func (e *Engine) Add(source string, paused bool) (Status, error) {
+	spec, magnet, err := parseSource(source)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return e.addNew(spec, magnet, nil, paused)
+}
+
+// AddMetaInfo добавляет раздачу из содержимого .torrent файла, метаданные у пиров не запрашиваются.
+// Если раздача уже добавлена, возвращается её текущее состояние.
+func (e *Engine) AddMetaInfo(data []byte, paused bool) (Status, error) {
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	spec, err := torrent.TorrentSpecFromMetaInfoErr(mi)
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	magnet := mi.Magnet(nil, &info)
+
+	return e.addNew(spec, magnet.String(), data, paused)
+}
+
+// addNew сохраняет сессию и передаёт раздачу клиенту. metaInfo - содержимое .torrent, если оно известно.
+func (e *Engine) addNew(spec *torrent.TorrentSpec, magnet string, metaInfo []byte, paused bool) (Status, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hash := spec.InfoHash.HexString()
+	if en, ok := e.torrents[hash]; ok {
+		return e.status(en), nil
+	}
+	if spec.InfoBytes == nil {
+		spec = e.withCachedInfo(spec)
+	}
+
+	en := &entry{magnet: magnet, paused: paused, addedAt: time.Now()}
+	// раздача из выдачи поиска: требования трекера сохраняются вместе с сессией
+	if t, err := e.store.Torrent(hash); err == nil {
+		en.minRatio, en.minSeedTime = t.MinimumRatio, t.MinimumSeedTime
+	}
+	if err := e.store.SaveSession(storage.Session{
+		InfoHash:        hash,
+		Magnet:          magnet,
+		Name:            spec.DisplayName,
+		MetaInfo:        metaInfo,
+		Paused:          paused,
+		AddedAt:         en.addedAt,
+		MinimumRatio:    en.minRatio,
+		MinimumSeedTime: en.minSeedTime,
+	}); err != nil {
+		return Status{}, err
+	}
+
+	if err := e.add(en, spec); err != nil {
+		e.store.DeleteSession(hash)
+		return Status{}, err
+	}
+
+	e.log.Info("torrent added", slog.String("info_hash", hash), slog.Bool("paused", paused),
+		slog.Float64("minimum_ratio", en.minRatio), slog.Duration("minimum_seed_time", en.minSeedTime))
+	e.publishState(en)
+
+	return e.status(en), nil
+}
+
+// Start возобновляет загрузку и раздачу
+func (e *Engine) Start(infoHash string) (Status, error) {
+	return e.setPaused(infoHash, false)
+}
+
+// Pause останавливает загрузку и раздачу, соединения с пирами и скачанные данные сохраняются
+func (e *Engine) Pause(infoHash string) (Status, error) {
+	return e.setPaused(infoHash, true)
+}
+
+// Remove удаляет раздачу из движка, с deleteData - вместе со скачанными файлами
+func (e *Engine) Remove(infoHash string, deleteData bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hash := strings.ToLower(infoHash)
+	en, ok := e.torrents[hash]
+	if !ok {
+		return ErrNotFound
+	}
+
+	return e.remove(hash, en, deleteData)
+}
+
+// Evict удаляет раздачу вместе с данными, если у неё нет открытых потоков, иначе ErrStreaming.
+// Проверка и удаление выполняются под e.mu, поэтому поток не может открыться между ними.
+func (e *Engine) Evict(infoHash string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hash := strings.ToLower(infoHash)
+	en, ok := e.torrents[hash]
+	if !ok {
+		return ErrNotFound
+	}
+	if en.picker != nil && en.picker.count() > 0 {
+		return ErrStreaming
+	}
+
+	return e.remove(hash, en, true)
+}
+
+// remove останавливает раздачу и удаляет её сессию, вызывается под e.mu
+func (e *Engine) remove(hash string, en *entry, deleteData bool) error {
+	var name string
+	if info := en.t.Info(); info != nil {
+		name = info.BestName()
+	}
+
+	en.t.Drop()
+	delete(e.torrents, hash)
+	e.events.publish(Event{Type: EventRemoved, InfoHash: hash, Time: time.Now()})
+
+	if err := e.store.DeleteSession(hash); err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		return err
+	}
+
+	if deleteData && name != "" {
+		path := filepath.Join(e.dataDir, name)
+		// имя берётся из метаданных раздачи, за пределы каталога данных выходить нельзя
+		if rel, err := filepath.Rel(e.dataDir, path); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("refusing to delete %q outside of data dir", path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	e.log.Info("torrent removed", slog.String("info_hash", hash), slog.Bool("delete_data", deleteData))
+
+	return nil
+}
+
+func (e *Engine) Get(infoHash string) (Status, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	en, ok := e.torrents[strings.ToLower(infoHash)]
+	if !ok {
+		return Status{}, ErrNotFound
+	}
+	return e.status(en), nil
+}
+
+// List возвращает все раздачи в порядке добавления
+func (e *Engine) List() []Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]Status, 0, len(e.torrents))
+	for _, en := range e.torrents {
+		list = append(list, e.status(en))
+	}
+	slices.SortFunc(list, func(a, b Status) int { return a.AddedAt.Compare(b.AddedAt) })
+
+	return list
+}
+
+// FileReader читает файл раздачи по мере загрузки. Read блокируется, пока нужные куски
+// не скачаны, куски от текущей позиции чтения и дальше загружаются в первую очередь.
+// Seek позволяет перематывать, не дожидаясь загрузки пропущенной части.
+type FileReader struct {
+	Path string // путь внутри раздачи
+	Size int64
+
+	ctx      context.Context
+	reader   torrent.Reader
+	picker   *picker
+	closed   func()
+	index    int
+	offset   int64 // начало файла в раздаче
+	piece    int64 // длина куска
+	openedAt time.Time
+
+	pos       atomic.Int64
+	bytesRead atomic.Int64
+}
+
+// Read прерывается, когда отменён контекст, с которым открыт файл (клиент отключился)
+func (f *FileReader) Read(p []byte) (int, error) {
+	n, err := f.reader.ReadContext(f.ctx, p)
+	if n > 0 {
+		f.bytesRead.Add(int64(n))
+		f.setPosition(f.pos.Load() + int64(n))
+	}
+	return n, err
+}
+
+func (f *FileReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.reader.Seek(offset, whence)
+	if err == nil {
+		f.setPosition(pos)
+	}
+	return pos, err
+}
+
+func (f *FileReader) Close() error {
+	f.picker.remove(f)
+	f.closed()
+	return f.reader.Close()
+}
+
+// Position - текущая позиция чтения в файле
+func (f *FileReader) Position() int64 {
+	return f.pos.Load()
+}
+
+// setPosition запоминает позицию и пересчитывает приоритеты, если читатель перешёл на другой кусок
+func (f *FileReader) setPosition(pos int64) {
+	old := f.pos.Swap(pos)
+	if (f.offset+old)/f.piece != (f.offset+pos)/f.piece {
+		f.picker.moved()
+	}
+}
+
+func (f *FileReader) stream(buffered int64) Stream {
+	s := Stream{
+		FileIndex: f.index,
+		Path:      f.Path,
+		Position:  f.Position(),
+		BytesRead: f.bytesRead.Load(),
+		Readahead: f.picker.readahead,
+		Buffered:  buffered,
+		OpenedAt:  f.openedAt,
+	}
+	if want := min(s.Readahead, f.Size-s.Position); want > 0 {
+		s.Health = float64(buffered) / float64(want)
+	} else {
+		s.Health = 1
+	}
+	return s
+}
+
+// OpenFile открывает файл раздачи с индексом index для потокового чтения.
+// Если метаданных ещё нет, ждёт их не дольше metadata_timeout.
+func (e *Engine) OpenFile(ctx context.Context, infoHash string, index int) (*FileReader, error) {
+	e.mu.Lock()
+	en, ok := e.torrents[strings.ToLower(infoHash)]
+	paused := ok && en.paused
+	e.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if paused {
+		return nil, ErrPaused
+	}
+
+	if err := e.waitInfo(ctx, en.t); err != nil {
+		return nil, err
+	}
+
+	files := en.t.Files()
+	if index < 0 || index >= len(files) {
+		return nil, ErrFileNotFound
+	}
+	f := files[index]
+
+	reader := f.NewReader()
+	// отдаём данные, как только пришли блоки, не дожидаясь проверки всего куска
+	reader.SetResponsive()
+	// окно упреждающего чтения ведёт picker, клиенту остаётся только кусок под позицией
+	reader.SetReadahead(0)
+
+	fr := &FileReader{
+		Path:     f.DisplayPath(),
+		Size:     f.Length(),
+		ctx:      ctx,
+		reader:   reader,
+		closed:   func() { e.touch(en) },
+		index:    index,
+		offset:   f.Offset(),
+		piece:    en.t.Info().PieceLength,
+		openedAt: time.Now(),
+	}
+
+	// читатель регистрируется под e.mu, чтобы Evict не удалил раздачу, которую начали смотреть
+	e.mu.Lock()
+	if e.torrents[strings.ToLower(infoHash)] != en {
+		e.mu.Unlock()
+		reader.Close()
+		return nil, ErrNotFound
+	}
+	if en.picker == nil {
+		en.picker = newPicker(en.t, e.readahead, e.headTail)
+	}
+	fr.picker = en.picker
+	en.picker.add(fr)
+	e.mu.Unlock()
+
+	e.touch(en)
+
+	return fr, nil
+}
+
+// Files возвращает файлы раздачи, дожидаясь метаданных не дольше metadata_timeout
+func (e *Engine) Files(ctx context.Context, infoHash string) ([]File, error) {
+	e.mu.Lock()
+	en, ok := e.torrents[strings.ToLower(infoHash)]
+	e.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if err := e.waitInfo(ctx, en.t); err != nil {
+		return nil, err
+	}
+	return files(en.t), nil
+}
+
+// waitInfo ждёт метаданные раздачи не дольше metadata_timeout
+func (e *Engine) waitInfo(ctx context.Context, t *torrent.Torrent) error {
+	if t.Info() != nil {
+		return nil
+	}
+
+	timer := time.NewTimer(e.metadataTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.GotInfo():
+		return nil
+	case <-t.Closed():
+		return ErrNotFound
+	case <-timer.C:
+		return ErrNoMetadata
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resolve возвращает список файлов раздачи по magnet-ссылке или info-hash. Метаданные берутся
+// из кэша или из добавленной раздачи, иначе запрашиваются у пиров (BEP 9, ut_metadata)
+// без загрузки самих данных и кэшируются. Ждёт не дольше metadata_timeout или отмены ctx.
+func (e *Engine) Resolve(ctx context.Context, source string) (Metadata, error) {
+	spec, _, err := parseSource(source)
+	if err != nil {
+		return Metadata{}, err
+	}
+	hash := spec.InfoHash.HexString()
+
+	if mi, err := e.store.MetaInfo(hash); err == nil {
+		return metadataFromBytes(hash, mi)
+	} else if !errors.Is(err, storage.ErrMetaInfoMissing) {
+		return Metadata{}, err
+	}
+
+	// одновременные запросы одной раздачи ждут общего результата, поэтому запрос к пирам
+	// не прерывается с отключением клиента: его ограничивает metadata_timeout, а полученные
+	// метаданные попадают в кэш и достанутся следующему запросу
+	ch := e.resolving.DoChan(hash, func() (interface{}, error) {
+		return e.fetchMetadata(context.WithoutCancel(ctx), spec)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return Metadata{}, res.Err
+		}
+		return res.Val.(Metadata), nil
+	case <-ctx.Done():
+		return Metadata{}, ctx.Err()
+	}
+}
+
+// fetchMetadata получает метаданные от пиров. Раздача, которой нет в движке, добавляется
+// в клиент только на время запроса и с запретом загрузки данных.
+func (e *Engine) fetchMetadata(ctx context.Context, spec *torrent.TorrentSpec) (Metadata, error) {
+	hash := spec.InfoHash.HexString()
+
+	e.mu.Lock()
+	en, added := e.torrents[hash]
+	var t *torrent.Torrent
+	if added {
+		t = en.t
+	} else {
+		var err error
+		t, _, err = e.client.AddTorrentSpec(spec)
+		if err != nil {
+			e.mu.Unlock()
+			return Metadata{}, err
+		}
+		t.DisallowDataDownload()
+	}
+	e.mu.Unlock()
+
+	if !added {
+		defer func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			// раздачу могли добавить в движок, пока ждали метаданные, а клиент - остановить,
+			// если запрос пережил отключившегося клиента
+			select {
+			case <-e.done:
+				return
+			default:
+			}
+			if _, ok := e.torrents[hash]; !ok {
+				t.Drop()
+			}
+		}()
+	}
+
+	if err := e.waitInfo(ctx, t); err != nil {
+		return Metadata{}, err
+	}
+
+	mi, err := bencode.Marshal(t.Metainfo())
+	if err != nil {
+		return Metadata{}, err
+	}
+	if err := e.store.SaveMetaInfo(hash, mi); err != nil {
+		e.log.Error("failed to cache metainfo", slog.String("info_hash", hash), sl.Err(err))
+	}
+
+	e.log.Info("torrent metadata resolved", slog.String("info_hash", hash), slog.String("name", t.Name()))
+
+	return metadataFromBytes(hash, mi)
+}
+
+// withCachedInfo добавляет к спецификации закэшированный словарь info,
+// чтобы не ждать метаданные от пиров
+func (e *Engine) withCachedInfo(spec *torrent.TorrentSpec) *torrent.TorrentSpec {
+	data, err := e.store.MetaInfo(spec.InfoHash.HexString())
+	if err != nil {
+		return spec
+	}
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return spec
+	}
+	spec.InfoBytes = mi.InfoBytes
+	return spec
+}
+
+// Close останавливает клиент и закрывает подписки на события, состояние раздач уже сохранено в SessionStore.
+// Повторный вызов ничего не делает.
+func (e *Engine) Close() {
+	e.closeOnce.Do(func() {
+		close(e.done)
+		e.events.closeAll()
+
+		// под e.mu, чтобы не пересечься с Drop раздач, которые ещё ждут метаданные
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.saveStats(time.Now())
+
+		for _, err := range e.client.Close() {
+			e.log.Error("failed to close engine", sl.Err(err))
+		}
+		e.sockets.Close()
+	})
+}
+
+func (e *Engine) setPaused(infoHash string, paused bool) (Status, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	en, ok := e.torrents[strings.ToLower(infoHash)]
+	if !ok {
+		return Status{}, ErrNotFound
+	}
+
+	if en.paused != paused {
+		e.account(en, time.Now()) // время до паузы засчитывается
+		if err := e.store.SaveSession(storage.Session{
+			InfoHash: en.t.InfoHash().HexString(),
+			Paused:   paused,
+			AddedAt:  en.addedAt,
+		}); err != nil {
+			return Status{}, err
+		}
+		en.paused = paused
+		applyPaused(en)
+		e.publishState(en)
+	}
+
+	return e.status(en), nil
+}
+
+// restore добавляет в клиент раздачи, сохранённые при прошлом запуске
+func (e *Engine) restore() error {
+	sessions, err := e.store.Sessions()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		spec, err := sessionSpec(s)
+		if err != nil {
+			e.log.Error("failed to restore torrent", slog.String("info_hash", s.InfoHash), sl.Err(err))
+			continue
+		}
+
+		en := &entry{
+			magnet:         s.Magnet,
+			paused:         s.Paused,
+			addedAt:        s.AddedAt,
+			watchedAt:      s.WatchedAt,
+			minRatio:       s.MinimumRatio,
+			minSeedTime:    s.MinimumSeedTime,
+			baseUploaded:   s.Uploaded,
+			baseDownloaded: s.Downloaded,
+			seedingTime:    s.SeedingTime,
+		}
+		if err := e.add(en, spec); err != nil {
+			e.log.Error("failed to restore torrent", slog.String("info_hash", s.InfoHash), sl.Err(err))
+		}
+	}
+
+	return nil
+}
+
+// add передаёт раздачу клиенту, вызывается под e.mu
+func (e *Engine) add(en *entry, spec *torrent.TorrentSpec) error {
+	t, _, err := e.client.AddTorrentSpec(spec)
+	if err != nil {
+		return err
+	}
+	en.t = t
+	e.torrents[t.InfoHash().HexString()] = en
+
+	applyPaused(en)
+	go e.awaitInfo(en)
+
+	return nil
+}
+
+// awaitInfo ждёт метаданные раздачи, сохраняет их, чтобы после перезапуска
+// не запрашивать у пиров заново, и начинает загрузку
+func (e *Engine) awaitInfo(en *entry) {
+	select {
+	case <-en.t.GotInfo():
+	case <-en.t.Closed():
+		return
+	}
+
+	go e.watchPieces(en)
+
+	hash := en.t.InfoHash().HexString()
+	mi, err := bencode.Marshal(en.t.Metainfo())
+	if err != nil {
+		e.reportError(hash, "failed to encode metainfo", err)
+	} else if err := e.store.SaveMetaInfo(hash, mi); err != nil {
+		e.reportError(hash, "failed to cache metainfo", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.torrents[hash] != en {
+		return // раздачу удалили, пока ждали метаданные
+	}
+	if err := e.store.SaveSession(storage.Session{
+		InfoHash: hash,
+		Name:     en.t.Name(),
+		MetaInfo: mi,
+		Paused:   en.paused,
+		AddedAt:  en.addedAt,
+	}); err != nil {
+		e.reportError(hash, "failed to save session", err)
+	}
+
+	e.log.Info("torrent metadata received", slog.String("info_hash", hash), slog.String("name", en.t.Name()))
+
+	applyPaused(en)
+	e.publishState(en)
+}
+
+// applyPaused приводит клиент в соответствие с en.paused
+func applyPaused(en *entry) {
+	// ограничение раздачи применится заново при следующей проверке
+	en.down.limited, en.up.limited = false, false
+	if en.paused {
+		en.t.DisallowDataDownload()
+		en.t.DisallowDataUpload()
+		return
+	}
+
+	en.t.AllowDataDownload()
+	en.t.AllowDataUpload()
+	if en.t.Info() != nil {
+		en.t.DownloadAll()
+	}
+}
+
+func (e *Engine) status(en *entry) Status {
+	t := en.t
+	stats := t.Stats()
+
+	s := Status{
+		InfoHash:        t.InfoHash().HexString(),
+		Name:            t.Name(),
+		Peers:           stats.ActivePeers,
+		Seeders:         stats.ConnectedSeeders,
+		Downloaded:      en.baseDownloaded + stats.BytesReadUsefulData.Int64(),
+		Uploaded:        en.baseUploaded + stats.BytesWrittenData.Int64(),
+		SeedingTime:     en.seedingTime,
+		MinimumRatio:    en.minRatio,
+		MinimumSeedTime: en.minSeedTime,
+		State:           e.state(en),
+		AddedAt:         en.addedAt,
+	}
+
+	if t.Info() != nil {
+		s.Size = t.Length()
+		s.Ratio = ratio(s.Uploaded, s.Downloaded, s.Size)
+		s.BytesCompleted = t.BytesCompleted()
+		s.Files = files(t)
+		if en.picker != nil {
+			s.Streams = en.picker.streams()
+			slices.SortFunc(s.Streams, func(a, b Stream) int { return a.OpenedAt.Compare(b.OpenedAt) })
+		}
+	}
+
+	return s
+}
+
+// files - файлы раздачи с известными метаданными
+func files(t *torrent.Torrent) []File {
+	var list []File
+	for i, f := range t.Files() {
+		list = append(list, File{
+			Index:          i,
+			Path:           f.DisplayPath(),
+			Size:           f.Length(),
+			BytesCompleted: f.BytesCompleted(),
+			Kind:           FileKind(f.DisplayPath()),
+		})
+	}
+	return list
+}
+
+func (e *Engine) state(en *entry) string {
+	switch {
+	case en.paused:
+		return StatePaused
+	case en.t.Info() == nil:
+		return StateMetadata
+	case en.t.Complete().Bool():
+		return StateSeeding
+	default:
+		return StateDownloading
+	}
+}
+
+// parseSource разбирает magnet-ссылку или info-hash и возвращает спецификацию раздачи
+// и magnet-ссылку, по которой её можно будет восстановить
+func parseSource(source string) (*torrent.TorrentSpec, string, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, "", ErrInvalidSource
+	}
+
+	if strings.HasPrefix(source, "magnet:") {
+		spec, err := torrent.TorrentSpecFromMagnetUri(source)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidSource, err)
+		}
+		if spec.InfoHash == (metainfo.Hash{}) {
+			return nil, "", fmt.Errorf("%w: magnet link has no btih info-hash", ErrInvalidSource)
+		}
+		return spec, source, nil
+	}
+
+	var hash metainfo.Hash
+	if err := hash.FromHexString(source); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	magnet := metainfo.Magnet{InfoHash: hash}
+
+	return &torrent.TorrentSpec{InfoHash: hash}, magnet.String(), nil
+}
+
+func sessionSpec(s storage.Session) (*torrent.TorrentSpec, error) {
+	if len(s.MetaInfo) > 0 {
+		mi, err := metainfo.Load(bytes.NewReader(s.MetaInfo))
+		if err != nil {
+			return nil, err
+		}
+		return torrent.TorrentSpecFromMetaInfoErr(mi)
+	}
+
+	spec, _, err := parseSource(s.Magnet)
+	return spec, err
+}
+
+func metadataFromBytes(hash string, data []byte) (Metadata, error) {
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return Metadata{}, err
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	md := Metadata{
+		InfoHash:    hash,
+		Name:        info.BestName(),
+		Size:        info.TotalLength(),
+		PieceLength: info.PieceLength,
+		Pieces:      info.NumPieces(),
+	}
+	// порядок и пути файлов совпадают с torrent.Files(), по индексу файл открывается в OpenFile
+	for i, fi := range info.UpvertedFiles() {
+		path := fi.DisplayPath(&info)
+		md.Files = append(md.Files, File{Index: i, Path: path, Size: fi.Length, Kind: FileKind(path)})
+	}
+
+	return md, nil
+}
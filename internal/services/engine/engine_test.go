@@ -0,0 +1,396 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/services/engine/enginetest"
+	"torrentServer/internal/storage"
+)
+
+func waitState(t *testing.T, e *engine.Engine, hash, state string) engine.Status {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		s, err := e.Get(hash)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if s.State == state {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("torrent state = %q, want %q", s.State, state)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.bin", map[string]int{"video.bin": 512 << 10})
+	data := seeder.Files["video.bin"]
+	store := enginetest.NewStorage(t)
+	dataDir := t.TempDir()
+
+	e := enginetest.NewEngine(t, enginetest.Config(dataDir), store)
+
+	s, err := e.Add(seeder.Magnet, false)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	hash := s.InfoHash
+
+	s = waitState(t, e, hash, engine.StateSeeding)
+	if s.Name != "video.bin" || s.Size != int64(len(data)) || s.BytesCompleted != s.Size || len(s.Files) != 1 {
+		t.Errorf("unexpected status after download: %+v", s)
+	}
+	got, err := os.ReadFile(filepath.Join(dataDir, "video.bin"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("downloaded file differs from the seeded one (err = %v)", err)
+	}
+
+	// повторное добавление не создаёт вторую раздачу
+	if _, err := e.Add(hash, false); err != nil || len(e.List()) != 1 {
+		t.Errorf("Add() of the same torrent: err = %v, torrents = %d", err, len(e.List()))
+	}
+
+	if s, err := e.Pause(hash); err != nil || s.State != engine.StatePaused {
+		t.Fatalf("Pause() = %q, %v", s.State, err)
+	}
+
+	// после перезапуска раздача восстанавливается на паузе и с метаданными
+	e.Close()
+	e = enginetest.NewEngine(t, enginetest.Config(dataDir), store)
+
+	s, err = e.Get(hash)
+	if err != nil {
+		t.Fatalf("Get() after restart error = %v", err)
+	}
+	if s.State != engine.StatePaused || s.Name != "video.bin" {
+		t.Errorf("restored status = %+v, want paused video.bin", s)
+	}
+
+	if _, err := e.Start(hash); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitState(t, e, hash, engine.StateSeeding)
+
+	if err := e.Remove(hash, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := e.Get(hash); !errors.Is(err, engine.ErrNotFound) {
+		t.Errorf("Get() after Remove() error = %v, want %v", err, engine.ErrNotFound)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "video.bin")); !os.IsNotExist(err) {
+		t.Errorf("data was not deleted: %v", err)
+	}
+	if sessions, _ := store.Sessions(); len(sessions) != 0 {
+		t.Errorf("sessions after Remove() = %d, want 0", len(sessions))
+	}
+}
+
+func TestAddInvalidSource(t *testing.T) {
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+
+	for _, source := range []string{"", "not-a-hash", "magnet:?dn=nohash"} {
+		if _, err := e.Add(source, false); !errors.Is(err, engine.ErrInvalidSource) {
+			t.Errorf("Add(%q) error = %v, want %v", source, err, engine.ErrInvalidSource)
+		}
+	}
+	if _, err := e.AddMetaInfo([]byte("not a torrent"), false); !errors.Is(err, engine.ErrInvalidSource) {
+		t.Errorf("AddMetaInfo() error = %v, want %v", err, engine.ErrInvalidSource)
+	}
+}
+
+func TestInvalidStatsInterval(t *testing.T) {
+	cfg := enginetest.Config(t.TempDir())
+	cfg.StatsInterval = 0
+	if _, err := engine.New(slogdiscard.NewDiscardLogger(), cfg, enginetest.NewStorage(t)); !errors.Is(err, engine.ErrInvalidStatsInterval) {
+		t.Errorf("New() with zero stats_interval: error = %v, want %v", err, engine.ErrInvalidStatsInterval)
+	}
+}
+
+func TestAddMetaInfo(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.bin", map[string]int{"video.bin": 64 << 10})
+	store := enginetest.NewStorage(t)
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), store)
+
+	// метаданные уже есть в файле, ждать пиров не нужно
+	s, err := e.AddMetaInfo(seeder.MetaInfo, true)
+	if err != nil {
+		t.Fatalf("AddMetaInfo() error = %v", err)
+	}
+	if s.InfoHash != seeder.InfoHash || s.Name != "video.bin" || s.State != engine.StatePaused || len(s.Files) != 1 {
+		t.Errorf("unexpected status: %+v", s)
+	}
+
+	sessions, err := store.Sessions()
+	if err != nil || len(sessions) != 1 || len(sessions[0].MetaInfo) == 0 {
+		t.Fatalf("sessions = %+v, %v; want one with metainfo", sessions, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "Show", map[string]int{
+		"e01.mkv":      256 << 10,
+		"subs/e01.srt": 1 << 10,
+		"readme.txt":   100,
+	})
+	store := enginetest.NewStorage(t)
+
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), store)
+
+	md, err := e.Resolve(context.Background(), seeder.Magnet)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if md.InfoHash != seeder.InfoHash || md.Name != "Show" || len(md.Files) != 3 {
+		t.Fatalf("unexpected metadata: %+v", md)
+	}
+	kinds := map[string]string{}
+	for _, f := range md.Files {
+		kinds[f.Path] = f.Kind
+	}
+	want := map[string]string{
+		"e01.mkv":      engine.KindVideo,
+		"subs/e01.srt": engine.KindSubtitle,
+		"readme.txt":   engine.KindOther,
+	}
+	for path, kind := range want {
+		if kinds[path] != kind {
+			t.Errorf("kind of %q = %q, want %q", path, kinds[path], kind)
+		}
+	}
+
+	// разрешение не добавляет раздачу в движок
+	if n := len(e.List()); n != 0 {
+		t.Errorf("torrents after Resolve() = %d, want 0", n)
+	}
+
+	// второй движок без адреса сидера берёт метаданные из кэша
+	e2 := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), store)
+	cached, err := e2.Resolve(context.Background(), seeder.InfoHash)
+	if err != nil {
+		t.Fatalf("Resolve() from cache error = %v", err)
+	}
+	if cached.Name != md.Name || len(cached.Files) != len(md.Files) {
+		t.Errorf("cached metadata = %+v, want %+v", cached, md)
+	}
+
+	// клиент не ждёт metadata_timeout после отмены запроса
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := e2.Resolve(ctx, strings.Repeat("ab", 20)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Resolve() of unknown torrent error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Resolve() returned after %v, want right after the deadline", d)
+	}
+}
+
+func TestStreamBufferHealth(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.mkv", map[string]int{"video.mkv": 512 << 10})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+
+	s, err := e.Add(seeder.Magnet, false)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	f, err := e.OpenFile(ctx, s.InfoHash, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	buf := make([]byte, 100<<10)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !bytes.Equal(buf, seeder.Files["video.mkv"][:len(buf)]) {
+		t.Fatal("read data differs from the seeded one")
+	}
+
+	// окно упреждающей загрузки больше файла, после загрузки буфер заполнен до конца файла
+	waitState(t, e, s.InfoHash, engine.StateSeeding)
+	s, _ = e.Get(s.InfoHash)
+	if len(s.Streams) != 1 {
+		t.Fatalf("streams = %d, want 1", len(s.Streams))
+	}
+	st := s.Streams[0]
+	if st.Position != int64(len(buf)) || st.BytesRead != int64(len(buf)) || st.Path != "video.mkv" {
+		t.Errorf("unexpected stream: %+v", st)
+	}
+	if st.Buffered != int64(512<<10-len(buf)) || st.Health != 1 {
+		t.Errorf("buffer = %d (health %v), want the rest of the file", st.Buffered, st.Health)
+	}
+
+	// просматриваемую раздачу при нехватке места удалять нельзя
+	if err := e.Evict(s.InfoHash); !errors.Is(err, engine.ErrStreaming) {
+		t.Errorf("Evict() of a watched torrent error = %v, want %v", err, engine.ErrStreaming)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if s, _ := e.Get(s.InfoHash); len(s.Streams) != 0 {
+		t.Errorf("streams after Close() = %d, want 0", len(s.Streams))
+	}
+
+	usage := e.Usage()
+	if len(usage) != 1 || !usage[0].Complete || usage[0].Streams != 0 || usage[0].Bytes != 512<<10 || usage[0].WatchedAt.IsZero() {
+		t.Errorf("usage after Close() = %+v", usage)
+	}
+	if err := e.Evict(s.InfoHash); err != nil {
+		t.Errorf("Evict() error = %v", err)
+	}
+	if len(e.Usage()) != 0 {
+		t.Error("torrent is not removed by Evict()")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.bin", map[string]int{"video.bin": 256 << 10})
+	e := enginetest.NewEngine(t, enginetest.Config(t.TempDir()), enginetest.NewStorage(t))
+
+	if _, err := e.Subscribe("0123456789abcdef0123456789abcdef01234567"); !errors.Is(err, engine.ErrNotFound) {
+		t.Errorf("Subscribe() to unknown torrent error = %v, want ErrNotFound", err)
+	}
+
+	all, err := e.Subscribe("")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer all.Close()
+
+	s, err := e.Add(seeder.Magnet, false)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	var states []string
+	pieces := make(map[int]bool)
+	var last *engine.PieceEvent
+	timeout := time.After(30 * time.Second)
+	for len(states) == 0 || states[len(states)-1] != engine.StateSeeding {
+		select {
+		case ev, ok := <-all.C:
+			if !ok {
+				t.Fatal("subscription closed")
+			}
+			if ev.InfoHash != s.InfoHash {
+				t.Errorf("event for %q, want %q", ev.InfoHash, s.InfoHash)
+			}
+			switch ev.Type {
+			case engine.EventState:
+				states = append(states, ev.State)
+			case engine.EventPiece:
+				pieces[ev.Piece.Index] = true
+				last = ev.Piece
+			}
+		case <-timeout:
+			t.Fatalf("no seeding state, got states %v", states)
+		}
+	}
+
+	if states[0] != engine.StateMetadata && states[0] != engine.StateDownloading {
+		t.Errorf("first state = %q, want metadata or downloading", states[0])
+	}
+	// 256 KiB кусками по 32 KiB; событие о переходе в seeding может обогнать последние куски
+	deadline := time.After(5 * time.Second)
+	for len(pieces) < 8 {
+		select {
+		case ev := <-all.C:
+			if ev.Type == engine.EventPiece {
+				pieces[ev.Piece.Index] = true
+				last = ev.Piece
+			}
+		case <-deadline:
+			t.Fatalf("piece events for %d pieces, want 8", len(pieces))
+		}
+	}
+	if last.Pieces != 8 {
+		t.Errorf("piece event pieces = %d, want 8", last.Pieces)
+	}
+
+	// подписка на одну раздачу начинается с её текущего состояния
+	one, err := e.Subscribe(s.InfoHash)
+	if err != nil {
+		t.Fatalf("Subscribe(%s) error = %v", s.InfoHash, err)
+	}
+	ev := <-one.C
+	if ev.Type != engine.EventState || ev.State != engine.StateSeeding || ev.Stats == nil || ev.Stats.BytesCompleted != 256<<10 {
+		t.Errorf("first event = %+v, want seeding snapshot", ev)
+	}
+
+	if err := e.Remove(s.InfoHash, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	// последним по удалённой раздаче приходит EventRemoved, после него подписка закрывается
+	var removed bool
+	for ev := range one.C {
+		removed = ev.Type == engine.EventRemoved
+	}
+	if !removed {
+		t.Error("subscription closed without removed event")
+	}
+
+	// остановка движка закрывает подписки
+	e.Close()
+	for range all.C {
+	}
+}
+
+func TestSeedingStats(t *testing.T) {
+	seeder := enginetest.NewSeeder(t, "video.bin", map[string]int{"video.bin": 128 << 10})
+	store := enginetest.NewStorage(t)
+	dataDir := t.TempDir()
+
+	// раздача встречалась в выдаче поиска, трекер требует рейтинг 1.5 и сутки раздачи
+	if err := store.SaveTorrents([]storage.Torrent{{
+		InfoHash:        seeder.InfoHash,
+		Title:           "video.bin",
+		MinimumRatio:    1.5,
+		MinimumSeedTime: 24 * time.Hour,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := enginetest.NewEngine(t, enginetest.Config(dataDir), store)
+	s, err := e.Add(seeder.Magnet, false)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if s.MinimumRatio != 1.5 || s.MinimumSeedTime != 24*time.Hour {
+		t.Errorf("requirements = %v, %v, want 1.5, 24h", s.MinimumRatio, s.MinimumSeedTime)
+	}
+
+	waitState(t, e, s.InfoHash, engine.StateSeeding)
+	time.Sleep(1200 * time.Millisecond)
+	if s, _ := e.Get(s.InfoHash); s.SeedingTime < time.Second || s.Downloaded != 128<<10 {
+		t.Errorf("seeding time = %v, downloaded = %d", s.SeedingTime, s.Downloaded)
+	}
+
+	// счётчики и требования переживают перезапуск
+	e.Close()
+	e = enginetest.NewEngine(t, enginetest.Config(dataDir), store)
+	s, err = e.Get(s.InfoHash)
+	if err != nil {
+		t.Fatalf("Get() after restart error = %v", err)
+	}
+	if s.MinimumRatio != 1.5 || s.MinimumSeedTime != 24*time.Hour || s.Downloaded != 128<<10 || s.SeedingTime < time.Second {
+		t.Errorf("restored status = %+v", s)
+	}
+}
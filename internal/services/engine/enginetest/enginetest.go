@@ -0,0 +1,143 @@
+// Package enginetest - вспомогательные функции для тестов, работающих с движком:
+// раздающий клиент в том же процессе и движок с хранилищем во временном каталоге.
+package enginetest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	alog "github.com/anacrolix/log"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+	"torrentServer/internal/storage/sqlite"
+)
+
+// Seeder - клиент, раздающий сгенерированные файлы
+type Seeder struct {
+	Magnet   string            // ссылка с адресом сидера (x.pe), по ней движок найдёт его без DHT и трекеров
+	InfoHash string            // hex
+	MetaInfo []byte            // bencode .torrent
+	Files    map[string][]byte // содержимое по пути внутри раздачи
+}
+
+// NewSeeder раздаёт файлы со случайным содержимым заданных размеров. Один файл раздаётся
+// как однофайловый торрент с именем файла, несколько - как каталог name.
+func NewSeeder(t testing.TB, name string, files map[string]int) *Seeder {
+	t.Helper()
+
+	contents := make(map[string][]byte, len(files))
+	for path, size := range files {
+		data := make([]byte, size)
+		rand.Read(data)
+		contents[path] = data
+	}
+	return NewSeederFiles(t, name, contents)
+}
+
+// NewSeederFiles раздаёт файлы с заданным содержимым, см. NewSeeder
+func NewSeederFiles(t testing.TB, name string, files map[string][]byte) *Seeder {
+	t.Helper()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, name)
+	s := &Seeder{Files: make(map[string][]byte, len(files))}
+	for path, data := range files {
+		full := filepath.Join(root, path)
+		if len(files) == 1 {
+			full = root
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s.Files[path] = data
+	}
+
+	info := metainfo.Info{PieceLength: 32 << 10}
+	if err := info.BuildFromFilePath(root); err != nil {
+		t.Fatal(err)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := metainfo.MetaInfo{InfoBytes: infoBytes}
+	if s.MetaInfo, err = bencode.Marshal(mi); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = dir
+	cfg.Seed = true
+	cfg.NoDHT = true
+	cfg.DisableTrackers = true
+	cfg.ListenPort = 0
+	cfg.NoDefaultPortForwarding = true
+	cfg.Logger = cfg.Logger.WithFilterLevel(alog.Critical) // сидер в тестах молчит
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	tor, err := client.AddTorrent(&mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-tor.GotInfo()
+	tor.VerifyData()
+
+	s.InfoHash = mi.HashInfoBytes().HexString()
+	s.Magnet = fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s&x.pe=127.0.0.1:%d", s.InfoHash, url.QueryEscape(name), client.LocalPort())
+
+	return s
+}
+
+// NewStorage открывает хранилище во временном каталоге теста
+func NewStorage(t testing.TB) *sqlite.Storage {
+	t.Helper()
+
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// Config - настройки движка для тестов: без DHT, случайный порт
+func Config(dataDir string) config.Engine {
+	return config.Engine{
+		DataDir:         dataDir,
+		NoDHT:           true,
+		Seed:            true,
+		MetadataTimeout: 10 * time.Second,
+		ReadaheadMB:     1,
+		HeadTailMB:      1,
+		StatsInterval:   100 * time.Millisecond,
+	}
+}
+
+// NewEngine запускает движок, который будет остановлен в конце теста
+func NewEngine(t testing.TB, cfg config.Engine, store engine.Store) *engine.Engine {
+	t.Helper()
+
+	e, err := engine.New(slogdiscard.NewDiscardLogger(), cfg, store)
+	if err != nil {
+		t.Fatalf("engine.New() error = %v", err)
+	}
+	t.Cleanup(e.Close)
+	return e
+}
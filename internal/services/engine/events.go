@@ -0,0 +1,331 @@
+package engine
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"torrentServer/internal/lib/logger/sl"
+)
+
+// Типы событий движка
+const (
+	EventState   = "state"   // состояние раздачи изменилось; сразу после подписки приходит текущее
+	EventStats   = "stats"   // скорости, пиры и прогресс, не чаще stats_interval и только при изменениях
+	EventPiece   = "piece"   // кусок скачан и проверен
+	EventRemoved = "removed" // раздача удалена, после этого событий по ней не будет
+	EventError   = "error"
+)
+
+type Event struct {
+	Type     string
+	InfoHash string
+	Time     time.Time
+	State    string      // EventState
+	Stats    *Stats      // EventState и EventStats
+	Piece    *PieceEvent // EventPiece
+	Error    string      // EventError
+	// сколько событий подписчик пропустил перед этим, потому что не успевал их читать
+	Dropped int
+}
+
+type Stats struct {
+	DownloadRate   int64 // байт в секунду
+	UploadRate     int64
+	Peers          int
+	Seeders        int
+	BytesCompleted int64
+	Size           int64
+	PiecesComplete int
+	Pieces         int
+}
+
+type PieceEvent struct {
+	Index          int
+	PiecesComplete int
+	Pieces         int
+}
+
+const (
+	subscriptionBuffer = 64
+	// подписчик, пропустивший столько событий подряд, отключается: он безнадёжно отстал
+	maxDropped = 1024
+)
+
+// Subscription - подписка на события одной раздачи или всех раздач.
+// C закрывается после Close, остановки движка или если подписчик слишком отстал (Lagged).
+// Подписка на одну раздачу закрывается и после EventRemoved по ней.
+type Subscription struct {
+	C <-chan Event
+
+	c        chan Event
+	infoHash string // пусто - все раздачи
+	bus      *bus
+	dropped  int
+	lagged   bool
+}
+
+// Close отменяет подписку, повторный вызов ничего не делает
+func (s *Subscription) Close() {
+	s.bus.remove(s, false)
+}
+
+// Lagged сообщает, что подписка закрыта из-за того, что подписчик не успевал читать события
+func (s *Subscription) Lagged() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.lagged
+}
+
+// bus раздаёт события подписчикам. Публикация никогда не блокируется: если буфер
+// подписчика полон, событие для него пропускается, а счётчик пропусков приходит
+// со следующим доставленным событием.
+type bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func newBus() *bus {
+	return &bus{subs: make(map[*Subscription]struct{})}
+}
+
+func (b *bus) subscribe(infoHash string, buffer int) *Subscription {
+	c := make(chan Event, buffer)
+	s := &Subscription{C: c, c: c, infoHash: infoHash, bus: b}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+func (b *bus) remove(s *Subscription, lagged bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(s, lagged)
+}
+
+func (b *bus) removeLocked(s *Subscription, lagged bool) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	s.lagged = lagged
+	close(s.c)
+}
+
+func (b *bus) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		b.removeLocked(s, false)
+	}
+}
+
+// active сообщает, есть ли подписчики
+func (b *bus) active() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs) > 0
+}
+
+func (b *bus) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subs {
+		if s.infoHash != "" && s.infoHash != ev.InfoHash {
+			continue
+		}
+		b.send(s, ev)
+		// по удалённой раздаче событий больше не будет
+		if ev.Type == EventRemoved && s.infoHash != "" {
+			b.removeLocked(s, false)
+		}
+	}
+}
+
+// send доставляет событие без блокировки, вызывается под b.mu
+func (b *bus) send(s *Subscription, ev Event) {
+	ev.Dropped = s.dropped
+	select {
+	case s.c <- ev:
+		s.dropped = 0
+	default:
+		s.dropped++
+		if s.dropped >= maxDropped {
+			b.removeLocked(s, true)
+		}
+	}
+}
+
+// Subscribe подписывает на события раздачи infoHash или, если он пуст, всех раздач.
+// Первыми приходят события EventState с текущим состоянием раздач.
+func (e *Engine) Subscribe(infoHash string) (*Subscription, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hash := strings.ToLower(infoHash)
+	if hash != "" {
+		if _, ok := e.torrents[hash]; !ok {
+			return nil, ErrNotFound
+		}
+	}
+
+	// буфер вмещает снимок всех раздач, чтобы он не вытеснял события
+	s := e.events.subscribe(hash, subscriptionBuffer+len(e.torrents))
+
+	now := time.Now()
+	e.events.mu.Lock()
+	for h, en := range e.torrents {
+		if hash != "" && h != hash {
+			continue
+		}
+		en.lastState = e.state(en)
+		e.events.send(s, Event{Type: EventState, InfoHash: h, Time: now, State: en.lastState, Stats: e.stats(en, now)})
+	}
+	e.events.mu.Unlock()
+
+	return s, nil
+}
+
+// runEvents раз в interval считает скорости и публикует изменения, пока движок не остановлен.
+// Счётчики для политики раздачи сохраняются реже, раз в statsSaveInterval.
+func (e *Engine) runEvents(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	savedAt := time.Now()
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			e.publishStats()
+			if now.Sub(savedAt) >= statsSaveInterval {
+				e.mu.Lock()
+				e.saveStats(now)
+				e.mu.Unlock()
+				savedAt = now
+			}
+		}
+	}
+}
+
+func (e *Engine) publishStats() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// скорости считаются и без подписчиков, иначе первая же выборка после подписки
+	// усреднила бы их за всё время без подписчиков
+	active := e.events.active()
+	now, streaming := time.Now(), e.streaming()
+	for hash, en := range e.torrents {
+		stats := e.stats(en, now)
+		e.account(en, now)
+		e.throttle(en, now, streaming)
+		if !active {
+			continue
+		}
+
+		if en.lastState != e.state(en) {
+			e.publishState(en) // со статистикой, отдельное EventStats не нужно
+			continue
+		}
+		if *stats != en.lastStats {
+			en.lastStats = *stats
+			e.events.publish(Event{Type: EventStats, InfoHash: hash, Time: now, Stats: stats})
+		}
+	}
+}
+
+// stats снимает статистику раздачи и обновляет данные для расчёта скоростей, вызывается под e.mu
+func (e *Engine) stats(en *entry, now time.Time) *Stats {
+	ts := en.t.Stats()
+	read, written := ts.BytesReadUsefulData.Int64(), ts.BytesWrittenData.Int64()
+
+	s := &Stats{
+		Peers:          ts.ActivePeers,
+		Seeders:        ts.ConnectedSeeders,
+		PiecesComplete: ts.PiecesComplete,
+	}
+	if en.t.Info() != nil {
+		s.BytesCompleted = en.t.BytesCompleted()
+		s.Size = en.t.Length()
+		s.Pieces = en.t.NumPieces()
+	}
+
+	if !en.sampledAt.IsZero() {
+		if dt := now.Sub(en.sampledAt).Seconds(); dt > 0 {
+			en.downloadRate = int64(float64(read-en.sampledRead) / dt)
+			en.uploadRate = int64(float64(written-en.sampledWritten) / dt)
+		}
+	}
+	if now.Sub(en.sampledAt) >= time.Second/2 {
+		// слишком частые выборки (снимок при подписке) не сдвигают окно расчёта
+		en.sampledAt, en.sampledRead, en.sampledWritten = now, read, written
+	}
+	s.DownloadRate, s.UploadRate = en.downloadRate, en.uploadRate
+
+	return s
+}
+
+// publishState публикует EventState, если состояние раздачи изменилось, вызывается под e.mu
+func (e *Engine) publishState(en *entry) {
+	state := e.state(en)
+	if state == en.lastState {
+		return
+	}
+	en.lastState = state
+
+	now := time.Now()
+	stats := e.stats(en, now)
+	en.lastStats = *stats
+	e.events.publish(Event{
+		Type:     EventState,
+		InfoHash: en.t.InfoHash().HexString(),
+		Time:     now,
+		State:    state,
+		Stats:    stats,
+	})
+}
+
+// watchPieces публикует EventPiece для каждого скачанного куска, пока раздача не закрыта
+func (e *Engine) watchPieces(en *entry) {
+	sub := en.t.SubscribePieceStateChanges()
+	defer sub.Close()
+
+	hash := en.t.InfoHash().HexString()
+	pieces := en.t.NumPieces()
+	done := make(map[int]bool)
+	for {
+		select {
+		case <-en.t.Closed():
+			return
+		case <-e.done:
+			return
+		case c, ok := <-sub.Values:
+			if !ok {
+				return
+			}
+			if !c.Complete || done[c.Index] {
+				continue
+			}
+			done[c.Index] = true
+			e.events.publish(Event{
+				Type:     EventPiece,
+				InfoHash: hash,
+				Time:     time.Now(),
+				Piece:    &PieceEvent{Index: c.Index, PiecesComplete: en.t.Stats().PiecesComplete, Pieces: pieces},
+			})
+		}
+	}
+}
+
+// reportError пишет ошибку раздачи в лог и отправляет её подписчикам
+func (e *Engine) reportError(infoHash, msg string, err error) {
+	e.log.Error(msg, slog.String("info_hash", infoHash), sl.Err(err))
+	e.events.publish(Event{Type: EventError, InfoHash: infoHash, Time: time.Now(), Error: msg + ": " + err.Error()})
+}
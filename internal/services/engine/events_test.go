@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestBusSlowSubscriber(t *testing.T) {
+	b := newBus()
+	fast := b.subscribe("", 8)
+	slow := b.subscribe("a", 1)
+	other := b.subscribe("b", 1)
+
+	// медленный подписчик ничего не читает: первое событие попадает в буфер, остальные пропускаются
+	b.publish(Event{Type: EventStats, InfoHash: "a"})
+	b.publish(Event{Type: EventStats, InfoHash: "a"})
+	b.publish(Event{Type: EventStats, InfoHash: "a"})
+	if ev := <-slow.C; ev.Dropped != 0 {
+		t.Errorf("first event dropped = %d, want 0", ev.Dropped)
+	}
+	b.publish(Event{Type: EventPiece, InfoHash: "a"})
+	if ev := <-slow.C; ev.Type != EventPiece || ev.Dropped != 2 {
+		t.Errorf("event after overflow = %s with dropped %d, want piece with 2", ev.Type, ev.Dropped)
+	}
+
+	// публикация не блокируется и после переполнения всех буферов
+	for range maxDropped + 1 {
+		b.publish(Event{Type: EventStats, InfoHash: "a"})
+	}
+	if _, ok := <-slow.C; !ok {
+		t.Fatal("slow subscription closed before draining its buffer")
+	}
+	if _, ok := <-slow.C; ok || !slow.Lagged() {
+		t.Errorf("slow subscription: open = %v, lagged = %v, want closed as lagged", ok, slow.Lagged())
+	}
+	if fast.Lagged() {
+		t.Error("fast subscription closed as lagged")
+	}
+
+	// события другой раздачи подписчику не приходят
+	select {
+	case ev := <-other.C:
+		t.Errorf("subscriber of b got %+v", ev)
+	default:
+	}
+
+	// удаление раздачи закрывает подписку на неё после события removed, но не общую подписку
+	b.publish(Event{Type: EventRemoved, InfoHash: "b"})
+	if ev, ok := <-other.C; !ok || ev.Type != EventRemoved {
+		t.Errorf("subscriber of b got %+v, open = %v, want removed event", ev, ok)
+	}
+	if _, ok := <-other.C; ok || other.Lagged() {
+		t.Error("subscription of removed torrent must be closed and not lagged")
+	}
+	select {
+	case _, ok := <-fast.C:
+		if !ok {
+			t.Error("subscription to all torrents closed on removal")
+		}
+	default:
+		t.Error("subscription to all torrents got no removed event")
+	}
+
+	other.Close()
+	other.Close()
+	if _, ok := <-other.C; ok || other.Lagged() {
+		t.Error("closed subscription must be closed and not lagged")
+	}
+}
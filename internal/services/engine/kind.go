@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"path"
+	"strings"
+)
+
+// Типы файлов раздачи
+const (
+	KindVideo    = "video"
+	KindAudio    = "audio"
+	KindSubtitle = "subtitle"
+	KindOther    = "other"
+)
+
+var kinds = map[string]string{
+	".mkv": KindVideo, ".mp4": KindVideo, ".m4v": KindVideo, ".avi": KindVideo, ".webm": KindVideo,
+	".mov": KindVideo, ".wmv": KindVideo, ".flv": KindVideo, ".ts": KindVideo, ".m2ts": KindVideo,
+	".mpg": KindVideo, ".mpeg": KindVideo, ".vob": KindVideo, ".3gp": KindVideo, ".ogv": KindVideo,
+
+	".mp3": KindAudio, ".flac": KindAudio, ".m4a": KindAudio, ".aac": KindAudio, ".ogg": KindAudio,
+	".opus": KindAudio, ".wav": KindAudio, ".mka": KindAudio, ".ac3": KindAudio, ".dts": KindAudio,
+	".wma": KindAudio, ".ape": KindAudio,
+
+	".srt": KindSubtitle, ".ass": KindSubtitle, ".ssa": KindSubtitle, ".vtt": KindSubtitle, ".sub": KindSubtitle,
+}
+
+// FileKind определяет тип файла по расширению
+func FileKind(name string) string {
+	if kind, ok := kinds[strings.ToLower(path.Ext(name))]; ok {
+		return kind
+	}
+	return KindOther
+}
+
+// Playable - файл можно воспроизвести в плеере
+func Playable(kind string) bool {
+	return kind == KindVideo || kind == KindAudio
+}
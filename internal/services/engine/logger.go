@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+
+	alog "github.com/anacrolix/log"
+)
+
+// newClientLogger направляет журнал BitTorrent-клиента в общий slog.
+// Клиент пишет очень подробно, поэтому пропускаются только предупреждения и ошибки.
+func newClientLogger(log *slog.Logger) alog.Logger {
+	l := alog.NewLogger("torrent")
+	l.Handlers = []alog.Handler{clientLogHandler{log: log.With(slog.String("component", "engine/client"))}}
+	return l.WithFilterLevel(alog.Warning)
+}
+
+type clientLogHandler struct {
+	log *slog.Logger
+}
+
+func (h clientLogHandler) Handle(r alog.Record) {
+	level := slog.LevelWarn
+	if r.Level.LessThan(alog.Warning) {
+		level = slog.LevelDebug
+	} else if !r.Level.LessThan(alog.Error) {
+		level = slog.LevelError
+	}
+	h.log.Log(context.Background(), level, r.Text())
+}
@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"golang.org/x/time/rate"
+)
+
+// Сети сокетов для пиров, как у встроенных сокетов клиента. Без IPv6 сервер работает только по IPv4.
+var peerNetworks = []struct {
+	network  string
+	optional bool
+}{
+	{"tcp4", false},
+	{"udp4", false},
+	{"tcp6", true},
+	{"udp6", true},
+}
+
+// peerLimits - ограничение скорости каждого соединения с пиром в байтах в секунду, 0 - без ограничения.
+// Соединения сверяют version при каждом чтении и записи, поэтому новые значения действуют
+// и на уже открытые соединения.
+type peerLimits struct {
+	version atomic.Int64
+
+	mu       sync.Mutex
+	download int64
+	upload   int64
+}
+
+func (l *peerLimits) set(download, upload int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.download == download && l.upload == upload {
+		return
+	}
+	l.download, l.upload = download, upload
+	l.version.Add(1)
+}
+
+func (l *peerLimits) get() (download, upload int64, version int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.download, l.upload, l.version.Load()
+}
+
+// peerSockets - TCP- и uTP-сокеты для соединений с пирами. anacrolix/torrent не умеет ограничивать
+// скорость отдельного пира, поэтому встроенные сокеты клиента отключены, а эти передаются ему через
+// AddListener и AddDialer и оборачивают каждое соединение в limitedConn. UDP-сокеты обслуживают и DHT.
+type peerSockets struct {
+	closers []func() error
+}
+
+// listenPeers открывает сокеты на порту cfg.ListenPort (0 - любой свободный) и подключает их к клиенту
+func listenPeers(client *torrent.Client, cfg *torrent.ClientConfig, noDHT bool, limits *peerLimits) (*peerSockets, error) {
+	s := &peerSockets{}
+	port := strconv.Itoa(cfg.ListenPort)
+
+	for _, n := range peerNetworks {
+		addr := net.JoinHostPort("", port)
+
+		var err error
+		switch n.network {
+		case "tcp4", "tcp6":
+			err = s.listenTCP(client, n.network, addr, limits)
+		default:
+			err = s.listenUTP(client, cfg, n.network, addr, noDHT, limits)
+		}
+		if err != nil && n.optional {
+			continue
+		}
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("listen %s %s: %w", n.network, addr, err)
+		}
+
+		// при listen_port 0 остальные сокеты слушают порт, выбранный для первого
+		port = strconv.Itoa(client.LocalPort())
+	}
+
+	return s, nil
+}
+
+func (s *peerSockets) listenTCP(client *torrent.Client, network, addr string, limits *peerLimits) error {
+	// BitTorrent сам отправляет keep-alive
+	lc := net.ListenConfig{KeepAlive: -1}
+	l, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, l.Close)
+
+	client.AddListener(limitedListener{l, limits})
+	client.AddDialer(limitedDialer{torrent.NetworkDialer{
+		Network: network,
+		Dialer:  &net.Dialer{KeepAlive: -1, FallbackDelay: -1},
+	}, limits})
+	return nil
+}
+
+func (s *peerSockets) listenUTP(client *torrent.Client, cfg *torrent.ClientConfig, network, addr string, noDHT bool, limits *peerLimits) error {
+	us, err := torrent.NewUtpSocket(network, addr, firewall(client, cfg), cfg.Logger)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, us.Close)
+
+	client.AddListener(limitedListener{us, limits})
+	client.AddDialer(limitedDialer{torrent.NetworkDialer{Network: network, Dialer: us}, limits})
+
+	if !noDHT {
+		ds, err := client.NewAnacrolixDhtServer(us)
+		if err != nil {
+			return err
+		}
+		client.AddDhtServer(torrent.AnacrolixDhtServerWrapper{Server: ds})
+		s.closers = append(s.closers, func() error { ds.Close(); return nil })
+	}
+	return nil
+}
+
+// firewall отклоняет входящие uTP-соединения до рукопожатия, как встроенные сокеты клиента:
+// если клиент не принимает соединения или всем раздачам хватает пиров. Сам обработчик клиента
+// не экспортирован, поэтому проверка повторена по открытому API; полную проверку клиент
+// всё равно выполняет при приёме соединения.
+func firewall(client *torrent.Client, cfg *torrent.ClientConfig) func(net.Addr) bool {
+	return func(net.Addr) bool {
+		if !cfg.AcceptPeerConnections {
+			return true
+		}
+		if cfg.AlwaysWantConns {
+			return false
+		}
+		for _, t := range client.Torrents() {
+			if t.Stats().ActivePeers < cfg.EstablishedConnsPerTorrent {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Close закрывает сокеты, клиент сам их не закрывает
+func (s *peerSockets) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+}
+
+type limitedListener struct {
+	torrent.Listener
+	limits *peerLimits
+}
+
+func (l limitedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return conn, err
+	}
+	return newLimitedConn(conn, l.limits), nil
+}
+
+type limitedDialer struct {
+	torrent.NetworkDialer
+	limits *peerLimits
+}
+
+func (d limitedDialer) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := d.NetworkDialer.Dial(ctx, addr)
+	if err != nil {
+		return conn, err
+	}
+	return newLimitedConn(conn, d.limits), nil
+}
+
+// limitedConn ограничивает скорость чтения и записи соединения с пиром.
+// Чтение и запись идут из разных горутин, поэтому у каждого направления свой ограничитель.
+// Ожидание квоты прерывается закрытием соединения и его дедлайнами, как обычные чтение и запись.
+type limitedConn struct {
+	net.Conn
+	limits *peerLimits
+	read   direction
+	write  direction
+
+	ctx    context.Context // отменяется при Close
+	cancel context.CancelFunc
+}
+
+type direction struct {
+	limiter  *rate.Limiter
+	version  int64
+	deadline atomic.Int64 // UnixNano, 0 - без дедлайна
+}
+
+func newLimitedConn(conn net.Conn, limits *peerLimits) *limitedConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &limitedConn{
+		Conn:   conn,
+		limits: limits,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, d := range []*direction{&c.read, &c.write} {
+		d.limiter = rate.NewLimiter(rate.Inf, minBurst)
+		d.version = -1
+	}
+	return c
+}
+
+// refresh применяет новые ограничения, если они изменились с прошлой проверки
+func (c *limitedConn) refresh(d *direction, upload bool) {
+	if c.limits.version.Load() == d.version {
+		return
+	}
+	download, up, version := c.limits.get()
+	limit := download
+	if upload {
+		limit = up
+	}
+	setRate(d.limiter, limit)
+	d.version = version
+}
+
+// wait ждёт квоту на n байт, пока соединение открыто и не наступил дедлайн направления
+func (c *limitedConn) wait(d *direction, n int) error {
+	ctx := c.ctx
+	if deadline := d.deadline.Load(); deadline != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.Unix(0, deadline))
+		defer cancel()
+	}
+
+	err := d.limiter.WaitN(ctx, n)
+	switch {
+	case err == nil:
+		return nil
+	case c.ctx.Err() != nil:
+		return net.ErrClosed
+	default:
+		// и истёкший дедлайн, и ожидание, которое его превысило бы
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func (c *limitedConn) Read(b []byte) (int, error) {
+	c.refresh(&c.read, false)
+	l := c.read.limiter
+	if l.Limit() != rate.Inf && len(b) > l.Burst() {
+		b = b[:l.Burst()]
+	}
+
+	n, err := c.Conn.Read(b)
+	if n > 0 && err == nil && l.Limit() != rate.Inf {
+		// прочитанное оплачивается после чтения: следующее чтение подождёт.
+		// Данные уже прочитаны, поэтому их отдаём, а ошибку вернёт следующее чтение.
+		c.wait(&c.read, n)
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(b []byte) (int, error) {
+	c.refresh(&c.write, true)
+	l := c.write.limiter
+	if l.Limit() == rate.Inf {
+		return c.Conn.Write(b)
+	}
+
+	var written int
+	for len(b) > 0 {
+		chunk := b[:min(len(b), l.Burst())]
+		if err := c.wait(&c.write, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+func (c *limitedConn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
+
+func (c *limitedConn) SetDeadline(t time.Time) error {
+	c.read.deadline.Store(unixNano(t))
+	c.write.deadline.Store(unixNano(t))
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *limitedConn) SetReadDeadline(t time.Time) error {
+	c.read.deadline.Store(unixNano(t))
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *limitedConn) SetWriteDeadline(t time.Time) error {
+	c.write.deadline.Store(unixNano(t))
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
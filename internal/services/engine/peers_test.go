@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimitedConn(t *testing.T) {
+	limits := &peerLimits{}
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newLimitedConn(client, limits)
+	defer conn.Close()
+
+	go io.Copy(io.Discard, server)
+
+	write := func(n int) time.Duration {
+		start := time.Now()
+		if _, err := conn.Write(make([]byte, n)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return time.Since(start)
+	}
+
+	if d := write(1 << 20); d > time.Second {
+		t.Errorf("without limit: 1 MiB written in %v", d)
+	}
+
+	// новое ограничение действует на открытое соединение: первая пачка уходит сразу, остальные ждут
+	limits.set(0, minBurst)
+	if d := write(3 * minBurst); d < 1900*time.Millisecond {
+		t.Errorf("with limit %d B/s: %d bytes written in %v", minBurst, 3*minBurst, d)
+	}
+
+	limits.set(0, 0)
+	if d := write(1 << 20); d > time.Second {
+		t.Errorf("limit removed: 1 MiB written in %v", d)
+	}
+}
+
+func TestLimitedConnUnblocks(t *testing.T) {
+	limits := &peerLimits{}
+	limits.set(0, minBurst)
+
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+
+	// дедлайн прерывает ожидание квоты и отдаёт ошибку таймаута, как у обычной записи
+	conn := newLimitedConn(client, limits)
+	conn.SetWriteDeadline(time.Now().Add(200 * time.Millisecond))
+	start := time.Now()
+	_, err := conn.Write(make([]byte, 10*minBurst))
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("Write() after deadline: error = %v, want timeout", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Write() with 200ms deadline took %v", d)
+	}
+
+	// закрытие соединения прерывает ожидание
+	conn.SetWriteDeadline(time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(make([]byte, 10*minBurst))
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	conn.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("Write() after Close: error = %v, want %v", err, net.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write() is still blocked after Close")
+	}
+}
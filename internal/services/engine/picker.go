@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/anacrolix/torrent"
+)
+
+// picker расставляет приоритеты кусков раздачи по позициям открытых FileReader.
+//
+// Внутри окна упреждающего чтения клиент выбирает куски одного приоритета по редкости,
+// а плееру нужны ближайшие. Поэтому окно делится на ступени по сроку, к которому кусок
+// понадобится: кусок под позицией чтения - Now, первая четверть окна - Next, вторая -
+// Readahead, остаток - High. Начало и конец открытого файла (moov в MP4, cues в MKV)
+// получают Readahead сразу при открытии. Вне окон остаётся обычный приоритет файла,
+// и такие куски клиент загружает в порядке редкости.
+type picker struct {
+	t         *torrent.Torrent
+	readahead int64
+	headTail  int64
+
+	mu      sync.Mutex
+	readers map[*FileReader]struct{}
+	set     map[int]torrent.PiecePriority // куски, которым приоритет выставлен пикером
+}
+
+func newPicker(t *torrent.Torrent, readahead, headTail int64) *picker {
+	return &picker{
+		t:         t,
+		readahead: readahead,
+		headTail:  headTail,
+		readers:   make(map[*FileReader]struct{}),
+		set:       make(map[int]torrent.PiecePriority),
+	}
+}
+
+func (p *picker) add(r *FileReader) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readers[r] = struct{}{}
+	p.update()
+}
+
+func (p *picker) remove(r *FileReader) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.readers, r)
+	p.update()
+}
+
+// moved пересчитывает приоритеты, когда читатель перешёл на другой кусок
+func (p *picker) moved() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.update()
+}
+
+// count - сколько читателей открыто
+func (p *picker) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.readers)
+}
+
+// streams возвращает состояние буфера каждого открытого читателя
+func (p *picker) streams() []Stream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := make([]Stream, 0, len(p.readers))
+	for r := range p.readers {
+		list = append(list, r.stream(p.buffered(r)))
+	}
+	return list
+}
+
+// buffered - сколько байт подряд от позиции читателя уже скачано, не больше окна
+func (p *picker) buffered(r *FileReader) int64 {
+	info := p.t.Info()
+	pos := r.offset + r.Position()
+	end := min(pos+p.readahead, r.offset+r.Size)
+	if pos >= end {
+		return 0
+	}
+
+	for i := int(pos / info.PieceLength); ; i++ {
+		if !p.t.Piece(i).State().Complete {
+			return max(0, int64(i)*info.PieceLength-pos)
+		}
+		if pieceEnd := int64(i+1) * info.PieceLength; pieceEnd >= end {
+			return end - pos
+		}
+	}
+}
+
+// update выставляет приоритеты по текущим позициям, вызывается под p.mu
+func (p *picker) update() {
+	windows := make([]window, 0, len(p.readers))
+	for r := range p.readers {
+		windows = append(windows, window{Offset: r.offset, Length: r.Size, Pos: r.Position()})
+	}
+	info := p.t.Info()
+	want := planPriorities(info.PieceLength, info.NumPieces(), windows, p.readahead, p.headTail)
+
+	for i := range p.set {
+		if _, ok := want[i]; !ok {
+			// остаётся приоритет файла: обычный при загрузке, нулевой на паузе
+			p.t.Piece(i).SetPriority(torrent.PiecePriorityNone)
+			delete(p.set, i)
+		}
+	}
+	for i, prio := range want {
+		if p.set[i] != prio {
+			p.t.Piece(i).SetPriority(prio)
+			p.set[i] = prio
+		}
+	}
+}
+
+// window - открытый файл раздачи и позиция чтения в нём
+type window struct {
+	Offset int64 // начало файла в раздаче
+	Length int64
+	Pos    int64 // позиция внутри файла
+}
+
+// planPriorities возвращает приоритеты кусков для набора читателей.
+// Если кусок нужен нескольким читателям, берётся наибольший приоритет.
+func planPriorities(pieceLength int64, pieces int, windows []window, readahead, headTail int64) map[int]torrent.PiecePriority {
+	want := make(map[int]torrent.PiecePriority)
+	raise := func(begin, end int64, prio torrent.PiecePriority) {
+		if end <= begin {
+			return
+		}
+		last := min(int((end-1)/pieceLength), pieces-1)
+		for i := int(begin / pieceLength); i <= last; i++ {
+			if want[i] < prio {
+				want[i] = prio
+			}
+		}
+	}
+
+	for _, w := range windows {
+		fileEnd := w.Offset + w.Length
+
+		raise(w.Offset, min(w.Offset+headTail, fileEnd), torrent.PiecePriorityReadahead)
+		raise(max(fileEnd-headTail, w.Offset), fileEnd, torrent.PiecePriorityReadahead)
+
+		pos := w.Offset + w.Pos
+		if pos >= fileEnd {
+			continue
+		}
+		end := min(pos+readahead, fileEnd)
+		raise(pos, end, torrent.PiecePriorityHigh)
+		raise(pos, min(pos+readahead/2, end), torrent.PiecePriorityReadahead)
+		raise(pos, min(pos+readahead/4, end), torrent.PiecePriorityNext)
+		raise(pos, pos+1, torrent.PiecePriorityNow)
+	}
+	return want
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestPlanPriorities(t *testing.T) {
+	const piece = 100
+
+	// файл 2000 байт с начала раздачи, читатель на 550, окно 800, начало и конец - по 100 байт
+	want := planPriorities(piece, 30, []window{{Offset: 0, Length: 2000, Pos: 550}}, 800, 100)
+
+	expect := map[int]torrent.PiecePriority{
+		0:  torrent.PiecePriorityReadahead, // начало файла
+		5:  torrent.PiecePriorityNow,       // под позицией чтения
+		6:  torrent.PiecePriorityNext,      // первая четверть окна: 550..750
+		7:  torrent.PiecePriorityNext,
+		8:  torrent.PiecePriorityReadahead, // вторая четверть: 750..950
+		9:  torrent.PiecePriorityReadahead,
+		10: torrent.PiecePriorityHigh, // остаток окна: 950..1350
+		13: torrent.PiecePriorityHigh,
+		19: torrent.PiecePriorityReadahead, // конец файла
+	}
+	for i, prio := range expect {
+		if want[i] != prio {
+			t.Errorf("piece %d priority = %v, want %v", i, want[i], prio)
+		}
+	}
+	// вне окон приоритет не выставляется, работает порядок редкости
+	for _, i := range []int{1, 4, 14, 18, 20} {
+		if prio, ok := want[i]; ok {
+			t.Errorf("piece %d priority = %v, want none", i, prio)
+		}
+	}
+}
+
+func TestPlanPrioritiesSeveralReaders(t *testing.T) {
+	const piece = 100
+
+	// второй файл начинается с 1000, окно ограничено концом файла
+	windows := []window{
+		{Offset: 0, Length: 1000, Pos: 0},
+		{Offset: 1000, Length: 500, Pos: 300},
+	}
+	want := planPriorities(piece, 15, windows, 400, 0)
+
+	if want[0] != torrent.PiecePriorityNow || want[13] != torrent.PiecePriorityNow {
+		t.Errorf("reader pieces = %v, %v, want Now", want[0], want[13])
+	}
+	if want[14] != torrent.PiecePriorityReadahead {
+		t.Errorf("piece 14 priority = %v, want Readahead", want[14])
+	}
+	if _, ok := want[15]; ok {
+		t.Error("window of the second reader crossed the end of the file")
+	}
+	if _, ok := want[10]; ok {
+		t.Error("piece before the second reader is prioritized")
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import "time"
+
+// как часто сохранять счётчики раздач, при остановке движка они сохраняются сразу
+const statsSaveInterval = time.Minute
+
+// account прибавляет ко времени раздачи время с прошлого учёта, если раздача скачана
+// целиком и не на паузе, вызывается под e.mu
+func (e *Engine) account(en *entry, now time.Time) {
+	if !en.accountedAt.IsZero() && e.state(en) == StateSeeding {
+		en.seedingTime += now.Sub(en.accountedAt)
+	}
+	en.accountedAt = now
+}
+
+// saveStats сохраняет счётчики всех раздач, чтобы рейтинг и время раздачи
+// не обнулялись при перезапуске, вызывается под e.mu
+func (e *Engine) saveStats(now time.Time) {
+	for hash, en := range e.torrents {
+		e.account(en, now)
+		stats := en.t.Stats()
+		uploaded := en.baseUploaded + stats.BytesWrittenData.Int64()
+		downloaded := en.baseDownloaded + stats.BytesReadUsefulData.Int64()
+		if err := e.store.SaveSessionStats(hash, uploaded, downloaded, en.seedingTime); err != nil {
+			e.reportError(hash, "failed to save torrent stats", err)
+		}
+	}
+}
+
+// requirementsMet сообщает, выполнила ли раздача требования трекера, вызывается под e.mu
+func requirementsMet(en *entry) bool {
+	if en.minRatio == 0 && en.minSeedTime == 0 {
+		return true
+	}
+	if en.t.Info() == nil {
+		return false
+	}
+	stats := en.t.Stats()
+	uploaded := en.baseUploaded + stats.BytesWrittenData.Int64()
+	downloaded := en.baseDownloaded + stats.BytesReadUsefulData.Int64()
+	return ratio(uploaded, downloaded, en.t.Length()) >= en.minRatio && en.seedingTime >= en.minSeedTime
+}
+
+// ratio - отданное к скачанному. Если скачано меньше размера раздачи (данные уже были на диске),
+// рейтинг считается к размеру, иначе раздача без загрузки сразу получала бы огромный рейтинг.
+func ratio(uploaded, downloaded, size int64) float64 {
+	base := max(downloaded, size)
+	if base == 0 {
+		return 0
+	}
+	return float64(uploaded) / float64(base)
+}
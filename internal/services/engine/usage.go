@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"slices"
+	"time"
+)
+
+// Usage - место, которое занимает раздача, и то, что нужно для решения об её удалении
+type Usage struct {
+	InfoHash  string
+	Name      string
+	Bytes     int64 // скачанные и проверенные данные
+	Size      int64 // 0, пока нет метаданных
+	Complete  bool
+	Streams   int       // открытые потоки, такую раздачу сейчас смотрят
+	WatchedAt time.Time // нулевое, если раздачу не открывали
+	AddedAt   time.Time
+	// требования трекера к раздаче (MinimumRatio, MinimumSeedTime) выполнены или их нет
+	RequirementsMet bool
+}
+
+// Usage возвращает занятое место по каждой раздаче в порядке добавления
+func (e *Engine) Usage() []Usage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]Usage, 0, len(e.torrents))
+	for hash, en := range e.torrents {
+		u := Usage{
+			InfoHash:        hash,
+			Name:            en.t.Name(),
+			WatchedAt:       en.watchedAt,
+			AddedAt:         en.addedAt,
+			RequirementsMet: requirementsMet(en),
+		}
+		if en.t.Info() != nil {
+			u.Bytes = en.t.BytesCompleted()
+			u.Size = en.t.Length()
+			u.Complete = en.t.Complete().Bool()
+		}
+		if en.picker != nil {
+			u.Streams = en.picker.count()
+		}
+		list = append(list, u)
+	}
+	slices.SortFunc(list, func(a, b Usage) int { return a.AddedAt.Compare(b.AddedAt) })
+
+	return list
+}
+
+// touch отмечает, что раздачу смотрят: при открытии и закрытии потока
+func (e *Engine) touch(en *entry) {
+	now := time.Now()
+
+	e.mu.Lock()
+	en.watchedAt = now
+	e.mu.Unlock()
+
+	hash := en.t.InfoHash().HexString()
+	if err := e.store.SetSessionWatched(hash, now); err != nil {
+		e.reportError(hash, "failed to save watch time", err)
+	}
+}
@@ -1,18 +1,26 @@
 package jackett
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
-	"golang.org/x/net/context"
+	"torrentServer/internal/lib/metrics"
+	"torrentServer/internal/lib/tracing"
 )
 
 var (
@@ -94,17 +102,19 @@ type Indexer struct {
 
 type Jackett struct {
 	settings *Settings
+	links    linkCache
 }
 
 type SimpleResult struct {
-	Title       string `json:"title"`
-	Category    []uint `json:"category"`
-	MagnetUri   string `json:"magnetUri"`
-	Seeders     uint   `json: "seeders`
-	Size        uint   `json: "size"`
-	Peers       uint   `json: "peers"`
-	Description string `json: "description"`
-	Tracker     string `json: "Tracker"`
+	Title     string `json:"title"`
+	Category  []uint `json:"category"`
+	MagnetUri string `json:"magnetUri"`
+	// ключи с заглавной буквы, как отдавал /search до версии API: на них рассчитаны клиенты
+	Seeders     uint   `json:"Seeders"`
+	Size        uint   `json:"Size"`
+	Peers       uint   `json:"Peers"`
+	Description string `json:"Description"`
+	Tracker     string `json:"Tracker"`
 }
 
 func NewJackett(s *Settings) *Jackett {
@@ -142,6 +152,53 @@ func (j *Jackett) generateFetchURL(fr *FetchRequest) (string, error) {
 }
 
 func (j *Jackett) Fetch(ctx context.Context, fr *FetchRequest) (*FetchResponse, error) {
+	categories := make([]int, 0, len(fr.Categories))
+	for _, c := range fr.Categories {
+		categories = append(categories, int(c))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "Jackett.Fetch", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("search.query", fr.Query),
+		attribute.IntSlice("search.categories", categories),
+		attribute.StringSlice("search.trackers", fr.Trackers),
+	))
+	defer span.End()
+
+	start := time.Now()
+	fres, err := j.fetch(ctx, fr)
+	metrics.JackettFetchDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.JackettFetchErrors.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "jackett fetch failed")
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("jackett.results", len(fres.Results)),
+		attribute.Int("jackett.indexers", len(fres.Indexers)),
+	)
+	for _, idx := range fres.Indexers {
+		// исход по каждому индексатору - отдельное событие спана
+		span.AddEvent("indexer", trace.WithAttributes(
+			attribute.String("indexer.id", idx.ID),
+			attribute.String("indexer.name", idx.Name),
+			attribute.Int("indexer.status", int(idx.Status)),
+			attribute.Int("indexer.results", int(idx.Results)),
+			attribute.String("indexer.error", idx.Error),
+		))
+
+		metrics.IndexerResults.WithLabelValues(idx.ID).Add(float64(idx.Results))
+		metrics.IndexerLastResults.WithLabelValues(idx.ID).Set(float64(idx.Results))
+		metrics.IndexerStatus.WithLabelValues(idx.ID, strconv.FormatUint(uint64(idx.Status), 10)).Inc()
+		if idx.Error != "" {
+			metrics.IndexerErrors.WithLabelValues(idx.ID).Inc()
+		}
+	}
+
+	return fres, nil
+}
+
+func (j *Jackett) fetch(ctx context.Context, fr *FetchRequest) (*FetchResponse, error) {
 	u, err := j.generateFetchURL(fr)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate fetch url")
@@ -159,14 +216,99 @@ func (j *Jackett) Fetch(ctx context.Context, fr *FetchRequest) (*FetchResponse,
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to read fetch data")
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("http.response.status_code", res.StatusCode),
+		attribute.Int("http.response.body.size", len(data)),
+	)
+
+	_, span := tracing.Tracer().Start(ctx, "Jackett.decode")
 	var fres FetchResponse
 	err = json.Unmarshal(data, &fres)
+	span.End()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal fetch data with url=%v and data=%v", u, string(data))
 	}
 	return &fres, nil
 }
 
+// Ping проверяет, что Jackett отвечает и принимает ключ API.
+// Запрашиваются только возможности (caps) Torznab, поиск по индексаторам не выполняется.
+func (j *Jackett) Ping(ctx context.Context) error {
+	res, err := j.torznab(ctx, url.Values{"t": {"caps"}})
+	if err != nil {
+		return errors.Wrap(err, "failed to invoke ping request")
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// IndexerInfo - индексатор, настроенный в Jackett
+type IndexerInfo struct {
+	ID          string `xml:"id,attr"`
+	Name        string `xml:"title"`
+	Description string `xml:"description"`
+	Link        string `xml:"link"`
+	Language    string `xml:"language"`
+	Type        string `xml:"type"` // public, semi-private или private
+}
+
+// Indexers возвращает настроенные индексаторы (Torznab t=indexers)
+func (j *Jackett) Indexers(ctx context.Context) ([]IndexerInfo, error) {
+	res, err := j.torznab(ctx, url.Values{"t": {"indexers"}, "configured": {"true"}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to invoke indexers request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, res.Body)
+		return nil, errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var body struct {
+		Indexers []IndexerInfo `xml:"indexer"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "failed to decode indexers")
+	}
+	return body.Indexers, nil
+}
+
+// torznab выполняет запрос к Torznab API агрегированного индексатора all
+func (j *Jackett) torznab(ctx context.Context, params url.Values) (*http.Response, error) {
+	u, err := url.Parse(j.settings.ApiURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse apiURL %q", j.settings.ApiURL)
+	}
+	u.Path = "/api/v2.0/indexers/all/results/torznab/api"
+	q := u.Query()
+	q.Set("apikey", j.settings.ApiKey)
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make torznab request")
+	}
+	res, err := j.settings.Client.Do(req)
+	if err != nil {
+		// url.Error содержит адрес вместе с apikey, его нельзя отдавать в ответах API
+		var uerr *url.Error
+		if stderrors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
 func (j *Jackett) FilterResults(results []Result, safeOnly int) ([]byte, error) {
 	simpleResults := make([]SimpleResult, 0, len(results))
 	for _, r := range results {
@@ -185,6 +327,9 @@ func (j *Jackett) FilterResults(results []Result, safeOnly int) ([]byte, error)
 			Peers:       r.Peers,
 			Description: r.Description,
 		}
+		// результаты с одной ссылкой на .torrent разрешает ResolveLinks. Неразрешённые отбрасываются:
+		// ссылка Jackett без его ключа не работает, а с ключом её нельзя отдавать клиентам.
+		// Такая выдача не кэшируется, и следующий поиск попробует их снова.
 		if r.MagnetUri == "" {
 			continue
 		}
@@ -0,0 +1,23 @@
+package jackett
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSimpleResultKeys(t *testing.T) {
+	data, err := json.Marshal(SimpleResult{Title: "t", Seeders: 1, Size: 2, Peers: 3, Description: "d", Tracker: "tr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	// ключи, которые /search отдавал с самого начала
+	for _, key := range []string{"title", "category", "magnetUri", "Seeders", "Size", "Peers", "Description", "Tracker"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("key %q is missing in %s", key, data)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package jackett
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+
+	"torrentServer/internal/lib/torrentfile"
+	"torrentServer/internal/lib/tracing"
+)
+
+const (
+	// сколько ссылок скачивается одновременно
+	linkWorkers = 8
+	// время на одну ссылку и на все ссылки запроса без дедлайна, медленный индексатор
+	// не должен задерживать всю выдачу
+	linkTimeout = 10 * time.Second
+	// при дедлайне запроса на ссылки уходит не больше этой доли оставшегося времени,
+	// остальное - на фильтрацию и ответ
+	linkDeadlineShare = 2
+	// сколько разрешённых ссылок держать в памяти, при переполнении кэш очищается
+	linkCacheSize = 10000
+)
+
+// linkCache запоминает magnet-ссылки по Link: ссылки Jackett на один и тот же
+// .torrent не меняются между запросами
+type linkCache struct {
+	mu      sync.Mutex
+	magnets map[string]string
+}
+
+func (c *linkCache) get(link string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.magnets[link]
+	return m, ok
+}
+
+func (c *linkCache) put(link, magnet string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.magnets == nil || len(c.magnets) >= linkCacheSize {
+		c.magnets = make(map[string]string)
+	}
+	c.magnets[link] = magnet
+}
+
+// ResolveLinks заполняет MagnetUri и InfoHash у результатов, для которых индексатор
+// отдал только Link на .torrent (например Internet Archive). Jackett по такой ссылке либо
+// перенаправляет на magnet, либо отдаёт сам файл - тогда magnet собирается из него.
+// Ссылки, которые не удалось разрешить за отведённое время, остаются без magnet и не попадают в выдачу.
+// Возвращает false, если время вышло раньше, чем были обработаны все ссылки: такую выдачу
+// нельзя кэшировать, следующий запрос разрешит оставшиеся ссылки.
+func (j *Jackett) ResolveLinks(ctx context.Context, results []Result) bool {
+	var pending []int
+	for i, r := range results {
+		if r.MagnetUri == "" && r.Link != "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return true
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "Jackett.ResolveLinks")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, linkBudget(ctx))
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		resolved int
+		jobs     = make(chan int)
+	)
+	for w := 0; w < min(linkWorkers, len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				magnet, err := j.resolveLink(ctx, results[i].Link)
+				if err != nil {
+					continue
+				}
+				// каждый индекс обрабатывает один воркер, остальные поля не трогаются
+				results[i].MagnetUri = magnet
+				if results[i].InfoHash == "" {
+					results[i].InfoHash = infoHashOf(magnet)
+				}
+				mu.Lock()
+				resolved++
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	complete := ctx.Err() == nil
+	span.SetAttributes(
+		attribute.Int("jackett.links", len(pending)),
+		attribute.Int("jackett.links_resolved", resolved),
+		attribute.Bool("jackett.links_complete", complete),
+	)
+	return complete
+}
+
+// linkBudget - сколько времени можно потратить на все ссылки запроса
+func linkBudget(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return linkTimeout
+	}
+	return min(linkTimeout, time.Until(deadline)/linkDeadlineShare)
+}
+
+// resolveLink возвращает magnet-ссылку для Link из выдачи Jackett
+func (j *Jackett) resolveLink(ctx context.Context, link string) (string, error) {
+	if strings.HasPrefix(link, "magnet:") {
+		return link, nil
+	}
+	if magnet, ok := j.links.get(link); ok {
+		return magnet, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, linkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to make link request")
+	}
+	// перенаправление на magnet клиент выполнить не может, Location забираем сами
+	client := *j.settings.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme == "magnet" {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", errors.New("failed to invoke link request") // url.Error содержит apikey
+	}
+	defer res.Body.Close()
+
+	var magnet string
+	switch {
+	case res.StatusCode >= 300 && res.StatusCode < 400 && strings.HasPrefix(res.Header.Get("Location"), "magnet:"):
+		magnet = res.Header.Get("Location")
+	case res.StatusCode == http.StatusOK:
+		data, err := io.ReadAll(io.LimitReader(res.Body, torrentfile.MaxSize+1))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read torrent file")
+		}
+		if len(data) > torrentfile.MaxSize {
+			return "", errors.New("torrent file is too large")
+		}
+		mi, err := torrentfile.Parse(data)
+		if err != nil {
+			return "", err
+		}
+		magnet = mi.Magnet()
+	default:
+		return "", errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	j.links.put(link, magnet)
+	return magnet, nil
+}
+
+func infoHashOf(magnet string) string {
+	i := strings.Index(magnet, "urn:btih:")
+	if i < 0 {
+		return ""
+	}
+	hash := magnet[i+len("urn:btih:"):]
+	if end := strings.IndexByte(hash, '&'); end >= 0 {
+		hash = hash[:end]
+	}
+	return strings.ToLower(hash)
+}
@@ -0,0 +1,112 @@
+package jackett
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"torrentServer/internal/lib/bencode"
+	"torrentServer/internal/lib/torrentfile"
+)
+
+func TestResolveLinks(t *testing.T) {
+	torrent, err := bencode.Marshal(map[string]any{
+		"announce": "http://tracker/announce",
+		"info": map[string]any{
+			"name": "archive.iso", "length": int64(10), "piece length": int64(16), "pieces": strings.Repeat("x", 20),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi, err := torrentfile.Parse(torrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const redirected = "magnet:?xt=urn:btih:0123456789ABCDEF0123456789ABCDEF01234567&dn=x"
+
+	var fileRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dl/file":
+			fileRequests.Add(1)
+			w.Header().Set("Content-Type", "application/x-bittorrent")
+			w.Write(torrent)
+		case "/dl/redirect":
+			http.Redirect(w, r, redirected, http.StatusFound)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	j := NewJackett(&Settings{ApiURL: srv.URL})
+	results := []Result{
+		{Title: "file", Link: srv.URL + "/dl/file"},
+		{Title: "redirect", Link: srv.URL + "/dl/redirect"},
+		{Title: "broken", Link: srv.URL + "/dl/missing"},
+		{Title: "magnet", MagnetUri: "magnet:?xt=urn:btih:aa", Link: srv.URL + "/dl/missing"},
+	}
+	j.ResolveLinks(context.Background(), results)
+
+	if results[0].MagnetUri != mi.Magnet() || results[0].InfoHash != mi.InfoHash {
+		t.Errorf("torrent link resolved to %q (%s), want %q", results[0].MagnetUri, results[0].InfoHash, mi.Magnet())
+	}
+	if results[1].MagnetUri != redirected || results[1].InfoHash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("redirect resolved to %q (%s)", results[1].MagnetUri, results[1].InfoHash)
+	}
+	if results[2].MagnetUri != "" {
+		t.Errorf("broken link resolved to %q", results[2].MagnetUri)
+	}
+	if results[3].MagnetUri != "magnet:?xt=urn:btih:aa" {
+		t.Errorf("existing magnet replaced with %q", results[3].MagnetUri)
+	}
+
+	// повторная выдача берёт magnet из кэша, а FilterResults больше не отбрасывает такие результаты
+	again := []Result{{Title: "file", Link: srv.URL + "/dl/file"}}
+	j.ResolveLinks(context.Background(), again)
+	if again[0].MagnetUri != mi.Magnet() || fileRequests.Load() != 1 {
+		t.Errorf("cached link: magnet = %q, requests = %d", again[0].MagnetUri, fileRequests.Load())
+	}
+	data, err := j.FilterResults(results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// неразрешённая ссылка в выдачу не попадает
+	if n := strings.Count(string(data), `"magnetUri"`); n != 3 {
+		t.Errorf("FilterResults() kept %d results, want 3", n)
+	}
+}
+
+func TestResolveLinksDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	j := NewJackett(&Settings{ApiURL: srv.URL})
+	results := []Result{{Title: "slow", Link: srv.URL + "/dl/slow?jackett_apikey=secret&file=x"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if j.ResolveLinks(ctx, results) {
+		t.Error("ResolveLinks() = true for a link that did not resolve in time")
+	}
+	// на ссылки уходит только часть времени запроса
+	if d := time.Since(start); d > 700*time.Millisecond {
+		t.Errorf("ResolveLinks() took %v with 1s deadline", d)
+	}
+
+	data, err := j.FilterResults(results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || string(data) != "[]" {
+		t.Errorf("FilterResults() = %s, want unresolved link dropped", data)
+	}
+}
@@ -0,0 +1,181 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+var ErrInvalidInterval = errors.New("storage quota interval must be positive")
+
+// Engine - учёт места и удаление раздач встроенного клиента (engine.Engine)
+type Engine interface {
+	Usage() []engine.Usage
+	Evict(infoHash string) error
+}
+
+// TorrentUsage - место, занятое раздачей
+type TorrentUsage struct {
+	engine.Usage
+	Pinned    bool // раздачу смотрят, удалять её нельзя
+	Evictable bool // скачана целиком и не смотрится, может быть удалена при нехватке места
+}
+
+// Report - занятое место по всем раздачам. Torrents отсортированы в порядке удаления:
+// первыми идут раздачи, которые дольше всех не смотрели.
+type Report struct {
+	Quota    int64 // 0 - без ограничения
+	Used     int64
+	Torrents []TorrentUsage
+}
+
+// Manager следит, чтобы данные раздач не занимали больше квоты. При превышении удаляет
+// скачанные целиком раздачи, начиная с тех, что дольше всех не смотрели.
+// Недокачанные раздачи и раздачи с открытыми потоками не удаляются. Раздачи, не выполнившие
+// требования трекера, удаляются последними, когда больше удалять нечего.
+type Manager struct {
+	log    *slog.Logger
+	cfg    config.Quota
+	engine Engine
+
+	mu sync.Mutex // одна проверка за раз
+}
+
+// New создаёт менеджер квоты. Отчёт по занятому месту работает и с выключенной квотой,
+// интервал проверяется только у включённой.
+func New(log *slog.Logger, cfg config.Quota, engine Engine) (*Manager, error) {
+	if cfg.Enabled && cfg.Interval <= 0 {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidInterval, cfg.Interval)
+	}
+
+	return &Manager{
+		log:    log.With(slog.String("component", "services/quota")),
+		cfg:    cfg,
+		engine: engine,
+	}, nil
+}
+
+// Run проверяет квоту сразу и затем с интервалом из конфига, пока не отменён ctx
+func (m *Manager) Run(ctx context.Context) {
+	m.log.Info("storage quota manager started",
+		slog.Int64("max_size_mb", m.cfg.MaxSizeMB),
+		slog.String("interval", m.cfg.Interval.String()),
+	)
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.Enforce()
+
+		select {
+		case <-ctx.Done():
+			m.log.Info("storage quota manager stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report возвращает занятое место по раздачам
+func (m *Manager) Report() Report {
+	r := Report{Torrents: make([]TorrentUsage, 0)}
+	if m.cfg.Enabled {
+		r.Quota = m.cfg.MaxSizeMB << 20
+	}
+
+	for _, u := range m.engine.Usage() {
+		r.Used += u.Bytes
+		r.Torrents = append(r.Torrents, TorrentUsage{
+			Usage:     u,
+			Pinned:    u.Streams > 0,
+			Evictable: u.Complete && u.Streams == 0,
+		})
+	}
+	slices.SortStableFunc(r.Torrents, func(a, b TorrentUsage) int {
+		return lastUse(a.Usage).Compare(lastUse(b.Usage))
+	})
+
+	return r
+}
+
+// Enforce удаляет раздачи, пока занятое место больше квоты, и возвращает info-hash удалённых
+func (m *Manager) Enforce() []string {
+	if !m.cfg.Enabled {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.Report()
+	if r.Used <= r.Quota {
+		return nil
+	}
+
+	var evicted []string
+	// сначала раздачи, выполнившие требования трекера, затем остальные
+	for _, requirementsMet := range []bool{true, false} {
+		for _, t := range r.Torrents {
+			if r.Used <= r.Quota {
+				break
+			}
+			if !t.Evictable || t.RequirementsMet != requirementsMet {
+				continue
+			}
+
+			err := m.engine.Evict(t.InfoHash)
+			if errors.Is(err, engine.ErrStreaming) || errors.Is(err, engine.ErrNotFound) {
+				continue // раздачу начали смотреть или удалили после составления отчёта
+			}
+			if err != nil {
+				m.log.Error("failed to evict torrent", slog.String("info_hash", t.InfoHash), sl.Err(err))
+				continue
+			}
+			r.Used -= t.Bytes
+			evicted = append(evicted, t.InfoHash)
+
+			if !requirementsMet {
+				m.log.Warn("torrent evicted before seeding requirements are met, nothing else to evict",
+					slog.String("info_hash", t.InfoHash),
+					slog.String("name", t.Name),
+					slog.Int64("bytes", t.Bytes),
+				)
+				continue
+			}
+			m.log.Info("torrent evicted",
+				slog.String("info_hash", t.InfoHash),
+				slog.String("name", t.Name),
+				slog.Int64("bytes", t.Bytes),
+				slog.Time("watched_at", t.WatchedAt),
+			)
+		}
+	}
+
+	if r.Used > r.Quota {
+		// остались недокачанные или просматриваемые раздачи, они станут кандидатами,
+		// когда докачаются и их закроют
+		m.log.Warn("storage quota exceeded, nothing left to evict",
+			slog.Int64("used", r.Used),
+			slog.Int64("quota", r.Quota),
+		)
+	}
+
+	return evicted
+}
+
+// lastUse - когда раздачу последний раз смотрели, а если не смотрели - когда добавили
+func lastUse(u engine.Usage) time.Time {
+	if u.WatchedAt.IsZero() {
+		return u.AddedAt
+	}
+	return u.WatchedAt
+}
@@ -0,0 +1,129 @@
+package quota
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+)
+
+type mockEngine struct {
+	torrents []engine.Usage
+	evicted  []string
+}
+
+func (e *mockEngine) Usage() []engine.Usage {
+	return e.torrents
+}
+
+func (e *mockEngine) Evict(infoHash string) error {
+	for i, u := range e.torrents {
+		if u.InfoHash == infoHash {
+			if u.Streams > 0 {
+				return engine.ErrStreaming
+			}
+			e.torrents = slices.Delete(e.torrents, i, i+1)
+			e.evicted = append(e.evicted, infoHash)
+			return nil
+		}
+	}
+	return engine.ErrNotFound
+}
+
+func TestEnforce(t *testing.T) {
+	now := time.Now()
+	const mb = 1 << 20
+	e := &mockEngine{torrents: []engine.Usage{
+		// смотрели давно, но смотрят и сейчас
+		{InfoHash: "watching", Bytes: 30 * mb, Complete: true, Streams: 1, WatchedAt: now.Add(-72 * time.Hour)},
+		// не смотрели, добавлена раньше всех
+		{InfoHash: "never", Bytes: 20 * mb, Complete: true, AddedAt: now.Add(-48 * time.Hour)},
+		{InfoHash: "old", Bytes: 20 * mb, Complete: true, WatchedAt: now.Add(-24 * time.Hour)},
+		// недокачанная, хотя и самая давняя
+		{InfoHash: "downloading", Bytes: 20 * mb, AddedAt: now.Add(-96 * time.Hour)},
+		{InfoHash: "recent", Bytes: 20 * mb, Complete: true, WatchedAt: now.Add(-time.Hour)},
+	}}
+
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Quota{Enabled: true, MaxSizeMB: 75, Interval: time.Minute}, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := m.Report()
+	if r.Used != 110*mb || r.Quota != 75*mb {
+		t.Fatalf("report used = %d, quota = %d", r.Used, r.Quota)
+	}
+	var order []string
+	for _, u := range r.Torrents {
+		order = append(order, u.InfoHash)
+	}
+	if want := []string{"downloading", "watching", "never", "old", "recent"}; !slices.Equal(order, want) {
+		t.Errorf("eviction order = %v, want %v", order, want)
+	}
+
+	// 110 MB при квоте 75: удаляются never и old, после них занято 70
+	evicted := m.Enforce()
+	if want := []string{"never", "old"}; !slices.Equal(evicted, want) {
+		t.Errorf("Enforce() = %v, want %v", evicted, want)
+	}
+	if r := m.Report(); r.Used != 70*mb {
+		t.Errorf("used after Enforce() = %d, want %d", r.Used, 70*mb)
+	}
+
+	// в квоте - ничего не удаляется
+	if evicted := m.Enforce(); len(evicted) != 0 {
+		t.Errorf("second Enforce() = %v, want nothing", evicted)
+	}
+}
+
+func TestEnforceDisabled(t *testing.T) {
+	e := &mockEngine{torrents: []engine.Usage{{InfoHash: "a", Bytes: 1 << 30, Complete: true}}}
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Quota{MaxSizeMB: 1}, e)
+	if err != nil {
+		t.Fatalf("New() with disabled quota and no interval: error = %v", err)
+	}
+
+	if evicted := m.Enforce(); len(evicted) != 0 || len(e.evicted) != 0 {
+		t.Errorf("Enforce() with disabled quota = %v", evicted)
+	}
+	if r := m.Report(); r.Quota != 0 || r.Used != 1<<30 {
+		t.Errorf("report = %+v, want usage without quota", r)
+	}
+}
+
+func TestInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		cfg := config.Quota{Enabled: true, MaxSizeMB: 1, Interval: interval}
+		if _, err := New(slogdiscard.NewDiscardLogger(), cfg, &mockEngine{}); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("New() with interval %s: error = %v, want %v", interval, err, ErrInvalidInterval)
+		}
+	}
+}
+
+func TestEnforceSeedingRequirements(t *testing.T) {
+	now := time.Now()
+	const mb = 1 << 20
+	e := &mockEngine{torrents: []engine.Usage{
+		// самая давняя, но трекер требует ещё раздавать
+		{InfoHash: "unmet", Bytes: 40 * mb, Complete: true, AddedAt: now.Add(-48 * time.Hour)},
+		{InfoHash: "met", Bytes: 40 * mb, Complete: true, AddedAt: now.Add(-time.Hour), RequirementsMet: true},
+	}}
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Quota{Enabled: true, MaxSizeMB: 50, Interval: time.Minute}, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if evicted := m.Enforce(); !slices.Equal(evicted, []string{"met"}) {
+		t.Errorf("Enforce() = %v, want [met]", evicted)
+	}
+
+	// больше удалять нечего: удаляется и раздача с невыполненными требованиями
+	e.torrents = append(e.torrents, engine.Usage{InfoHash: "met2", Bytes: 20 * mb, Complete: true, AddedAt: now, RequirementsMet: true})
+	m.cfg.MaxSizeMB = 10
+	if evicted := m.Enforce(); !slices.Equal(evicted, []string{"met2", "unmet"}) {
+		t.Errorf("Enforce() over quota = %v, want [met2 unmet]", evicted)
+	}
+}
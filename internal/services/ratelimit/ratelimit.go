@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+
+	"torrentServer/internal/config"
+)
+
+// Token bucket в Redis. Скрипт выполняется атомарно, а время берётся у самого Redis,
+// поэтому несколько реплик сервера видят одни и те же счётчики.
+//
+// KEYS[1] - ключ бакета, ARGV[1] - скорость пополнения (токенов в секунду), ARGV[2] - ёмкость.
+// Возвращает {разрешено (0/1), через сколько мс появится токен, сколько токенов осталось}.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry_after = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+
+return {allowed, retry_after, math.floor(tokens)}
+`)
+
+const keyPrefix = "ratelimit:"
+
+type Result struct {
+	Allowed    bool
+	Limit      int           // ёмкость бакета
+	Remaining  int           // сколько запросов ещё можно сделать без ожидания
+	RetryAfter time.Duration // через сколько можно повторить запрос, если он отклонён
+}
+
+type Limiter struct {
+	client redis.Scripter
+}
+
+func New(client redis.Scripter) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Allow расходует один токен из бакета key.
+// При нулевом лимите запрос всегда разрешён.
+func (l *Limiter) Allow(ctx context.Context, key string, limit config.Limit) (Result, error) {
+	const op = "services.ratelimit.Allow"
+
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+
+	res, err := tokenBucket.Run(ctx, l.client, []string{keyPrefix + key}, rate, burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(res) != 3 {
+		return Result{}, fmt.Errorf("%s: unexpected script result %v", op, res)
+	}
+
+	return Result{
+		Allowed:    res[0] == 1,
+		Limit:      burst,
+		Remaining:  int(res[2]),
+		RetryAfter: time.Duration(res[1]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+
+	"torrentServer/internal/config"
+)
+
+func TestAllow(t *testing.T) {
+	m := miniredis.RunT(t)
+	l := New(redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	ctx := context.Background()
+	limit := config.Limit{Requests: 60, Per: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(ctx, "ip:127.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("Allow() error: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Errorf("Allow() #%d = %+v, want allowed with %d remaining", i, res, 2-i)
+		}
+	}
+
+	res, err := l.Allow(ctx, "ip:127.0.0.1", limit)
+	if err != nil {
+		t.Fatalf("Allow() error: %v", err)
+	}
+	if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > time.Second {
+		t.Errorf("Allow() after burst = %+v, want rejected with retry after <= 1s", res)
+	}
+
+	// другой ключ считается отдельно
+	if res, _ := l.Allow(ctx, "ip:10.0.0.1", limit); !res.Allowed {
+		t.Errorf("Allow() for another key = %+v, want allowed", res)
+	}
+
+	if res, _ := l.Allow(ctx, "ip:127.0.0.1", config.Limit{}); !res.Allowed {
+		t.Errorf("Allow() with zero limit = %+v, want allowed", res)
+	}
+}
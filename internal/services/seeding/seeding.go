@@ -0,0 +1,171 @@
+// Package seeding - политика раздачи: скачанная раздача продолжает раздаваться, пока не выполнены
+// требования трекера к рейтингу и времени раздачи, после этого она ставится на паузу или удаляется.
+package seeding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/services/engine"
+)
+
+// Действия над раздачей, которая больше не должна раздаваться
+const (
+	ActionPause  = "pause"
+	ActionRemove = "remove" // удалить из движка, оставив файлы
+	ActionDelete = "delete" // удалить вместе с файлами
+)
+
+// Причины остановки раздачи
+const (
+	ReasonRequirements = "requirements_met" // выполнены требования трекера или требования по умолчанию
+	ReasonMaxRatio     = "max_ratio"
+	ReasonMaxSeedTime  = "max_seed_time"
+)
+
+var (
+	ErrInvalidAction   = errors.New("seeding action must be pause, remove or delete")
+	ErrInvalidInterval = errors.New("seeding interval must be positive")
+)
+
+// Engine - раздачи встроенного клиента (engine.Engine)
+type Engine interface {
+	List() []engine.Status
+	Pause(infoHash string) (engine.Status, error)
+	Remove(infoHash string, deleteData bool) error
+	Evict(infoHash string) error
+}
+
+// Decision - раздача, к которой применена политика
+type Decision struct {
+	InfoHash string
+	Name     string
+	Reason   string
+	Action   string
+}
+
+// Manager с интервалом из конфига проверяет скачанные раздачи и останавливает те,
+// что выполнили требования трекера или достигли глобальных ограничений
+type Manager struct {
+	log    *slog.Logger
+	cfg    config.Seeding
+	engine Engine
+
+	mu sync.Mutex // одна проверка за раз
+}
+
+func New(log *slog.Logger, cfg config.Seeding, engine Engine) (*Manager, error) {
+	switch cfg.Action {
+	case ActionPause, ActionRemove, ActionDelete:
+	default:
+		return nil, fmt.Errorf("%w, got %q", ErrInvalidAction, cfg.Action)
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidInterval, cfg.Interval)
+	}
+
+	return &Manager{
+		log:    log.With(slog.String("component", "services/seeding")),
+		cfg:    cfg,
+		engine: engine,
+	}, nil
+}
+
+// Run проверяет раздачи сразу и затем с интервалом из конфига, пока не отменён ctx
+func (m *Manager) Run(ctx context.Context) {
+	m.log.Info("seeding policy started",
+		slog.String("action", m.cfg.Action),
+		slog.String("interval", m.cfg.Interval.String()),
+	)
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.Enforce()
+
+		select {
+		case <-ctx.Done():
+			m.log.Info("seeding policy stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce применяет действие из конфига к раздачам, которым пора перестать раздаваться
+func (m *Manager) Enforce() []Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var decisions []Decision
+	for _, s := range m.engine.List() {
+		reason, ok := m.Check(s)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch m.cfg.Action {
+		case ActionPause:
+			_, err = m.engine.Pause(s.InfoHash)
+		case ActionRemove:
+			err = m.engine.Remove(s.InfoHash, false)
+		case ActionDelete:
+			err = m.engine.Evict(s.InfoHash)
+		}
+		if errors.Is(err, engine.ErrStreaming) || errors.Is(err, engine.ErrNotFound) {
+			continue // раздачу начали смотреть или удалили после составления списка
+		}
+		if err != nil {
+			m.log.Error("failed to stop seeding", slog.String("info_hash", s.InfoHash), sl.Err(err))
+			continue
+		}
+
+		decisions = append(decisions, Decision{InfoHash: s.InfoHash, Name: s.Name, Reason: reason, Action: m.cfg.Action})
+		m.log.Info("seeding stopped",
+			slog.String("info_hash", s.InfoHash),
+			slog.String("name", s.Name),
+			slog.String("reason", reason),
+			slog.String("action", m.cfg.Action),
+			slog.Float64("ratio", s.Ratio),
+			slog.Duration("seeding_time", s.SeedingTime),
+		)
+	}
+
+	return decisions
+}
+
+// Check решает, пора ли раздаче перестать раздаваться. Глобальные ограничения проверяются первыми
+// и действуют даже при невыполненных требованиях трекера. Требования трекера должны быть выполнены
+// оба; раздачи без требований и без требований по умолчанию раздаются без ограничений.
+func (m *Manager) Check(s engine.Status) (string, bool) {
+	if s.State != engine.StateSeeding || len(s.Streams) > 0 {
+		return "", false
+	}
+
+	switch {
+	case m.cfg.MaxRatio > 0 && s.Ratio >= m.cfg.MaxRatio:
+		return ReasonMaxRatio, true
+	case m.cfg.MaxSeedTime > 0 && s.SeedingTime >= m.cfg.MaxSeedTime:
+		return ReasonMaxSeedTime, true
+	}
+
+	minRatio, minSeedTime := s.MinimumRatio, s.MinimumSeedTime
+	if minRatio == 0 && minSeedTime == 0 {
+		minRatio, minSeedTime = m.cfg.DefaultRatio, m.cfg.DefaultSeedTime
+	}
+	if minRatio == 0 && minSeedTime == 0 {
+		return "", false
+	}
+	if s.Ratio >= minRatio && s.SeedingTime >= minSeedTime {
+		return ReasonRequirements, true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package seeding
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/services/engine"
+)
+
+type mockEngine struct {
+	torrents []engine.Status
+	paused   []string
+	removed  []string
+	deleted  []string
+}
+
+func (e *mockEngine) List() []engine.Status {
+	return e.torrents
+}
+
+func (e *mockEngine) Pause(infoHash string) (engine.Status, error) {
+	for i, s := range e.torrents {
+		if s.InfoHash == infoHash {
+			e.torrents[i].State = engine.StatePaused
+			e.paused = append(e.paused, infoHash)
+			return e.torrents[i], nil
+		}
+	}
+	return engine.Status{}, engine.ErrNotFound
+}
+
+func (e *mockEngine) Remove(infoHash string, deleteData bool) error {
+	e.removed = append(e.removed, infoHash)
+	return nil
+}
+
+func (e *mockEngine) Evict(infoHash string) error {
+	e.deleted = append(e.deleted, infoHash)
+	return nil
+}
+
+func TestEnforce(t *testing.T) {
+	e := &mockEngine{torrents: []engine.Status{
+		// требования трекера выполнены
+		{InfoHash: "done", State: engine.StateSeeding, Ratio: 1.2, SeedingTime: 50 * time.Hour, MinimumRatio: 1, MinimumSeedTime: 48 * time.Hour},
+		// рейтинг набран, но время раздачи ещё нет
+		{InfoHash: "ratio-only", State: engine.StateSeeding, Ratio: 2, SeedingTime: time.Hour, MinimumRatio: 1, MinimumSeedTime: 48 * time.Hour},
+		// требования выполнены, но раздачу смотрят
+		{InfoHash: "watching", State: engine.StateSeeding, Ratio: 3, SeedingTime: 72 * time.Hour, MinimumRatio: 1,
+			Streams: []engine.Stream{{FileIndex: 0}}},
+		// ещё качается
+		{InfoHash: "downloading", State: engine.StateDownloading, Ratio: 5, MinimumRatio: 1},
+		// без требований трекера: действуют требования по умолчанию
+		{InfoHash: "public", State: engine.StateSeeding, Ratio: 0.6, SeedingTime: 2 * time.Hour},
+		// глобальное ограничение действует раньше требований трекера
+		{InfoHash: "greedy", State: engine.StateSeeding, Ratio: 10, SeedingTime: time.Hour, MinimumSeedTime: 48 * time.Hour},
+	}}
+
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Seeding{
+		Action:          ActionPause,
+		Interval:        time.Minute,
+		DefaultRatio:    0.5,
+		DefaultSeedTime: time.Hour,
+		MaxRatio:        5,
+	}, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var got []string
+	for _, d := range m.Enforce() {
+		got = append(got, d.InfoHash+":"+d.Reason)
+	}
+	want := []string{"done:" + ReasonRequirements, "public:" + ReasonRequirements, "greedy:" + ReasonMaxRatio}
+	if !slices.Equal(got, want) {
+		t.Errorf("Enforce() = %v, want %v", got, want)
+	}
+	if want := []string{"done", "public", "greedy"}; !slices.Equal(e.paused, want) {
+		t.Errorf("paused = %v, want %v", e.paused, want)
+	}
+
+	// поставленные на паузу раздачи больше не проверяются
+	if decisions := m.Enforce(); len(decisions) != 0 {
+		t.Errorf("second Enforce() = %+v, want nothing", decisions)
+	}
+}
+
+func TestWithoutRequirements(t *testing.T) {
+	e := &mockEngine{torrents: []engine.Status{
+		{InfoHash: "public", State: engine.StateSeeding, Ratio: 100, SeedingTime: 1000 * time.Hour},
+	}}
+	m, err := New(slogdiscard.NewDiscardLogger(), config.Seeding{Action: ActionDelete, Interval: time.Minute}, e)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// ни требований трекера, ни требований по умолчанию: раздаётся без ограничений
+	if decisions := m.Enforce(); len(decisions) != 0 || len(e.deleted) != 0 {
+		t.Errorf("Enforce() = %+v, deleted = %v, want nothing", decisions, e.deleted)
+	}
+}
+
+func TestInvalidAction(t *testing.T) {
+	if _, err := New(slogdiscard.NewDiscardLogger(), config.Seeding{Action: "stop", Interval: time.Minute}, &mockEngine{}); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("New() error = %v, want %v", err, ErrInvalidAction)
+	}
+}
+
+func TestInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		cfg := config.Seeding{Action: ActionPause, Interval: interval}
+		if _, err := New(slogdiscard.NewDiscardLogger(), cfg, &mockEngine{}); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("New() with interval %s: error = %v, want %v", interval, err, ErrInvalidInterval)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package warmer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/sl"
+	"torrentServer/internal/storage"
+)
+
+type Query struct {
+	Query      string
+	Categories []uint
+	SafeOnly   int
+}
+
+func (q Query) key() string {
+	categories := slices.Clone(q.Categories)
+	slices.Sort(categories)
+	return fmt.Sprintf("%s|%v|%d", q.Query, categories, q.SafeOnly)
+}
+
+// History - источник популярных запросов
+type History interface {
+	TopSearches(limit int, since time.Time) ([]storage.Search, error)
+}
+
+var ErrInvalidInterval = errors.New("cache warmer interval must be positive")
+
+// RefreshFunc заново запрашивает результаты у Jackett и кладёт их в кэш.
+// Возвращает количество полученных результатов.
+type RefreshFunc func(ctx context.Context, q Query) (int, error)
+
+// Warmer периодически обновляет кэш для популярных запросов,
+// чтобы они не протухали и отдавались без обращения к Jackett
+type Warmer struct {
+	log     *slog.Logger
+	cfg     config.CacheWarmer
+	history History
+	refresh RefreshFunc
+}
+
+func New(log *slog.Logger, cfg config.CacheWarmer, history History, refresh RefreshFunc) (*Warmer, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidInterval, cfg.Interval)
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	return &Warmer{
+		log:     log.With(slog.String("component", "services/warmer")),
+		cfg:     cfg,
+		history: history,
+		refresh: refresh,
+	}, nil
+}
+
+// Run обновляет кэш сразу и затем с интервалом из конфига, пока не отменён ctx
+func (w *Warmer) Run(ctx context.Context) {
+	w.log.Info("cache warmer started", slog.String("interval", w.cfg.Interval.String()))
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.log.Info("cache warmer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce обновляет все запросы один раз, одновременно выполняется не больше Concurrency запросов
+func (w *Warmer) RunOnce(ctx context.Context) {
+	queries := w.queries()
+	if len(queries) == 0 {
+		w.log.Debug("nothing to warm")
+		return
+	}
+
+	start := time.Now()
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var refreshed, failed int
+
+loop:
+	for _, q := range queries {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(q Query) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			t := time.Now()
+			count, err := w.refresh(ctx, q)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				w.log.Warn("failed to warm query", slog.String("query", q.Query), sl.Err(err))
+				return
+			}
+			refreshed++
+			w.log.Debug("query warmed",
+				slog.String("query", q.Query),
+				slog.Any("categories", q.Categories),
+				slog.Int("results", count),
+				slog.String("duration", time.Since(t).String()),
+			)
+		}(q)
+	}
+	wg.Wait()
+
+	w.log.Info("cache warming completed",
+		slog.Int("refreshed", refreshed),
+		slog.Int("failed", failed),
+		slog.String("duration", time.Since(start).String()),
+	)
+}
+
+// queries собирает запросы из конфига и истории поиска без повторов
+func (w *Warmer) queries() []Query {
+	queries := make([]Query, 0, len(w.cfg.Queries)+w.cfg.TopN)
+	seen := make(map[string]struct{})
+	add := func(q Query) {
+		if q.Query == "" {
+			return
+		}
+		if _, ok := seen[q.key()]; ok {
+			return
+		}
+		seen[q.key()] = struct{}{}
+		queries = append(queries, q)
+	}
+
+	for _, q := range w.cfg.Queries {
+		add(Query{Query: q.Query, Categories: q.Categories, SafeOnly: q.SafeOnly})
+	}
+
+	if w.history != nil && w.cfg.TopN > 0 {
+		searches, err := w.history.TopSearches(w.cfg.TopN, time.Now().Add(-w.cfg.HistoryWindow))
+		if err != nil {
+			w.log.Error("failed to load search history", sl.Err(err))
+		}
+		for _, s := range searches {
+			add(Query{Query: s.Query, Categories: s.Categories, SafeOnly: s.SafeOnly})
+		}
+	}
+
+	return queries
+}
@@ -0,0 +1,81 @@
+package warmer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"torrentServer/internal/config"
+	"torrentServer/internal/lib/logger/handlers/slogdiscard"
+	"torrentServer/internal/storage"
+)
+
+type mockHistory []storage.Search
+
+func (h mockHistory) TopSearches(limit int, since time.Time) ([]storage.Search, error) {
+	return h, nil
+}
+
+func TestRunOnce(t *testing.T) {
+	cfg := config.CacheWarmer{
+		Interval:    time.Minute,
+		TopN:        10,
+		Concurrency: 2,
+		Queries: []config.WarmQuery{
+			{Query: "ubuntu", Categories: []uint{4000}},
+		},
+	}
+	history := mockHistory{
+		{Query: "ubuntu", Categories: []uint{4000}},
+		{Query: "debian", Categories: []uint{4000}},
+		{Query: "arch", Categories: []uint{4000, 2000}},
+		{Query: "arch", Categories: []uint{2000, 4000}},
+	}
+
+	var mu sync.Mutex
+	var active, maxActive int32
+	refreshed := make(map[string]int)
+	refresh := func(ctx context.Context, q Query) (int, error) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		mu.Lock()
+		if n > maxActive {
+			maxActive = n
+		}
+		refreshed[q.Query]++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	}
+
+	w, err := New(slogdiscard.NewDiscardLogger(), cfg, history, refresh)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	w.RunOnce(context.Background())
+
+	want := map[string]int{"ubuntu": 1, "debian": 1, "arch": 1}
+	if len(refreshed) != len(want) {
+		t.Fatalf("refreshed = %v, want %v", refreshed, want)
+	}
+	for q, n := range want {
+		if refreshed[q] != n {
+			t.Errorf("refreshed[%q] = %d, want %d", q, refreshed[q], n)
+		}
+	}
+	if maxActive > int32(cfg.Concurrency) {
+		t.Errorf("max concurrent refreshes = %d, want <= %d", maxActive, cfg.Concurrency)
+	}
+}
+
+func TestInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		cfg := config.CacheWarmer{Enabled: true, Interval: interval}
+		if _, err := New(slogdiscard.NewDiscardLogger(), cfg, mockHistory{}, nil); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("New() with interval %s: error = %v, want %v", interval, err, ErrInvalidInterval)
+		}
+	}
+}
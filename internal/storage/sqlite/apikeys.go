@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"torrentServer/internal/storage"
+)
+
+func (s *Storage) SaveAPIKey(key storage.APIKey) error {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	endpoints, err := json.Marshal(key.Endpoints)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(`
+	INSERT INTO api_keys(id, name, key_hash, endpoints, daily_quota, policy, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.KeyHash, string(endpoints), key.DailyQuota, key.Policy, key.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+const apiKeyColumns = `id, name, key_hash, endpoints, daily_quota, policy, created_at, revoked_at`
+
+func (s *Storage) APIKeyByHash(hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.APIKeyByHash"
+
+	key, err := scanAPIKey(s.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) APIKeyByID(id string) (storage.APIKey, error) {
+	const op = "storage.sqlite.APIKeyByID"
+
+	key, err := scanAPIKey(s.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) APIKeys() ([]storage.APIKey, error) {
+	const op = "storage.sqlite.APIKeys"
+
+	rows, err := s.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := make([]storage.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ. Запись остаётся в базе, чтобы была видна в списке ключей.
+func (s *Storage) RevokeAPIKey(id string) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	res, err := s.db.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// IncrementAPIKeyUsage увеличивает счётчик запросов ключа за сутки и возвращает новое значение
+func (s *Storage) IncrementAPIKeyUsage(keyID string, day time.Time) (int, error) {
+	const op = "storage.sqlite.IncrementAPIKeyUsage"
+
+	var count int
+	err := s.db.QueryRow(`
+	INSERT INTO api_key_usage(key_id, day, count) VALUES(?, ?, 1)
+	ON CONFLICT(key_id, day) DO UPDATE SET count = count + 1
+	RETURNING count`, keyID, day.UTC().Format(time.DateOnly)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// DecrementAPIKeyUsage уменьшает счётчик запросов ключа за сутки, если он больше нуля
+func (s *Storage) DecrementAPIKeyUsage(keyID string, day time.Time) error {
+	const op = "storage.sqlite.DecrementAPIKeyUsage"
+
+	_, err := s.db.Exec(`
+	UPDATE api_key_usage SET count = count - 1
+	WHERE key_id = ? AND day = ? AND count > 0`, keyID, day.UTC().Format(time.DateOnly))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var key storage.APIKey
+	var endpoints string
+	var revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &endpoints, &key.DailyQuota, &key.Policy, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+	if err := json.Unmarshal([]byte(endpoints), &key.Endpoints); err != nil {
+		return storage.APIKey{}, err
+	}
+	key.RevokedAt = revokedAt.Time
+
+	return key, nil
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"torrentServer/internal/storage"
+)
+
+// migrateFTS создаёт полнотекстовый индекс по названиям и описаниям раздач.
+// Индекс хранит только токены, сами данные берутся из таблицы torrents,
+// а синхронизацию выполняют триггеры.
+func (s *Storage) migrateFTS() error {
+	var exists int
+	err := s.db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'torrents_fts'").Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS torrents_fts USING fts5(
+		title, description,
+		content='torrents', content_rowid='rowid',
+		tokenize='unicode61 remove_diacritics 2');
+	CREATE TRIGGER IF NOT EXISTS torrents_fts_ai AFTER INSERT ON torrents BEGIN
+		INSERT INTO torrents_fts(rowid, title, description) VALUES (new.rowid, new.title, new.description);
+	END;
+	CREATE TRIGGER IF NOT EXISTS torrents_fts_ad AFTER DELETE ON torrents BEGIN
+		INSERT INTO torrents_fts(torrents_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+	END;
+	CREATE TRIGGER IF NOT EXISTS torrents_fts_au AFTER UPDATE ON torrents BEGIN
+		INSERT INTO torrents_fts(torrents_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+		INSERT INTO torrents_fts(rowid, title, description) VALUES (new.rowid, new.title, new.description);
+	END;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// индекс появился в базе, где уже есть раздачи - заполняем его
+	if exists == 0 {
+		if _, err := s.db.Exec("INSERT INTO torrents_fts(torrents_fts) VALUES ('rebuild')"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SearchTorrents ищет раздачи с magnet-ссылкой по локальному индексу. Условия filter проверяются
+// в запросе, поэтому limit ограничивает уже отфильтрованные раздачи.
+// Результаты отсортированы по релевантности, при равной релевантности - по количеству сидов.
+func (s *Storage) SearchTorrents(query string, filter storage.TorrentFilter, limit int) ([]storage.Torrent, error) {
+	const op = "storage.sqlite.SearchTorrents"
+
+	match := ftsQuery(query)
+	if match == "" {
+		return []storage.Torrent{}, nil
+	}
+
+	where := "torrents_fts MATCH ? AND t.magnet_uri != ''"
+	args := []any{match}
+	if len(filter.Categories) > 0 {
+		in := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Categories)), ", ")
+		where += ` AND EXISTS (SELECT 1 FROM json_each(t.category) c
+			WHERE c.value IN (` + in + `) OR c.value - c.value % 1000 IN (` + in + `))`
+		for range 2 {
+			for _, c := range filter.Categories {
+				args = append(args, c)
+			}
+		}
+	}
+	if filter.Tracker != "" {
+		where += " AND EXISTS (SELECT 1 FROM json_each(t.trackers) tr WHERE tr.value = ?)"
+		args = append(args, filter.Tracker)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT `+prefixColumns("t", torrentColumns)+`
+	FROM torrents_fts f JOIN torrents t ON t.rowid = f.rowid
+	WHERE `+where+`
+	ORDER BY bm25(torrents_fts, 10.0, 1.0), t.seeders DESC
+	LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	torrents := make([]storage.Torrent, 0)
+	for rows.Next() {
+		t, err := scanTorrent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		torrents = append(torrents, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return torrents, nil
+}
+
+// ftsQuery превращает пользовательский запрос в запрос FTS5:
+// каждое слово берётся в кавычки, чтобы спецсимволы не ломали синтаксис,
+// а последнее слово ищется по префиксу.
+func ftsQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, `"`+w+`"`)
+	}
+	terms[len(terms)-1] += "*"
+
+	return strings.Join(terms, " ")
+}
+
+func prefixColumns(prefix, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, p := range parts {
+		parts[i] = prefix + "." + strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"torrentServer/internal/storage"
+)
+
+// SaveSearch увеличивает счётчик запроса в истории поиска
+func (s *Storage) SaveSearch(query string, categories []uint, safeOnly int) error {
+	const op = "storage.sqlite.SaveSearch"
+
+	_, err := s.db.Exec(`
+	INSERT INTO search_history(query, categories, safe_only, count, last_searched)
+	VALUES(?, ?, ?, 1, ?)
+	ON CONFLICT(query, categories, safe_only) DO UPDATE SET
+		count = count + 1,
+		last_searched = excluded.last_searched`,
+		strings.TrimSpace(query), joinCategories(categories), safeOnly, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TopSearches возвращает самые частые запросы, выполнявшиеся после since
+func (s *Storage) TopSearches(limit int, since time.Time) ([]storage.Search, error) {
+	const op = "storage.sqlite.TopSearches"
+
+	rows, err := s.db.Query(`
+	SELECT query, categories, safe_only, count, last_searched
+	FROM search_history
+	WHERE last_searched >= ?
+	ORDER BY count DESC, last_searched DESC
+	LIMIT ?`, since.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	searches := make([]storage.Search, 0, limit)
+	for rows.Next() {
+		var search storage.Search
+		var categories string
+		if err := rows.Scan(&search.Query, &categories, &search.SafeOnly, &search.Count, &search.LastSearched); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if search.Categories, err = splitCategories(categories); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		searches = append(searches, search)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return searches, nil
+}
+
+// joinCategories приводит категории к каноничному виду "1000,5000",
+// чтобы один и тот же запрос не попадал в историю дважды
+func joinCategories(categories []uint) string {
+	sorted := slices.Clone(categories)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	parts := make([]string, 0, len(sorted))
+	for _, c := range sorted {
+		parts = append(parts, strconv.FormatUint(uint64(c), 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitCategories(s string) ([]uint, error) {
+	categories := make([]uint, 0)
+	if s == "" {
+		return categories, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		c, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, uint(c))
+	}
+	return categories, nil
+}
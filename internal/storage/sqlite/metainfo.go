@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"torrentServer/internal/storage"
+)
+
+// SaveMetaInfo кэширует .torrent (bencode), полученный от пиров или загруженный пользователем
+func (s *Storage) SaveMetaInfo(infoHash string, metainfo []byte) error {
+	const op = "storage.sqlite.SaveMetaInfo"
+
+	_, err := s.db.Exec(`
+	INSERT INTO metainfo_cache(info_hash, metainfo, resolved_at) VALUES(?, ?, ?)
+	ON CONFLICT(info_hash) DO UPDATE SET metainfo = excluded.metainfo, resolved_at = excluded.resolved_at`,
+		infoHash, metainfo, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) MetaInfo(infoHash string) ([]byte, error) {
+	const op = "storage.sqlite.MetaInfo"
+
+	var metainfo []byte
+	err := s.db.QueryRow("SELECT metainfo FROM metainfo_cache WHERE info_hash = ?", infoHash).Scan(&metainfo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrMetaInfoMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return metainfo, nil
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"torrentServer/internal/storage"
+)
+
+// SaveSession добавляет раздачу движка или обновляет её состояние.
+// Пустые MetaInfo и Name и нулевые требования трекера не затирают уже сохранённые,
+// счётчики обновляются только через SaveSessionStats.
+func (s *Storage) SaveSession(session storage.Session) error {
+	const op = "storage.sqlite.SaveSession"
+
+	_, err := s.db.Exec(`
+	INSERT INTO engine_sessions(info_hash, magnet, name, metainfo, paused, added_at, minimum_ratio, minimum_seed_time)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(info_hash) DO UPDATE SET
+		magnet = CASE WHEN excluded.magnet != '' THEN excluded.magnet ELSE magnet END,
+		name = CASE WHEN excluded.name != '' THEN excluded.name ELSE name END,
+		metainfo = COALESCE(excluded.metainfo, metainfo),
+		paused = excluded.paused,
+		minimum_ratio = CASE WHEN excluded.minimum_ratio > 0 THEN excluded.minimum_ratio ELSE minimum_ratio END,
+		minimum_seed_time = CASE WHEN excluded.minimum_seed_time > 0 THEN excluded.minimum_seed_time ELSE minimum_seed_time END`,
+		session.InfoHash, session.Magnet, session.Name, nullBytes(session.MetaInfo), session.Paused, session.AddedAt.UTC(),
+		session.MinimumRatio, int64(session.MinimumSeedTime.Seconds()),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Sessions() ([]storage.Session, error) {
+	const op = "storage.sqlite.Sessions"
+
+	rows, err := s.db.Query(`
+	SELECT info_hash, magnet, name, metainfo, paused, added_at, watched_at,
+		minimum_ratio, minimum_seed_time, uploaded, downloaded, seeding_time
+	FROM engine_sessions ORDER BY added_at`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]storage.Session, 0)
+	for rows.Next() {
+		var session storage.Session
+		var watchedAt sql.NullTime
+		var minSeedTime, seedingTime int64
+		if err := rows.Scan(&session.InfoHash, &session.Magnet, &session.Name, &session.MetaInfo, &session.Paused, &session.AddedAt, &watchedAt,
+			&session.MinimumRatio, &minSeedTime, &session.Uploaded, &session.Downloaded, &seedingTime); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		session.WatchedAt = watchedAt.Time
+		session.MinimumSeedTime = time.Duration(minSeedTime) * time.Second
+		session.SeedingTime = time.Duration(seedingTime) * time.Second
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// SetSessionWatched запоминает, когда раздачу последний раз смотрели
+func (s *Storage) SetSessionWatched(infoHash string, at time.Time) error {
+	const op = "storage.sqlite.SetSessionWatched"
+
+	res, err := s.db.Exec("UPDATE engine_sessions SET watched_at = ? WHERE info_hash = ?", at.UTC(), infoHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// SaveSessionStats сохраняет накопленные за все запуски счётчики отданного, скачанного и времени раздачи
+func (s *Storage) SaveSessionStats(infoHash string, uploaded, downloaded int64, seedingTime time.Duration) error {
+	const op = "storage.sqlite.SaveSessionStats"
+
+	res, err := s.db.Exec("UPDATE engine_sessions SET uploaded = ?, downloaded = ?, seeding_time = ? WHERE info_hash = ?",
+		uploaded, downloaded, int64(seedingTime.Seconds()), infoHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(infoHash string) error {
+	const op = "storage.sqlite.DeleteSession"
+
+	res, err := s.db.Exec("DELETE FROM engine_sessions WHERE info_hash = ?", infoHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// nullBytes - пустой срез записывается как NULL, чтобы COALESCE сохранил прежнее значение
+func nullBytes(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
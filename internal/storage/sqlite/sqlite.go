@@ -0,0 +1,281 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"torrentServer/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+type Storage struct {
+	db *sql.DB
+}
+
+func New(storagePath string) (*Storage, error) {
+	const op = "storage.sqlite.New"
+
+	if err := os.MkdirAll(filepath.Dir(storagePath), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite", storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// sqlite не умеет параллельную запись, поэтому держим одно соединение
+	db.SetMaxOpenConns(1)
+
+	s := &Storage{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// migrate создаёт таблицы и добавляет колонки, которых нет в базах, созданных старыми версиями
+func (s *Storage) migrate() error {
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS torrents(
+		info_hash TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		magnet_uri TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL DEFAULT 0,
+		trackers TEXT NOT NULL DEFAULT '[]',
+		seeders INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL);
+	CREATE INDEX IF NOT EXISTS idx_torrents_last_seen ON torrents(last_seen);
+	CREATE TABLE IF NOT EXISTS search_history(
+		query TEXT NOT NULL,
+		categories TEXT NOT NULL,
+		safe_only INTEGER NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		last_searched DATETIME NOT NULL,
+		PRIMARY KEY(query, categories, safe_only));
+	CREATE TABLE IF NOT EXISTS api_keys(
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		endpoints TEXT NOT NULL DEFAULT '[]',
+		daily_quota INTEGER NOT NULL DEFAULT 0,
+		policy TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		revoked_at DATETIME);
+	CREATE TABLE IF NOT EXISTS api_key_usage(
+		key_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY(key_id, day));
+	CREATE TABLE IF NOT EXISTS engine_sessions(
+		info_hash TEXT PRIMARY KEY,
+		magnet TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL DEFAULT '',
+		metainfo BLOB,
+		paused INTEGER NOT NULL DEFAULT 0,
+		added_at DATETIME NOT NULL);
+	CREATE TABLE IF NOT EXISTS metainfo_cache(
+		info_hash TEXT PRIMARY KEY,
+		metainfo BLOB NOT NULL,
+		resolved_at DATETIME NOT NULL);
+	`)
+	if err != nil {
+		return err
+	}
+
+	columns := []struct{ table, name, definition string }{
+		{"torrents", "description", "TEXT NOT NULL DEFAULT ''"},
+		{"torrents", "category", "TEXT NOT NULL DEFAULT '[]'"},
+		{"torrents", "peers", "INTEGER NOT NULL DEFAULT 0"},
+		{"engine_sessions", "watched_at", "DATETIME"},
+		{"torrents", "minimum_ratio", "REAL NOT NULL DEFAULT 0"},
+		{"torrents", "minimum_seed_time", "INTEGER NOT NULL DEFAULT 0"},
+		{"engine_sessions", "minimum_ratio", "REAL NOT NULL DEFAULT 0"},
+		{"engine_sessions", "minimum_seed_time", "INTEGER NOT NULL DEFAULT 0"},
+		{"engine_sessions", "uploaded", "INTEGER NOT NULL DEFAULT 0"},
+		{"engine_sessions", "downloaded", "INTEGER NOT NULL DEFAULT 0"},
+		{"engine_sessions", "seeding_time", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := s.addColumnIfMissing(c.table, c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	return s.migrateFTS()
+}
+
+func (s *Storage) addColumnIfMissing(table, name, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return err
+		}
+		if column == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, definition))
+	return err
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// SaveTorrents сохраняет раздачи из очередной выдачи Jackett.
+// Для уже известных раздач обновляется время последнего появления и количество сидов,
+// а список трекеров дополняется новыми. Из требований трекеров к раздаче остаются самые строгие.
+func (s *Storage) SaveTorrents(torrents []storage.Torrent) error {
+	const op = "storage.sqlite.SaveTorrents"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range torrents {
+		var trackersStr string
+		err := tx.QueryRow("SELECT trackers FROM torrents WHERE info_hash = ?", t.InfoHash).Scan(&trackersStr)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var trackers []string
+		if trackersStr != "" {
+			if err := json.Unmarshal([]byte(trackersStr), &trackers); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		trackersJSON, err := json.Marshal(mergeTrackers(trackers, t.Trackers))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		seen := t.LastSeen
+		if seen.IsZero() {
+			seen = time.Now()
+		}
+
+		categoryJSON, err := json.Marshal(t.Category)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO torrents(info_hash, title, description, category, magnet_uri, size, trackers, seeders, peers, first_seen, last_seen,
+			minimum_ratio, minimum_seed_time)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(info_hash) DO UPDATE SET
+			title = excluded.title,
+			description = CASE WHEN excluded.description != '' THEN excluded.description ELSE torrents.description END,
+			category = excluded.category,
+			magnet_uri = CASE WHEN excluded.magnet_uri != '' THEN excluded.magnet_uri ELSE torrents.magnet_uri END,
+			size = excluded.size,
+			trackers = excluded.trackers,
+			seeders = excluded.seeders,
+			peers = excluded.peers,
+			last_seen = excluded.last_seen,
+			minimum_ratio = max(torrents.minimum_ratio, excluded.minimum_ratio),
+			minimum_seed_time = max(torrents.minimum_seed_time, excluded.minimum_seed_time)`,
+			t.InfoHash, t.Title, t.Description, string(categoryJSON), t.MagnetUri, t.Size, string(trackersJSON),
+			t.Seeders, t.Peers, seen.UTC(), seen.UTC(), t.MinimumRatio, int64(t.MinimumSeedTime.Seconds()),
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+const torrentColumns = `info_hash, title, description, category, magnet_uri, size, trackers, seeders, peers, first_seen, last_seen,
+	minimum_ratio, minimum_seed_time`
+
+// Torrent возвращает запись о раздаче по info-hash
+func (s *Storage) Torrent(infoHash string) (storage.Torrent, error) {
+	const op = "storage.sqlite.Torrent"
+
+	row := s.db.QueryRow("SELECT "+torrentColumns+" FROM torrents WHERE info_hash = ?", infoHash)
+	t, err := scanTorrent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Torrent{}, storage.ErrTorrentNotFound
+	}
+	if err != nil {
+		return storage.Torrent{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTorrent(row scanner) (storage.Torrent, error) {
+	var t storage.Torrent
+	var categoryStr, trackersStr string
+	var seedTime int64
+	err := row.Scan(&t.InfoHash, &t.Title, &t.Description, &categoryStr, &t.MagnetUri, &t.Size,
+		&trackersStr, &t.Seeders, &t.Peers, &t.FirstSeen, &t.LastSeen, &t.MinimumRatio, &seedTime)
+	if err != nil {
+		return storage.Torrent{}, err
+	}
+	t.MinimumSeedTime = time.Duration(seedTime) * time.Second
+
+	if err := json.Unmarshal([]byte(categoryStr), &t.Category); err != nil {
+		return storage.Torrent{}, err
+	}
+	if err := json.Unmarshal([]byte(trackersStr), &t.Trackers); err != nil {
+		return storage.Torrent{}, err
+	}
+
+	return t, nil
+}
+
+func mergeTrackers(existing, added []string) []string {
+	set := make(map[string]struct{}, len(existing)+len(added))
+	merged := make([]string, 0, len(existing)+len(added))
+	for _, tr := range append(existing, added...) {
+		if tr == "" {
+			continue
+		}
+		if _, ok := set[tr]; ok {
+			continue
+		}
+		set[tr] = struct{}{}
+		merged = append(merged, tr)
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"torrentServer/internal/storage"
+)
+
+func TestSaveTorrents(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer s.Close()
+
+	first := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	err = s.SaveTorrents([]storage.Torrent{{
+		InfoHash:  "828d3f022e50c0d038e64b7d2c981645a812ce2b",
+		Title:     "Gardeners.World.S53E12",
+		MagnetUri: "magnet:?xt=urn:btih:828d3f022e50c0d038e64b7d2c981645a812ce2b",
+		Trackers:  []string{"RARBG"},
+		Seeders:   10,
+		LastSeen:  first,
+		// требования трекеров: остаются самые строгие
+		MinimumRatio:    1,
+		MinimumSeedTime: time.Hour,
+	}})
+	if err != nil {
+		t.Fatalf("SaveTorrents() error: %v", err)
+	}
+
+	err = s.SaveTorrents([]storage.Torrent{{
+		InfoHash: "828d3f022e50c0d038e64b7d2c981645a812ce2b",
+		Title:    "Gardeners.World.S53E12",
+		Trackers: []string{"1337x", "RARBG"},
+		Seeders:  3,
+		LastSeen: second,
+		// у второго трекера мягче рейтинг, но дольше время раздачи
+		MinimumRatio:    0.5,
+		MinimumSeedTime: 48 * time.Hour,
+	}})
+	if err != nil {
+		t.Fatalf("SaveTorrents() error: %v", err)
+	}
+
+	got, err := s.Torrent("828d3f022e50c0d038e64b7d2c981645a812ce2b")
+	if err != nil {
+		t.Fatalf("Torrent() error: %v", err)
+	}
+	if want := []string{"1337x", "RARBG"}; !reflect.DeepEqual(got.Trackers, want) {
+		t.Errorf("Trackers = %v, want %v", got.Trackers, want)
+	}
+	if got.Seeders != 3 {
+		t.Errorf("Seeders = %d, want 3", got.Seeders)
+	}
+	if got.MinimumRatio != 1 || got.MinimumSeedTime != 48*time.Hour {
+		t.Errorf("requirements = %v, %v, want 1, 48h", got.MinimumRatio, got.MinimumSeedTime)
+	}
+	if !got.FirstSeen.Equal(first) || !got.LastSeen.Equal(second) {
+		t.Errorf("FirstSeen, LastSeen = %v, %v, want %v, %v", got.FirstSeen, got.LastSeen, first, second)
+	}
+	if got.MagnetUri == "" {
+		t.Errorf("MagnetUri was overwritten with empty value")
+	}
+
+	if _, err := s.Torrent("unknown"); !errors.Is(err, storage.ErrTorrentNotFound) {
+		t.Errorf("Torrent(unknown) error = %v, want %v", err, storage.ErrTorrentNotFound)
+	}
+}
+
+func TestSearchTorrents(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer s.Close()
+
+	err = s.SaveTorrents([]storage.Torrent{
+		{InfoHash: "aaa", Title: "Gardeners.World.S53E12.720p.HDTV", MagnetUri: "magnet:?xt=urn:btih:aaa", Seeders: 10,
+			Category: []uint{5040}, Trackers: []string{"EZTV"}},
+		{InfoHash: "bbb", Title: "RUSH Oakland Coliseum 1992", Description: "speed corrected gardeners edition",
+			MagnetUri: "magnet:?xt=urn:btih:bbb", Seeders: 1, Category: []uint{3000}, Trackers: []string{"EZTV", "Internet Archive"}},
+		{InfoHash: "ccc", Title: "Ubuntu 24.04 desktop amd64", MagnetUri: "magnet:?xt=urn:btih:ccc"},
+		// без magnet-ссылки раздачу из индекса не открыть
+		{InfoHash: "ddd", Title: "Gardeners without magnet", Seeders: 100},
+	})
+	if err != nil {
+		t.Fatalf("SaveTorrents() error: %v", err)
+	}
+
+	tests := []struct {
+		query  string
+		filter storage.TorrentFilter
+		want   []string
+	}{
+		{"gardeners world", storage.TorrentFilter{}, []string{"aaa"}},
+		{"gardeners", storage.TorrentFilter{}, []string{"aaa", "bbb"}},
+		{"ubu", storage.TorrentFilter{}, []string{"ccc"}},
+		{`"); DROP TABLE torrents; --`, storage.TorrentFilter{}, []string{}},
+		// родительская категория включает подкатегории
+		{"gardeners", storage.TorrentFilter{Categories: []uint{5000}}, []string{"aaa"}},
+		{"gardeners", storage.TorrentFilter{Categories: []uint{3000, 5040}}, []string{"aaa", "bbb"}},
+		{"gardeners", storage.TorrentFilter{Categories: []uint{2000}}, []string{}},
+		{"gardeners", storage.TorrentFilter{Tracker: "Internet Archive"}, []string{"bbb"}},
+	}
+	for _, test := range tests {
+		got, err := s.SearchTorrents(test.query, test.filter, 10)
+		if err != nil {
+			t.Fatalf("SearchTorrents(%q, %+v) error: %v", test.query, test.filter, err)
+		}
+		hashes := make([]string, 0, len(got))
+		for _, tr := range got {
+			hashes = append(hashes, tr.InfoHash)
+		}
+		if !reflect.DeepEqual(hashes, test.want) {
+			t.Errorf("SearchTorrents(%q, %+v) = %v, want %v", test.query, test.filter, hashes, test.want)
+		}
+	}
+	// limit ограничивает уже отфильтрованные раздачи: более релевантная aaa не вытесняет bbb
+	got, err := s.SearchTorrents("gardeners", storage.TorrentFilter{Categories: []uint{3000}}, 1)
+	if err != nil || len(got) != 1 || got[0].InfoHash != "bbb" {
+		t.Errorf("SearchTorrents() with limit 1 = %v, %v, want bbb", got, err)
+	}
+}
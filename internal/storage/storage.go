@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTorrentNotFound = errors.New("torrent not found")
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrSessionNotFound = errors.New("engine session not found")
+	ErrMetaInfoMissing = errors.New("metainfo is not cached")
+)
+
+// Torrent - запись о раздаче, которую сервер когда-либо видел в выдаче Jackett
+type Torrent struct {
+	InfoHash    string
+	Title       string
+	Description string
+	Category    []uint
+	MagnetUri   string
+	Size        uint
+	Trackers    []string // трекеры (индексаторы Jackett), на которых встречалась раздача
+	Seeders     uint     // последнее известное количество сидов
+	Peers       uint
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	// требования трекера: раздавать до рейтинга MinimumRatio и не меньше MinimumSeedTime.
+	// Если раздача встречалась на нескольких трекерах, хранятся самые строгие.
+	MinimumRatio    float64
+	MinimumSeedTime time.Duration
+}
+
+// TorrentFilter - условия поиска по локальному индексу
+type TorrentFilter struct {
+	// категории раздачи; родительская категория (например 5000), как и в Jackett,
+	// включает все свои подкатегории (5040 и т.д.). Пусто - любые категории.
+	Categories []uint
+	Tracker    string // раздача встречалась на этом трекере, пусто - на любом
+}
+
+// Search - запрос из истории поиска
+type Search struct {
+	Query        string
+	Categories   []uint
+	SafeOnly     int
+	Count        int // сколько раз запрос выполнялся
+	LastSearched time.Time
+}
+
+// Политики доступа API-ключа
+const (
+	PolicyFull = "full" // клиент сам выбирает safeOnly
+	PolicySafe = "safe" // поиск всегда выполняется с safeOnly=1
+)
+
+// APIKey - ключ доступа к API. Сам ключ не хранится, только его sha256-хэш.
+type APIKey struct {
+	ID         string
+	Name       string
+	KeyHash    string
+	Endpoints  []string // префиксы путей, к которым разрешён доступ, "*" - ко всем
+	DailyQuota int      // 0 - без ограничений
+	Policy     string
+	CreatedAt  time.Time
+	RevokedAt  time.Time // нулевое значение - ключ действует
+}
+
+// Session - раздача, добавленная во встроенный BitTorrent-клиент.
+// Сохраняется, чтобы после перезапуска сервера восстановить раздачи в прежнем состоянии.
+type Session struct {
+	InfoHash string
+	Magnet   string
+	Name     string
+	MetaInfo []byte // bencode .torrent, пусто, пока метаданные не получены от пиров
+	Paused   bool
+	AddedAt  time.Time
+	// последнее открытие потока, нулевое - раздачу не смотрели. По нему выбираются раздачи для удаления при нехватке места.
+	WatchedAt time.Time
+	// требования трекера из выдачи Jackett на момент добавления, 0 - требования нет
+	MinimumRatio    float64
+	MinimumSeedTime time.Duration
+	// счётчики за все запуски сервера, сохраняются через SaveSessionStats
+	Uploaded    int64
+	Downloaded  int64
+	SeedingTime time.Duration // сколько раздача была скачана целиком и не на паузе
+}